@@ -1,20 +1,52 @@
 package main
 
-// При желании конфигурацию можно вынести в internal/config.
-// Организация конфига в main принуждает нас сужать API компонентов, использовать
-// при их конструировании только необходимые параметры, а также уменьшает вероятность циклической зависимости.
+import (
+	"errors"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/config"
+)
+
+// Общие секции конфигурации и их загрузка вынесены в internal/config,
+// но каждый процесс собирает из них собственный конфиг. Организация конфига в main принуждает нас
+// сужать API компонентов, использовать при их конструировании только необходимые параметры,
+// а также уменьшает вероятность циклической зависимости.
 type Config struct {
-	Logger LoggerConf
-	// TODO
+	Logger  config.LoggerConf  `yaml:"logger" toml:"logger"`
+	Storage config.StorageConf `yaml:"storage" toml:"storage"`
+	HTTP    config.ServerConf  `yaml:"http" toml:"http"`
+	GRPC    config.ServerConf  `yaml:"grpc" toml:"grpc"`
+	// RateLimit and CORS are shared by HTTP and gRPC servers, they're applied on SIGHUP
+	// along with the log level.
+	RateLimit config.RateLimitConf `yaml:"rate_limit" toml:"rate_limit"`
+	CORS      config.CORSConf      `yaml:"cors" toml:"cors"`
 }
 
-type LoggerConf struct {
-	Level string
-	// TODO
+func NewConfig() Config {
+	return Config{
+		Logger:  config.LoggerConf{Level: "info", Format: "json"},
+		Storage: config.StorageConf{Type: config.StorageMemory},
+		HTTP:    config.ServerConf{Host: "0.0.0.0", Port: 8080},
+		GRPC:    config.ServerConf{Host: "0.0.0.0", Port: 50051},
+	}
 }
 
-func NewConfig() Config {
-	return Config{}
+// LoadConfig reads the configuration file, every key of which can be overridden
+// by an environment variable with CALENDAR_ prefix, e.g. CALENDAR_STORAGE_DSN.
+func LoadConfig(path string) (Config, error) {
+	c := NewConfig()
+	if err := config.Load(path, "CALENDAR", &c); err != nil {
+		return Config{}, err
+	}
+	return c, nil
 }
 
-// TODO
+func (c *Config) Validate() error {
+	return errors.Join(
+		config.Section("logger", c.Logger.Validate()),
+		config.Section("storage", c.Storage.Validate()),
+		config.Section("http", c.HTTP.Validate()),
+		config.Section("grpc", c.GRPC.Validate()),
+		config.Section("rate_limit", c.RateLimit.Validate()),
+		config.Section("cors", c.CORS.Validate()),
+	)
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	c, err := LoadConfig("../../configs/config.yaml")
+	require.NoError(t, err)
+	require.Equal(t, "info", c.Logger.Level)
+	require.Equal(t, "0.0.0.0:8080", c.HTTP.Addr())
+
+	t.Setenv("CALENDAR_STORAGE_TYPE", "sql")
+	t.Setenv("CALENDAR_STORAGE_DRIVER", "sqlite")
+	t.Setenv("CALENDAR_STORAGE_DSN", "calendar.db")
+	c, err = LoadConfig("../../configs/config.yaml")
+	require.NoError(t, err)
+	require.Equal(t, "sql", c.Storage.Type)
+	require.Equal(t, "calendar.db", c.Storage.DSN)
+
+	t.Setenv("CALENDAR_GRPC_PORT", "-1")
+	_, err = LoadConfig("../../configs/config.yaml")
+	require.ErrorContains(t, err, "grpc.port")
+}
@@ -3,21 +3,27 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/app"
 	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/logger"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/monitoring"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/ratelimit"
+	internalgrpc "github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/server/grpc"
 	internalhttp "github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/server/http"
-	memorystorage "github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage/memory"
+	storagefactory "github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage/factory"
 )
 
 var configFile string
 
 func init() {
-	flag.StringVar(&configFile, "config", "/etc/calendar/config.toml", "Path to configuration file")
+	flag.StringVar(&configFile, "config", "/etc/calendar/config.yaml", "Path to configuration file")
 }
 
 func main() {
@@ -28,34 +34,116 @@ func main() {
 		return
 	}
 
-	config := NewConfig()
-	logg := logger.New(config.Logger.Level)
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
-	storage := memorystorage.New()
-	calendar := app.New(logg, storage)
+	if flag.Arg(0) == "migrate" {
+		if err := storagefactory.Migrate(context.Background(), config.Storage, flag.Arg(1), os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	server := internalhttp.NewServer(logg, calendar)
+	logg, err := logger.New(config.Logger.Level, config.Logger.Format, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
-	ctx, cancel := signal.NotifyContext(context.Background(),
-		syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
+	// SIGHUP reloads the configuration, see reloader. It's caught from now on, so one sent
+	// while the storage is connecting doesn't kill the process, it's applied once serving starts.
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
 
-	go func() {
-		<-ctx.Done()
-
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
-		defer cancel()
-
-		if err := server.Stop(ctx); err != nil {
-			logg.Error("failed to stop http server: " + err.Error())
+	storage, closeStorage, err := storagefactory.New(ctx, config.Storage)
+	if err != nil {
+		logg.Error("failed to initialize storage", "error", err)
+		os.Exit(1) //nolint:gocritic
+	}
+	defer func() {
+		if err := closeStorage(context.Background()); err != nil {
+			logg.Error("failed to close storage", "error", err)
 		}
 	}()
 
+	calendar := app.New(logg, storage)
+	monitoring.SetBuildInfo("calendar", release, buildDate, gitHash)
+	checks := map[string]monitoring.Check{"storage": storage.Ping}
+	limiter := ratelimit.New(config.RateLimit.RPS, config.RateLimit.Burst)
+
+	httpServer := internalhttp.NewServer(logg, calendar, config.HTTP.Addr(), checks, limiter)
+	httpServer.SetOrigins(config.CORS.Origins)
+	servers := map[string]server{
+		"http": httpServer,
+		"grpc": internalgrpc.NewServer(logg, calendar, config.GRPC.Addr(), limiter),
+	}
+
+	reload := &reloader{
+		logger:     logg,
+		path:       configFile,
+		config:     config,
+		limiter:    limiter,
+		setOrigins: httpServer.SetOrigins,
+	}
+	go reload.run(ctx, hangups)
+
 	logg.Info("calendar is running...")
 
-	if err := server.Start(ctx); err != nil {
-		logg.Error("failed to start http server: " + err.Error())
+	if !runServers(ctx, logg, servers) {
 		cancel()
 		os.Exit(1) //nolint:gocritic
 	}
 }
+
+type server interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// runServers starts the servers and stops all of them when ctx is done or any of them fails.
+// In-flight requests are drained before it returns. The result is false if any server failed.
+func runServers(ctx context.Context, logg *logger.Logger, servers map[string]server) bool {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg     sync.WaitGroup
+		failed atomic.Bool
+	)
+	for name, srv := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := srv.Start(ctx); err != nil {
+				logg.Error("failed to start "+name+" server", "error", err)
+				failed.Store(true)
+				cancel()
+			}
+		}()
+	}
+
+	<-ctx.Done()
+
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer stopCancel()
+
+	for name, srv := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := srv.Stop(stopCtx); err != nil {
+				logg.Error("failed to stop "+name+" server", "error", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+	return !failed.Load()
+}
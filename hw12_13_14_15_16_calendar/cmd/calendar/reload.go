@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"os"
+	"slices"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/logger"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/ratelimit"
+)
+
+// reloader re-reads the configuration file on SIGHUP and applies the settings which are safe
+// to change while requests are served: the log level, the rate limit and CORS origins.
+// An invalid configuration is rejected as a whole, the current one stays active then.
+type reloader struct {
+	logger     *logger.Logger
+	path       string
+	config     Config
+	limiter    *ratelimit.Limiter
+	setOrigins func(origins []string)
+}
+
+// run reloads the configuration on every signal received from signals until ctx is done.
+func (r *reloader) run(ctx context.Context, signals <-chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			r.reload()
+		}
+	}
+}
+
+func (r *reloader) reload() {
+	c, err := LoadConfig(r.path)
+	if err != nil {
+		r.logger.Error("failed to reload config, the current one stays active", "error", err)
+		return
+	}
+
+	// the level is valid, as the config has passed validation
+	_ = r.logger.SetLevel(c.Logger.Level)
+	if c.RateLimit != r.config.RateLimit {
+		r.limiter.Configure(c.RateLimit.RPS, c.RateLimit.Burst)
+	}
+	if !slices.Equal(c.CORS.Origins, r.config.CORS.Origins) {
+		r.setOrigins(c.CORS.Origins)
+	}
+
+	if sections := restartRequired(r.config, c); len(sections) > 0 {
+		r.logger.Warn("changes of these sections take effect after restart", "sections", sections)
+	}
+	r.config.Logger.Level = c.Logger.Level
+	r.config.RateLimit = c.RateLimit
+	r.config.CORS = c.CORS
+
+	r.logger.Info("config reloaded", "log_level", c.Logger.Level,
+		"rate_limit", c.RateLimit.RPS, "cors_origins", c.CORS.Origins)
+}
+
+// restartRequired returns names of the sections changed in a way which can't be applied live.
+func restartRequired(current, next Config) []string {
+	var sections []string
+	if current.Logger.Format != next.Logger.Format {
+		sections = append(sections, "logger.format")
+	}
+	if current.Storage != next.Storage {
+		sections = append(sections, "storage")
+	}
+	if current.HTTP != next.HTTP {
+		sections = append(sections, "http")
+	}
+	if current.GRPC != next.GRPC {
+		sections = append(sections, "grpc")
+	}
+	return sections
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/logger"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/ratelimit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(content string) {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+	write(`
+logger:
+  level: info
+`)
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	logg, err := logger.New(config.Logger.Level, config.Logger.Format, buf)
+	require.NoError(t, err)
+
+	var origins []string
+	limiter := ratelimit.New(config.RateLimit.RPS, config.RateLimit.Burst)
+	r := &reloader{
+		logger:     logg,
+		path:       path,
+		config:     config,
+		limiter:    limiter,
+		setOrigins: func(o []string) { origins = o },
+	}
+
+	write(`
+logger:
+  level: debug
+http:
+  port: 8081
+rate_limit:
+  rps: 1
+  burst: 1
+cors:
+  origins: [https://calendar.example.com]
+`)
+	r.reload()
+	require.Equal(t, []string{"https://calendar.example.com"}, origins)
+	require.True(t, limiter.Allow("user"))
+	require.False(t, limiter.Allow("user"))
+	require.Contains(t, buf.String(), "changes of these sections take effect after restart")
+	require.Contains(t, buf.String(), "config reloaded")
+
+	buf.Reset()
+	logg.Debug("debug is enabled")
+	require.Contains(t, buf.String(), "debug is enabled")
+
+	t.Run("invalid config is rejected", func(t *testing.T) {
+		write(`
+logger:
+  level: error
+rate_limit:
+  rps: -1
+`)
+		buf.Reset()
+		r.reload()
+		require.Contains(t, buf.String(), "failed to reload config")
+		require.Contains(t, buf.String(), "rate_limit.rps")
+
+		buf.Reset()
+		logg.Debug("debug is still enabled")
+		require.Contains(t, buf.String(), "debug is still enabled")
+		require.Equal(t, []string{"https://calendar.example.com"}, origins)
+	})
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	c, err := LoadConfig("../../configs/scheduler_config.yaml")
+	require.NoError(t, err)
+	require.Equal(t, "notifications", c.Queue.Name)
+	require.Equal(t, time.Minute, c.Scheduler.ScanInterval)
+	require.Equal(t, 365*24*time.Hour, c.Retention.Age)
+	require.False(t, c.Retention.DryRun)
+	require.Equal(t, "0.0.0.0:9091", c.Monitoring.Addr())
+
+	t.Setenv("CALENDAR_SCHEDULER_SCAN_INTERVAL", "10s")
+	c, err = LoadConfig("../../configs/scheduler_config.yaml")
+	require.NoError(t, err)
+	require.Equal(t, 10*time.Second, c.Scheduler.ScanInterval)
+
+	t.Setenv("CALENDAR_RETENTION_DRY_RUN", "true")
+	c, err = LoadConfig("../../configs/scheduler_config.yaml")
+	require.NoError(t, err)
+	require.True(t, c.Retention.DryRun)
+
+	t.Setenv("CALENDAR_QUEUE_NAME", "")
+	_, err = LoadConfig("../../configs/scheduler_config.yaml")
+	require.ErrorContains(t, err, "queue.name")
+
+	t.Setenv("CALENDAR_QUEUE_DRIVER", "local")
+	c, err = LoadConfig("../../configs/scheduler_config.yaml")
+	require.NoError(t, err)
+	require.Equal(t, "/var/lib/calendar/queue", c.Queue.Dir)
+
+	t.Setenv("CALENDAR_QUEUE_DIR", "")
+	_, err = LoadConfig("../../configs/scheduler_config.yaml")
+	require.ErrorContains(t, err, "queue.dir")
+
+	t.Setenv("CALENDAR_QUEUE_DRIVER", "kafka")
+	_, err = LoadConfig("../../configs/scheduler_config.yaml")
+	require.ErrorContains(t, err, "queue.driver")
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/logger"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/monitoring"
+	queuefactory "github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/queue/factory"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/scheduler"
+	storagefactory "github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage/factory"
+)
+
+var configFile string
+
+func init() {
+	flag.StringVar(&configFile, "config", "/etc/calendar/scheduler_config.yaml", "Path to configuration file")
+}
+
+func main() {
+	flag.Parse()
+
+	if flag.Arg(0) == "version" {
+		printVersion()
+		return
+	}
+
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	logg, err := logger.New(config.Logger.Level, config.Logger.Format, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	storage, closeStorage, err := storagefactory.New(ctx, config.Storage)
+	if err != nil {
+		logg.Error("failed to initialize storage", "error", err)
+		os.Exit(1) //nolint:gocritic
+	}
+	defer func() {
+		if err := closeStorage(context.Background()); err != nil {
+			logg.Error("failed to close storage", "error", err)
+		}
+	}()
+
+	publisher, closePublisher, err := queuefactory.NewPublisher(ctx, config.Queue)
+	if err != nil {
+		logg.Error("failed to connect to queue", "error", err)
+		cancel()
+		os.Exit(1)
+	}
+	defer func() {
+		if err := closePublisher(context.Background()); err != nil {
+			logg.Error("failed to close queue connection", "error", err)
+		}
+	}()
+
+	logg.Info("scheduler is running...",
+		"scan_interval", config.Scheduler.ScanInterval, "retention_age", config.Retention.Age)
+
+	monitoring.SetBuildInfo("scheduler", release, buildDate, gitHash)
+	monitor := monitoring.NewServer(logg, config.Monitoring.Addr(), map[string]monitoring.Check{
+		"storage": storage.Ping,
+		"queue":   publisher.Ping,
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		if err := monitor.Run(ctx); err != nil {
+			logg.Error("failed to run monitoring server", "error", err)
+			cancel()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		scheduler.New(logg, storage, publisher, config.Scheduler.ScanInterval).Run(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		scheduler.NewRetention(logg, storage, config.Retention.Age, config.Retention.Interval,
+			config.Retention.BatchSize, config.Retention.DryRun).Run(ctx)
+	}()
+	wg.Wait()
+
+	logg.Info("scheduler is stopped")
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	c, err := LoadConfig("../../configs/sender_config.yaml")
+	require.NoError(t, err)
+	require.Equal(t, []string{"log"}, c.Sender.Channels)
+	require.Equal(t, 5, c.Sender.MaxAttempts)
+	require.Equal(t, "0.0.0.0:9092", c.Monitoring.Addr())
+
+	t.Setenv("CALENDAR_SENDER_CHANNELS", "log,stdout")
+	c, err = LoadConfig("../../configs/sender_config.yaml")
+	require.NoError(t, err)
+	require.Equal(t, []string{"log", "stdout"}, c.Sender.Channels)
+
+	t.Setenv("CALENDAR_SENDER_MAX_ATTEMPTS", "0")
+	_, err = LoadConfig("../../configs/sender_config.yaml")
+	require.ErrorContains(t, err, "sender.max_attempts")
+}
+
+func TestWebhookConfig(t *testing.T) {
+	t.Setenv("CALENDAR_SENDER_CHANNELS", "log,webhook")
+	_, err := LoadConfig("../../configs/sender_config.yaml")
+	require.ErrorContains(t, err, "storage.type")
+
+	t.Setenv("CALENDAR_STORAGE_TYPE", "sql")
+	c, err := LoadConfig("../../configs/sender_config.yaml")
+	require.NoError(t, err)
+	require.True(t, c.webhooks())
+	require.Equal(t, 3, c.Sender.Webhook.MaxRetries)
+	require.Equal(t, 30*time.Second, c.Sender.Webhook.MaxBackoff)
+
+	t.Setenv("CALENDAR_SENDER_WEBHOOK_MAX_BACKOFF", "10ms")
+	_, err = LoadConfig("../../configs/sender_config.yaml")
+	require.ErrorContains(t, err, "sender.webhook.max_backoff")
+}
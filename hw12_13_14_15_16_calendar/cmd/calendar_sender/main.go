@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/logger"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/monitoring"
+	queuefactory "github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/queue/factory"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/sender"
+	storagefactory "github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage/factory"
+)
+
+var configFile string
+
+func init() {
+	flag.StringVar(&configFile, "config", "/etc/calendar/sender_config.yaml", "Path to configuration file")
+}
+
+func main() {
+	flag.Parse()
+
+	if flag.Arg(0) == "version" {
+		printVersion()
+		return
+	}
+
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	logg, err := logger.New(config.Logger.Level, config.Logger.Format, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	monitoring.SetBuildInfo("sender", release, buildDate, gitHash)
+	checks := make(map[string]monitoring.Check)
+
+	var webhook *sender.WebhookChannel
+	if config.webhooks() {
+		storage, closeStorage, err := storagefactory.New(ctx, config.Storage)
+		if err != nil {
+			logg.Error("failed to initialize storage", "error", err)
+			os.Exit(1) //nolint:gocritic
+		}
+		defer func() {
+			if err := closeStorage(context.Background()); err != nil {
+				logg.Error("failed to close storage", "error", err)
+			}
+		}()
+
+		checks["storage"] = storage.Ping
+
+		webhook = sender.NewWebhookChannel(logg, storage, sender.WebhookOptions{
+			Timeout:    config.Sender.Webhook.Timeout,
+			MaxRetries: config.Sender.Webhook.MaxRetries,
+			MinBackoff: config.Sender.Webhook.MinBackoff,
+			MaxBackoff: config.Sender.Webhook.MaxBackoff,
+
+			AllowPrivateAddresses: config.Sender.Webhook.AllowPrivateAddresses,
+		})
+	}
+
+	channels, err := sender.NewChannels(config.Sender.Channels, logg, os.Stdout, webhook)
+	if err != nil {
+		logg.Error("failed to initialize channels", "error", err)
+		os.Exit(1)
+	}
+
+	consumer, err := queuefactory.NewConsumer(config.Queue)
+	if err != nil {
+		logg.Error("failed to initialize queue", "error", err)
+		os.Exit(1)
+	}
+	s := sender.New(logg, consumer, channels, config.Sender.MaxAttempts)
+	checks["queue"] = s.Ready
+
+	logg.Info("sender is running...", "channels", config.Sender.Channels)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if err := monitoring.NewServer(logg, config.Monitoring.Addr(), checks).Run(ctx); err != nil {
+			logg.Error("failed to run monitoring server", "error", err)
+			cancel()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		s.Run(ctx)
+	}()
+	wg.Wait()
+
+	logg.Info("sender is stopped")
+}
@@ -2,25 +2,340 @@ package app
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/notification"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/rrule"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+	"github.com/google/uuid"
+)
+
+const (
+	maxTitleLength = 255
+	maxReminders   = 10
 )
 
-type App struct { // TODO
+var (
+	ErrInvalidEvent = errors.New("invalid event")
+	ErrEmptyUserID  = errors.New("user id is empty")
+)
+
+type App struct {
+	logger  Logger
+	storage Storage
 }
 
-type Logger interface { // TODO
+type Logger interface {
+	DebugContext(ctx context.Context, msg string, args ...any)
+	InfoContext(ctx context.Context, msg string, args ...any)
+	WarnContext(ctx context.Context, msg string, args ...any)
+	ErrorContext(ctx context.Context, msg string, args ...any)
 }
 
-type Storage interface { // TODO
+type Storage interface {
+	Ping(ctx context.Context) error
+	CreateEvent(ctx context.Context, event storage.Event, audit storage.Audit) error
+	UpdateEvent(ctx context.Context, id string, event storage.Event, audit storage.Audit) error
+	DeleteEvent(ctx context.Context, id string, audit storage.Audit) error
+	GetEvent(ctx context.Context, id string) (storage.Event, error)
+	ListEvents(ctx context.Context, userID string, from, to time.Time) ([]storage.Event, error)
+	ListUserEvents(ctx context.Context, userID string) ([]storage.Event, error)
+	ListDueReminders(ctx context.Context, now time.Time) ([]storage.DueReminder, error)
+	MarkReminderFired(ctx context.Context, due storage.DueReminder) error
+	DeleteEventsBefore(ctx context.Context, before time.Time, limit int) (int, error)
+	CountEventsBefore(ctx context.Context, before time.Time) (int, error)
+
+	CreateWebhook(ctx context.Context, webhook storage.Webhook) error
+	GetWebhook(ctx context.Context, id string) (storage.Webhook, error)
+	ListWebhooks(ctx context.Context, userID string) ([]storage.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	AddDelivery(ctx context.Context, delivery storage.Delivery) error
+	ListDeliveries(ctx context.Context, webhookID string, limit int) ([]storage.Delivery, error)
+
+	ListChanges(ctx context.Context, eventID string) ([]storage.Change, error)
 }
 
 func New(logger Logger, storage Storage) *App {
-	return &App{}
+	return &App{
+		logger:  logger,
+		storage: storage,
+	}
+}
+
+// CreateEvent stores a new event owned by the user. The ID of the event is generated,
+// so the one passed in is ignored. Attendees are invited once the event exists.
+func (a *App) CreateEvent(ctx context.Context, userID string, event storage.Event) (storage.Event, error) {
+	if userID == "" {
+		return storage.Event{}, ErrEmptyUserID
+	}
+
+	event.ID = uuid.NewString()
+	event.UserID = userID
+	event.Attendees = nil
+	return a.createEvent(ctx, event, 0)
+}
+
+// createEvent stores the event on behalf of its owner. Non-zero restoredFrom is the revision
+// of the deleted event it's restored to.
+func (a *App) createEvent(ctx context.Context, event storage.Event, restoredFrom int64) (storage.Event, error) {
+	event, err := a.prepareEvent(ctx, event)
+	if err != nil {
+		return storage.Event{}, err
+	}
+
+	audit := newAudit(event.UserID)
+	audit.RestoredFrom = restoredFrom
+	if err := a.storage.CreateEvent(ctx, event, audit); err != nil {
+		return storage.Event{}, a.storageError(ctx, "create event", err)
+	}
+	event.Version = 1
+
+	a.logger.InfoContext(ctx, "event created", "event_id", event.ID, "user_id", event.UserID)
+	eventsCreated.Inc()
+	return event, nil
+}
+
+// UpdateEvent replaces the event with the given ID. Only the owner is allowed to update
+// the event, for anyone else it doesn't exist. Attendees are kept, they are changed
+// by invitations and responses only. Non-zero version of the event must be
+// the current one, otherwise the update fails with storage.ErrVersionConflict.
+// The update is based on the version read here anyway, so that a concurrent change
+// isn't overwritten silently.
+func (a *App) UpdateEvent(ctx context.Context, userID, id string, event storage.Event) (storage.Event, error) {
+	return a.updateEvent(ctx, userID, id, event, 0)
 }
 
-func (a *App) CreateEvent(ctx context.Context, id, title string) error {
-	// TODO
+func (a *App) updateEvent(ctx context.Context, userID, id string, event storage.Event, restoredFrom int64,
+) (storage.Event, error) {
+	current, err := a.ownEvent(ctx, userID, id)
+	if err != nil {
+		return storage.Event{}, err
+	}
+	if event.Version == 0 {
+		event.Version = current.Version
+	}
+	event.Attendees = current.Attendees
+
+	event.ID = id
+	event.UserID = userID
+	event, err = a.prepareEvent(ctx, event)
+	if err != nil {
+		return storage.Event{}, err
+	}
+
+	audit := newAudit(userID)
+	audit.RestoredFrom = restoredFrom
+	if err := a.storage.UpdateEvent(ctx, id, event, audit); err != nil {
+		return storage.Event{}, a.storageError(ctx, "update event", err)
+	}
+	event.Version++
+
+	a.logger.InfoContext(ctx, "event updated", "event_id", id, "user_id", userID)
+	return event, nil
+}
+
+// DeleteEvent deletes the event. Deletion of a series deletes its overrides as well,
+// each of them gets the deletion into its audit log.
+func (a *App) DeleteEvent(ctx context.Context, userID, id string) error {
+	if _, err := a.ownEvent(ctx, userID, id); err != nil {
+		return err
+	}
+
+	if err := a.storage.DeleteEvent(ctx, id, newAudit(userID)); err != nil {
+		return a.storageError(ctx, "delete event", err)
+	}
+
+	a.logger.InfoContext(ctx, "event deleted", "event_id", id, "user_id", userID)
 	return nil
-	// return a.storage.CreateEvent(storage.Event{ID: id, Title: title})
 }
 
-// TODO
+// GetEvent returns the event to its owner or attendee, for anyone else it doesn't exist.
+func (a *App) GetEvent(ctx context.Context, userID, id string) (storage.Event, error) {
+	if userID == "" {
+		return storage.Event{}, ErrEmptyUserID
+	}
+
+	event, err := a.storage.GetEvent(ctx, id)
+	if err != nil {
+		return storage.Event{}, a.storageError(ctx, "get event", err)
+	}
+	if _, ok := event.Attendee(userID); event.UserID != userID && !ok {
+		return storage.Event{}, storage.ErrEventNotFound
+	}
+	return event, nil
+}
+
+// ownEvent returns the event owned by the user, for anyone else, attendees included, it doesn't exist.
+func (a *App) ownEvent(ctx context.Context, userID, id string) (storage.Event, error) {
+	event, err := a.GetEvent(ctx, userID, id)
+	if err == nil && event.UserID != userID {
+		return storage.Event{}, storage.ErrEventNotFound
+	}
+	return event, err
+}
+
+func (a *App) ListEventsForDay(ctx context.Context, userID string, date time.Time) ([]storage.Event, error) {
+	from, to := storage.DayPeriod(date)
+	return a.listEvents(ctx, userID, from, to)
+}
+
+func (a *App) ListEventsForWeek(ctx context.Context, userID string, date time.Time) ([]storage.Event, error) {
+	from, to := storage.WeekPeriod(date)
+	return a.listEvents(ctx, userID, from, to)
+}
+
+func (a *App) ListEventsForMonth(ctx context.Context, userID string, date time.Time) ([]storage.Event, error) {
+	from, to := storage.MonthPeriod(date)
+	return a.listEvents(ctx, userID, from, to)
+}
+
+func (a *App) listEvents(ctx context.Context, userID string, from, to time.Time) ([]storage.Event, error) {
+	if userID == "" {
+		return nil, ErrEmptyUserID
+	}
+
+	events, err := a.storage.ListEvents(ctx, userID, from, to)
+	if err != nil {
+		return nil, a.storageError(ctx, "list events", err)
+	}
+	return events, nil
+}
+
+// storageError logs unexpected storage failures. Errors caused by the request itself
+// are returned as is, so callers can match them with errors.Is.
+func (a *App) storageError(ctx context.Context, op string, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrEventNotFound),
+		errors.Is(err, storage.ErrEventAlreadyExists),
+		errors.Is(err, storage.ErrDateBusy),
+		errors.Is(err, storage.ErrVersionConflict),
+		errors.Is(err, storage.ErrWebhookNotFound),
+		errors.Is(err, storage.ErrRevisionNotFound),
+		errors.Is(err, storage.ErrAttendeeNotFound):
+		return err
+	}
+
+	a.logger.ErrorContext(ctx, "failed to "+op, "error", err)
+	return fmt.Errorf("failed to %s: %w", op, err)
+}
+
+// prepareEvent validates the event and brings its recurrence to the canonical form.
+// An override must refer to an existing occurrence of a series of the same owner,
+// whose attendees it gets.
+func (a *App) prepareEvent(ctx context.Context, event storage.Event) (storage.Event, error) {
+	if err := validateEvent(event); err != nil {
+		return storage.Event{}, err
+	}
+
+	if event.Recurring() {
+		rule, err := rrule.Parse(event.RRule)
+		if err != nil {
+			return storage.Event{}, fmt.Errorf("%w: %w", ErrInvalidEvent, err)
+		}
+		start := event.StartAt.In(event.Location())
+		if !rule.Includes(start, start) {
+			return storage.Event{}, fmt.Errorf("%w: start time doesn't match the recurrence rule", ErrInvalidEvent)
+		}
+		event.RRule = rule.String()
+	}
+
+	if event.SeriesID == "" {
+		return event, nil
+	}
+
+	series, err := a.ownEvent(ctx, event.UserID, event.SeriesID)
+	if errors.Is(err, storage.ErrEventNotFound) || err == nil && !series.Recurring() {
+		return storage.Event{}, fmt.Errorf("%w: series %s is not found", ErrInvalidEvent, event.SeriesID)
+	}
+	if err != nil {
+		return storage.Event{}, err
+	}
+
+	rule, err := rrule.Parse(series.RRule)
+	if err != nil {
+		return storage.Event{}, a.storageError(ctx, "parse series rule", err)
+	}
+	if !rule.Includes(series.StartAt.In(series.Location()), event.RecurrenceID) {
+		return storage.Event{}, fmt.Errorf("%w: series %s has no occurrence at %s",
+			ErrInvalidEvent, event.SeriesID, event.RecurrenceID.Format(time.RFC3339))
+	}
+	event.Attendees = series.Attendees
+	return event, nil
+}
+
+func validateEvent(event storage.Event) error {
+	var errs []error
+
+	if event.Title == "" {
+		errs = append(errs, fmt.Errorf("%w: title is empty", ErrInvalidEvent))
+	} else if utf8.RuneCountInString(event.Title) > maxTitleLength {
+		errs = append(errs, fmt.Errorf("%w: title is longer than %d characters", ErrInvalidEvent, maxTitleLength))
+	}
+	if event.StartAt.IsZero() {
+		errs = append(errs, fmt.Errorf("%w: start time is not set", ErrInvalidEvent))
+	}
+	if event.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("%w: duration must be positive", ErrInvalidEvent))
+	}
+	switch {
+	case event.NotifyBefore < 0:
+		errs = append(errs, fmt.Errorf("%w: notification offset must not be negative", ErrInvalidEvent))
+	case event.NotifyBefore%time.Second != 0:
+		// the offset is stored in seconds
+		errs = append(errs, fmt.Errorf("%w: notification offset must be whole seconds", ErrInvalidEvent))
+	}
+	errs = append(errs, validateReminders(event)...)
+	if _, err := storage.LoadLocation(event.TimeZone); err != nil {
+		errs = append(errs, fmt.Errorf("%w: unknown time zone %q", ErrInvalidEvent, event.TimeZone))
+	}
+	if len(event.ExDates) > 0 && !event.Recurring() {
+		errs = append(errs, fmt.Errorf("%w: exception dates are allowed only for recurring events", ErrInvalidEvent))
+	}
+	if event.SeriesID != "" {
+		if event.Recurring() {
+			errs = append(errs, fmt.Errorf("%w: an override of an occurrence can't recur", ErrInvalidEvent))
+		}
+		if event.RecurrenceID.IsZero() {
+			errs = append(errs, fmt.Errorf("%w: recurrence id of the overridden occurrence is not set", ErrInvalidEvent))
+		}
+		if event.SeriesID == event.ID {
+			errs = append(errs, fmt.Errorf("%w: an event can't override itself", ErrInvalidEvent))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateReminders(event storage.Event) []error {
+	if len(event.Reminders) == 0 {
+		return nil
+	}
+
+	var errs []error
+	if event.NotifyBefore != 0 {
+		errs = append(errs, fmt.Errorf("%w: notification offset can't be combined with reminders", ErrInvalidEvent))
+	}
+	if len(event.Reminders) > maxReminders {
+		errs = append(errs, fmt.Errorf("%w: no more than %d reminders are allowed", ErrInvalidEvent, maxReminders))
+	}
+	for i, reminder := range event.Reminders {
+		switch {
+		case reminder.Before <= 0:
+			errs = append(errs, fmt.Errorf("%w: offset of reminder %d must be positive", ErrInvalidEvent, i+1))
+		case reminder.Before%time.Second != 0:
+			// the SQL storage keeps offsets in seconds
+			errs = append(errs, fmt.Errorf("%w: offset of reminder %d must be whole seconds", ErrInvalidEvent, i+1))
+		}
+		if reminder.Channel != "" && !notification.KnownChannel(reminder.Channel) {
+			errs = append(errs, fmt.Errorf("%w: unknown channel %q of reminder %d", ErrInvalidEvent, reminder.Channel, i+1))
+		}
+		if slices.Contains(event.Reminders[:i], reminder) {
+			errs = append(errs, fmt.Errorf("%w: reminder %d repeats another one", ErrInvalidEvent, i+1))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,606 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) DebugContext(context.Context, string, ...any) {}
+func (nopLogger) InfoContext(context.Context, string, ...any)  {}
+func (nopLogger) WarnContext(context.Context, string, ...any)  {}
+func (nopLogger) ErrorContext(context.Context, string, ...any) {}
+
+var baseTime = time.Date(2024, time.March, 11, 10, 0, 0, 0, time.UTC)
+
+func newEvent(title string, startAt time.Time) storage.Event {
+	return storage.Event{
+		Title:    title,
+		StartAt:  startAt,
+		Duration: time.Hour,
+	}
+}
+
+func withReminders(event storage.Event, reminders ...storage.Reminder) storage.Event {
+	event.Reminders = reminders
+	return event
+}
+
+func TestApp(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("create", func(t *testing.T) {
+		a := New(nopLogger{}, memorystorage.New())
+
+		event := newEvent("meeting", baseTime)
+		event.ID = "ignored"
+		event.UserID = "ignored"
+
+		created, err := a.CreateEvent(ctx, "user", event)
+		require.NoError(t, err)
+		require.NotEmpty(t, created.ID)
+		require.NotEqual(t, "ignored", created.ID)
+		require.Equal(t, "user", created.UserID)
+		require.Equal(t, int64(1), created.Version)
+
+		got, err := a.GetEvent(ctx, "user", created.ID)
+		require.NoError(t, err)
+		require.Equal(t, created, got)
+
+		_, err = a.CreateEvent(ctx, "user", newEvent("overlapping", baseTime.Add(time.Minute)))
+		require.ErrorIs(t, err, storage.ErrDateBusy)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		a := New(nopLogger{}, memorystorage.New())
+
+		_, err := a.CreateEvent(ctx, "", newEvent("meeting", baseTime))
+		require.ErrorIs(t, err, ErrEmptyUserID)
+
+		tests := []struct {
+			name  string
+			event storage.Event
+		}{
+			{"empty title", newEvent("", baseTime)},
+			{"long title", newEvent(strings.Repeat("я", maxTitleLength+1), baseTime)},
+			{"no start", newEvent("meeting", time.Time{})},
+			{"no duration", storage.Event{Title: "meeting", StartAt: baseTime}},
+			{"negative notify", storage.Event{Title: "meeting", StartAt: baseTime, Duration: time.Hour, NotifyBefore: -1}},
+			{"notify of fractional seconds", storage.Event{
+				Title: "meeting", StartAt: baseTime, Duration: time.Hour, NotifyBefore: time.Minute + time.Millisecond,
+			}},
+			{"reminder without offset", withReminders(newEvent("meeting", baseTime), storage.Reminder{Channel: "log"})},
+			{"reminder of fractional seconds", withReminders(newEvent("meeting", baseTime),
+				storage.Reminder{Before: time.Minute + time.Millisecond})},
+			{"reminder of unknown channel", withReminders(newEvent("meeting", baseTime),
+				storage.Reminder{Before: time.Hour, Channel: "pigeon"})},
+			{"repeated reminder", withReminders(newEvent("meeting", baseTime),
+				storage.Reminder{Before: time.Hour}, storage.Reminder{Before: time.Hour})},
+			{"reminders with notify", func() storage.Event {
+				event := withReminders(newEvent("meeting", baseTime), storage.Reminder{Before: time.Hour})
+				event.NotifyBefore = time.Minute
+				return event
+			}()},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				_, err := a.CreateEvent(ctx, "user", tc.event)
+				require.ErrorIs(t, err, ErrInvalidEvent)
+			})
+		}
+
+		_, err = a.CreateEvent(ctx, "user", newEvent(strings.Repeat("я", maxTitleLength), baseTime))
+		require.NoError(t, err)
+		_, err = a.CreateEvent(ctx, "user", withReminders(newEvent("meeting", baseTime.Add(2*time.Hour)),
+			storage.Reminder{Before: 24 * time.Hour, Channel: "webhook"}, storage.Reminder{Before: 10 * time.Minute}))
+		require.NoError(t, err)
+	})
+
+	t.Run("update and delete", func(t *testing.T) {
+		a := New(nopLogger{}, memorystorage.New())
+
+		created, err := a.CreateEvent(ctx, "user", newEvent("meeting", baseTime))
+		require.NoError(t, err)
+
+		_, err = a.UpdateEvent(ctx, "other", created.ID, newEvent("stolen", baseTime))
+		require.ErrorIs(t, err, storage.ErrEventNotFound)
+		_, err = a.UpdateEvent(ctx, "user", created.ID, newEvent("", baseTime))
+		require.ErrorIs(t, err, ErrInvalidEvent)
+
+		updated, err := a.UpdateEvent(ctx, "user", created.ID, newEvent("renamed", baseTime.Add(time.Hour)))
+		require.NoError(t, err)
+		require.Equal(t, created.ID, updated.ID)
+		require.Equal(t, "user", updated.UserID)
+
+		got, err := a.GetEvent(ctx, "user", created.ID)
+		require.NoError(t, err)
+		require.Equal(t, updated, got)
+		require.Equal(t, int64(2), got.Version)
+
+		// the update based on the first version would overwrite the previous one
+		stale := newEvent("stale", baseTime)
+		stale.Version = created.Version
+		_, err = a.UpdateEvent(ctx, "user", created.ID, stale)
+		require.ErrorIs(t, err, storage.ErrVersionConflict)
+
+		require.ErrorIs(t, a.DeleteEvent(ctx, "other", created.ID), storage.ErrEventNotFound)
+		require.NoError(t, a.DeleteEvent(ctx, "user", created.ID))
+		require.ErrorIs(t, a.DeleteEvent(ctx, "user", created.ID), storage.ErrEventNotFound)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		a := New(nopLogger{}, memorystorage.New())
+
+		for _, date := range []time.Time{baseTime, baseTime.AddDate(0, 0, 1), baseTime.AddDate(0, 0, 8)} {
+			_, err := a.CreateEvent(ctx, "user", newEvent("meeting", date))
+			require.NoError(t, err)
+		}
+
+		events, err := a.ListEventsForDay(ctx, "user", baseTime)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+
+		events, err = a.ListEventsForWeek(ctx, "user", baseTime)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+
+		events, err = a.ListEventsForMonth(ctx, "user", baseTime)
+		require.NoError(t, err)
+		require.Len(t, events, 3)
+
+		events, err = a.ListEventsForMonth(ctx, "other", baseTime)
+		require.NoError(t, err)
+		require.Empty(t, events)
+
+		_, err = a.ListEventsForDay(ctx, "", baseTime)
+		require.ErrorIs(t, err, ErrEmptyUserID)
+	})
+
+	t.Run("recurrence", func(t *testing.T) {
+		a := New(nopLogger{}, memorystorage.New())
+
+		standup := newEvent("standup", baseTime)
+		standup.RRule = "RRULE:FREQ=WEEKLY;BYDAY=MO,WE;INTERVAL=1"
+		series, err := a.CreateEvent(ctx, "user", standup)
+		require.NoError(t, err)
+		require.Equal(t, "FREQ=WEEKLY;BYDAY=MO,WE", series.RRule)
+
+		override := newEvent("moved standup", baseTime.AddDate(0, 0, 2).Add(time.Hour))
+		override.SeriesID = series.ID
+		override.RecurrenceID = baseTime.AddDate(0, 0, 2)
+		_, err = a.CreateEvent(ctx, "user", override)
+		require.NoError(t, err)
+
+		events, err := a.ListEventsForWeek(ctx, "user", baseTime)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		require.Equal(t, "moved standup", events[1].Title)
+
+		_, err = a.CreateEvent(ctx, "other", override)
+		require.ErrorIs(t, err, ErrInvalidEvent, "series of another user")
+
+		tests := []struct {
+			name   string
+			modify func(event *storage.Event)
+		}{
+			{"malformed rule", func(e *storage.Event) { e.RRule = "FREQ=SOMETIMES" }},
+			{"start out of rule", func(e *storage.Event) { e.RRule = "FREQ=WEEKLY;BYDAY=TU" }},
+			{"exdates of single event", func(e *storage.Event) { e.ExDates = []time.Time{baseTime} }},
+			{"recurring override", func(e *storage.Event) {
+				e.SeriesID, e.RecurrenceID, e.RRule = series.ID, baseTime, "FREQ=DAILY"
+			}},
+			{"override without recurrence id", func(e *storage.Event) { e.SeriesID = series.ID }},
+			{"override of unknown series", func(e *storage.Event) { e.SeriesID, e.RecurrenceID = "unknown", baseTime }},
+			{"override of missing occurrence", func(e *storage.Event) {
+				e.SeriesID, e.RecurrenceID = series.ID, baseTime.AddDate(0, 0, 1)
+			}},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				event := newEvent("meeting", baseTime)
+				tc.modify(&event)
+				_, err := a.CreateEvent(ctx, "user", event)
+				require.ErrorIs(t, err, ErrInvalidEvent)
+			})
+		}
+	})
+	t.Run("import", func(t *testing.T) {
+		a := New(nopLogger{}, memorystorage.New())
+
+		other, err := a.CreateEvent(ctx, "other", newEvent("other", baseTime.AddDate(0, 1, 0)))
+		require.NoError(t, err)
+
+		single := newEvent("meeting", baseTime)
+		single.ID = "meeting@example.com"
+		series := newEvent("standup", baseTime.Add(-time.Hour))
+		series.ID = "standup@example.com"
+		series.Duration = 30 * time.Minute
+		series.RRule = "FREQ=DAILY"
+		override := newEvent("late standup", baseTime.AddDate(0, 0, 1).Add(time.Hour))
+		override.SeriesID = series.ID
+		override.RecurrenceID = baseTime.AddDate(0, 0, 1).Add(-time.Hour)
+		stolen := newEvent("stolen", baseTime.AddDate(0, 0, 10))
+		stolen.ID = other.ID
+		invalid := newEvent("", baseTime.AddDate(0, 0, 20))
+		noID := newEvent("no id", baseTime.AddDate(0, 0, 30))
+
+		// the override comes before its series
+		events := []storage.Event{override, single, series, stolen, invalid, noID}
+		result, err := a.ImportEvents(ctx, "user", events)
+		require.NoError(t, err)
+		require.Equal(t, 4, result.Created)
+		require.Zero(t, result.Updated)
+		require.Len(t, result.Failed, 2)
+		require.Equal(t, other.ID, result.Failed[0].ID)
+		require.ErrorIs(t, result.Failed[0].Err, storage.ErrEventAlreadyExists)
+		require.ErrorIs(t, result.Failed[1].Err, ErrInvalidEvent)
+
+		exported, err := a.ExportEvents(ctx, "user")
+		require.NoError(t, err)
+		require.Len(t, exported, 4)
+		require.Equal(t, series.ID, exported[0].ID)
+		require.Equal(t, single.ID, exported[1].ID)
+		require.Equal(t, "late standup", exported[2].Title)
+
+		// importing again updates the events, including overrides matched by recurrence id
+		single.Title = "renamed"
+		override.Title = "very late standup"
+		result, err = a.ImportEvents(ctx, "user", []storage.Event{exported[0], single, override})
+		require.NoError(t, err)
+		require.Equal(t, ImportResult{Updated: 3}, result)
+
+		reexported, err := a.ExportEvents(ctx, "user")
+		require.NoError(t, err)
+		require.Len(t, reexported, 4)
+		require.Equal(t, "renamed", reexported[1].Title)
+		require.Equal(t, exported[2].ID, reexported[2].ID)
+		require.Equal(t, "very late standup", reexported[2].Title)
+
+		got, err := a.GetEvent(ctx, "other", other.ID)
+		require.NoError(t, err)
+		require.Equal(t, other, got)
+
+		_, err = a.ImportEvents(ctx, "", events)
+		require.ErrorIs(t, err, ErrEmptyUserID)
+		_, err = a.ExportEvents(ctx, "")
+		require.ErrorIs(t, err, ErrEmptyUserID)
+	})
+	t.Run("time zones", func(t *testing.T) {
+		a := New(nopLogger{}, memorystorage.New())
+		newYork, err := time.LoadLocation("America/New_York")
+		require.NoError(t, err)
+
+		// Monday in New York, but Tuesday in UTC
+		standup := newEvent("standup", time.Date(2024, time.March, 11, 22, 0, 0, 0, newYork))
+		standup.RRule = "FREQ=WEEKLY;BYDAY=MO"
+		_, err = a.CreateEvent(ctx, "user", standup)
+		require.ErrorIs(t, err, ErrInvalidEvent)
+
+		standup.TimeZone = "America/New_York"
+		_, err = a.CreateEvent(ctx, "user", standup)
+		require.NoError(t, err)
+
+		// DST ends on November 3, so the day lasts 25 hours and has all the events
+		for _, hour := range []int{0, 1, 23} {
+			_, err := a.CreateEvent(ctx, "user", newEvent("event", time.Date(2024, time.November, 3, hour, 30, 0, 0, newYork)))
+			require.NoError(t, err)
+		}
+		events, err := a.ListEventsForDay(ctx, "user", time.Date(2024, time.November, 3, 12, 0, 0, 0, newYork))
+		require.NoError(t, err)
+		require.Len(t, events, 3)
+
+		standup.TimeZone = "Mars/Olympus"
+		_, err = a.CreateEvent(ctx, "user", standup)
+		require.ErrorIs(t, err, ErrInvalidEvent)
+	})
+
+	t.Run("free busy", func(t *testing.T) {
+		a := New(nopLogger{}, memorystorage.New())
+		at := func(day, hour, minute int) time.Time {
+			return time.Date(2024, time.March, day, hour, minute, 0, 0, time.UTC)
+		}
+
+		for _, start := range []time.Time{at(11, 10, 0), at(11, 11, 0)} {
+			_, err := a.CreateEvent(ctx, "alice", newEvent("meeting", start))
+			require.NoError(t, err)
+		}
+		lunch := newEvent("lunch", at(11, 13, 0))
+		lunch.Duration = 30 * time.Minute
+		lunch.RRule = "FREQ=DAILY"
+		_, err := a.CreateEvent(ctx, "bob", lunch)
+		require.NoError(t, err)
+		call := newEvent("call", at(11, 15, 10))
+		call.Duration = 10 * time.Minute
+		_, err = a.CreateEvent(ctx, "bob", call)
+		require.NoError(t, err)
+
+		busy, err := a.FreeBusy(ctx, "carol", []string{"alice", "bob", "alice"}, at(11, 10, 30), at(12, 0, 0))
+		require.NoError(t, err)
+		require.Equal(t, []UserBusy{
+			{UserID: "alice", Busy: []Interval{{at(11, 10, 30), at(11, 12, 0)}}},
+			{UserID: "bob", Busy: []Interval{{at(11, 13, 0), at(11, 13, 30)}, {at(11, 15, 10), at(11, 15, 20)}}},
+		}, busy)
+
+		query := SlotQuery{
+			UserIDs:   []string{"alice", "bob"},
+			Duration:  time.Hour,
+			From:      at(11, 0, 0),
+			To:        at(13, 0, 0),
+			WorkStart: 9 * time.Hour,
+			WorkEnd:   17 * time.Hour,
+			Limit:     5,
+		}
+		slots, err := a.FindSlots(ctx, "carol", query)
+		require.NoError(t, err)
+		require.Equal(t, []Interval{
+			{at(11, 9, 0), at(11, 10, 0)},
+			{at(11, 12, 0), at(11, 13, 0)},
+			{at(11, 13, 30), at(11, 14, 30)},
+			{at(11, 15, 30), at(11, 16, 30)},
+			{at(12, 9, 0), at(12, 10, 0)},
+		}, slots)
+
+		query.Weekdays = []time.Weekday{time.Tuesday}
+		query.Duration = 4 * time.Hour
+		slots, err = a.FindSlots(ctx, "carol", query)
+		require.NoError(t, err)
+		require.Equal(t, []Interval{{at(12, 9, 0), at(12, 13, 0)}}, slots)
+
+		// DST starts in New York on March 10, working hours follow the wall clock
+		newYork, err := time.LoadLocation("America/New_York")
+		require.NoError(t, err)
+		slots, err = a.FindSlots(ctx, "carol", SlotQuery{
+			UserIDs:   []string{"carol"},
+			Duration:  8 * time.Hour,
+			From:      time.Date(2024, time.March, 9, 0, 0, 0, 0, newYork),
+			To:        time.Date(2024, time.March, 11, 0, 0, 0, 0, newYork),
+			WorkStart: 9 * time.Hour,
+			WorkEnd:   17 * time.Hour,
+			Location:  newYork,
+		})
+		require.NoError(t, err)
+		require.Equal(t, []Interval{{at(9, 14, 0), at(9, 22, 0)}, {at(10, 13, 0), at(10, 21, 0)}}, slots)
+
+		_, err = a.FreeBusy(ctx, "", []string{"alice"}, at(11, 0, 0), at(12, 0, 0))
+		require.ErrorIs(t, err, ErrEmptyUserID)
+
+		invalid := []SlotQuery{
+			{Duration: time.Hour, From: at(11, 0, 0), To: at(12, 0, 0)},
+			{UserIDs: []string{"alice"}, Duration: time.Hour, From: at(12, 0, 0), To: at(11, 0, 0)},
+			{UserIDs: []string{"alice"}, Duration: time.Hour, From: at(11, 0, 0), To: at(11, 0, 0).AddDate(1, 0, 0)},
+			{UserIDs: []string{"alice"}, From: at(11, 0, 0), To: at(12, 0, 0)},
+			{UserIDs: []string{"alice"}, Duration: time.Hour, From: at(11, 0, 0), To: at(12, 0, 0),
+				WorkStart: 18 * time.Hour, WorkEnd: 9 * time.Hour},
+			{UserIDs: []string{"alice"}, Duration: time.Hour, From: at(11, 0, 0), To: at(12, 0, 0), Limit: -1},
+		}
+		for _, query := range invalid {
+			_, err := a.FindSlots(ctx, "carol", query)
+			require.ErrorIs(t, err, ErrInvalidQuery)
+		}
+	})
+
+	t.Run("webhooks", func(t *testing.T) {
+		s := memorystorage.New()
+		a := New(nopLogger{}, s)
+
+		webhook, err := a.CreateWebhook(ctx, "user", "https://example.com/hook")
+		require.NoError(t, err)
+		require.NotEmpty(t, webhook.ID)
+		require.Len(t, webhook.Secret, 2*secretSize)
+
+		for _, url := range []string{
+			"", "example.com/hook", "ftp://example.com/hook", "https:///hook", "http://[::1",
+			"http://localhost:8080/hook", "http://127.0.0.1/hook", "http://[::1]/hook", "http://10.0.0.1/hook",
+			"http://169.254.169.254/latest/meta-data",
+		} {
+			_, err := a.CreateWebhook(ctx, "user", url)
+			require.ErrorIs(t, err, ErrInvalidWebhook, url)
+		}
+
+		require.NoError(t, s.AddDelivery(ctx, storage.Delivery{ID: "1", WebhookID: webhook.ID, Attempt: 1}))
+		deliveries, err := a.ListDeliveries(ctx, "user", webhook.ID, 0)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		_, err = a.ListDeliveries(ctx, "other", webhook.ID, 0)
+		require.ErrorIs(t, err, storage.ErrWebhookNotFound)
+		_, err = a.ListDeliveries(ctx, "user", webhook.ID, -1)
+		require.ErrorIs(t, err, ErrInvalidQuery)
+
+		require.ErrorIs(t, a.DeleteWebhook(ctx, "other", webhook.ID), storage.ErrWebhookNotFound)
+		require.NoError(t, a.DeleteWebhook(ctx, "user", webhook.ID))
+		webhooks, err := a.ListWebhooks(ctx, "user")
+		require.NoError(t, err)
+		require.Empty(t, webhooks)
+
+		for range maxWebhooks {
+			_, err := a.CreateWebhook(ctx, "user", "https://example.com/hook")
+			require.NoError(t, err)
+		}
+		_, err = a.CreateWebhook(ctx, "user", "https://example.com/hook")
+		require.ErrorIs(t, err, ErrInvalidWebhook)
+	})
+
+	t.Run("history", func(t *testing.T) {
+		a := New(nopLogger{}, memorystorage.New())
+
+		created, err := a.CreateEvent(ctx, "user", newEvent("meeting", baseTime))
+		require.NoError(t, err)
+		moved := newEvent("meeting", baseTime.Add(time.Hour))
+		moved.Description = "moved"
+		moved, err = a.UpdateEvent(ctx, "user", created.ID, moved)
+		require.NoError(t, err)
+		require.NoError(t, a.DeleteEvent(ctx, "user", created.ID))
+
+		history, err := a.EventHistory(ctx, "user", created.ID)
+		require.NoError(t, err)
+		require.Len(t, history, 3)
+		for i, action := range []storage.Action{storage.ActionCreate, storage.ActionUpdate, storage.ActionDelete} {
+			require.Equal(t, int64(i+1), history[i].Revision)
+			require.Equal(t, action, history[i].Action)
+			require.Equal(t, "user", history[i].ActorID)
+		}
+		require.Equal(t, created, history[0].Event)
+		require.Equal(t, []storage.FieldChange{
+			{Field: "startAt", Before: "2024-03-11T10:00:00Z", After: "2024-03-11T11:00:00Z"},
+			{Field: "description", Before: "", After: "moved"},
+		}, history[1].Fields)
+		require.Equal(t, moved, history[2].Event)
+		require.Contains(t, history[2].Fields, storage.FieldChange{Field: "title", Before: "meeting"})
+
+		_, err = a.EventHistory(ctx, "other", created.ID)
+		require.ErrorIs(t, err, storage.ErrEventNotFound)
+		_, err = a.RestoreEvent(ctx, "other", created.ID, 1)
+		require.ErrorIs(t, err, storage.ErrEventNotFound)
+		_, err = a.RestoreEvent(ctx, "user", created.ID, 4)
+		require.ErrorIs(t, err, storage.ErrRevisionNotFound)
+		_, err = a.RestoreEvent(ctx, "user", created.ID, 3)
+		require.ErrorIs(t, err, ErrInvalidQuery)
+
+		// the deleted event is created again
+		restored, err := a.RestoreEvent(ctx, "user", created.ID, 2)
+		require.NoError(t, err)
+		require.Equal(t, moved.StartAt, restored.StartAt)
+		require.Equal(t, int64(1), restored.Version)
+
+		// the existing event is updated
+		restored, err = a.RestoreEvent(ctx, "user", created.ID, 1)
+		require.NoError(t, err)
+		require.Equal(t, created.StartAt, restored.StartAt)
+		require.Empty(t, restored.Description)
+		require.Equal(t, int64(2), restored.Version)
+
+		history, err = a.EventHistory(ctx, "user", created.ID)
+		require.NoError(t, err)
+		require.Len(t, history, 5)
+		require.Equal(t, storage.ActionCreate, history[3].Action)
+		require.Equal(t, int64(2), history[3].RestoredFrom)
+		require.Equal(t, storage.ActionUpdate, history[4].Action)
+		require.Equal(t, int64(1), history[4].RestoredFrom)
+	})
+
+	t.Run("attendees", func(t *testing.T) {
+		a := New(nopLogger{}, memorystorage.New())
+
+		meeting, err := a.CreateEvent(ctx, "user", newEvent("meeting", baseTime))
+		require.NoError(t, err)
+
+		_, err = a.InviteAttendee(ctx, "user", meeting.ID, "user")
+		require.ErrorIs(t, err, ErrInvalidAttendee)
+		_, err = a.InviteAttendee(ctx, "user", meeting.ID, "")
+		require.ErrorIs(t, err, ErrInvalidAttendee)
+		_, err = a.InviteAttendee(ctx, "alice", meeting.ID, "bob")
+		require.ErrorIs(t, err, storage.ErrEventNotFound)
+
+		_, err = a.InviteAttendee(ctx, "user", meeting.ID, "alice")
+		require.NoError(t, err)
+		meeting, err = a.InviteAttendee(ctx, "user", meeting.ID, "bob")
+		require.NoError(t, err)
+		// inviting again changes nothing
+		meeting, err = a.InviteAttendee(ctx, "user", meeting.ID, "bob")
+		require.NoError(t, err)
+		require.Equal(t, []storage.Attendee{
+			{UserID: "alice", Status: storage.StatusNeedsAction},
+			{UserID: "bob", Status: storage.StatusNeedsAction},
+		}, meeting.Attendees)
+		require.Equal(t, int64(3), meeting.Version)
+
+		// attendees see the event, but can't change it
+		got, err := a.GetEvent(ctx, "alice", meeting.ID)
+		require.NoError(t, err)
+		require.Equal(t, meeting, got)
+		events, err := a.ListEventsForDay(ctx, "alice", baseTime)
+		require.NoError(t, err)
+		require.Equal(t, []storage.Event{meeting}, events)
+		_, err = a.UpdateEvent(ctx, "alice", meeting.ID, newEvent("stolen", baseTime))
+		require.ErrorIs(t, err, storage.ErrEventNotFound)
+		require.ErrorIs(t, a.DeleteEvent(ctx, "alice", meeting.ID), storage.ErrEventNotFound)
+		_, err = a.InviteAttendee(ctx, "alice", meeting.ID, "carol")
+		require.ErrorIs(t, err, storage.ErrEventNotFound)
+
+		_, err = a.RespondToInvitation(ctx, "alice", meeting.ID, "maybe")
+		require.ErrorIs(t, err, ErrInvalidAttendee)
+		_, err = a.RespondToInvitation(ctx, "user", meeting.ID, storage.StatusAccepted)
+		require.ErrorIs(t, err, storage.ErrEventNotFound)
+		_, err = a.RespondToInvitation(ctx, "carol", meeting.ID, storage.StatusAccepted)
+		require.ErrorIs(t, err, storage.ErrEventNotFound)
+
+		meeting, err = a.RespondToInvitation(ctx, "alice", meeting.ID, storage.StatusAccepted)
+		require.NoError(t, err)
+		meeting, err = a.RespondToInvitation(ctx, "bob", meeting.ID, storage.StatusDeclined)
+		require.NoError(t, err)
+		require.Equal(t, []storage.Attendee{
+			{UserID: "alice", Status: storage.StatusAccepted},
+			{UserID: "bob", Status: storage.StatusDeclined},
+		}, meeting.Attendees)
+
+		// updates by the owner keep the attendees
+		updated, err := a.UpdateEvent(ctx, "user", meeting.ID, newEvent("renamed", baseTime))
+		require.NoError(t, err)
+		require.Equal(t, meeting.Attendees, updated.Attendees)
+
+		// only the accepted invitation takes the time
+		busy, err := a.FreeBusy(ctx, "user", []string{"alice", "bob"}, baseTime, baseTime.Add(24*time.Hour))
+		require.NoError(t, err)
+		require.Len(t, busy[0].Busy, 1)
+		require.Empty(t, busy[1].Busy)
+
+		history, err := a.EventHistory(ctx, "user", meeting.ID)
+		require.NoError(t, err)
+		require.Equal(t, "bob", history[len(history)-2].ActorID)
+		require.Equal(t, []storage.FieldChange{{
+			Field:  "attendees",
+			Before: "alice:accepted,bob:needs-action",
+			After:  "alice:accepted,bob:declined",
+		}}, history[len(history)-2].Fields)
+
+		meeting, err = a.RemoveAttendee(ctx, "user", meeting.ID, "bob")
+		require.NoError(t, err)
+		require.Equal(t, []storage.Attendee{{UserID: "alice", Status: storage.StatusAccepted}}, meeting.Attendees)
+		_, err = a.RemoveAttendee(ctx, "user", meeting.ID, "bob")
+		require.ErrorIs(t, err, storage.ErrAttendeeNotFound)
+		_, err = a.GetEvent(ctx, "bob", meeting.ID)
+		require.ErrorIs(t, err, storage.ErrEventNotFound)
+
+		t.Run("series", func(t *testing.T) {
+			series := newEvent("standup", baseTime.AddDate(0, 0, 1))
+			series.RRule = "FREQ=DAILY"
+			series, err := a.CreateEvent(ctx, "user", series)
+			require.NoError(t, err)
+			override := newEvent("moved", baseTime.AddDate(0, 0, 2).Add(time.Hour))
+			override.SeriesID = series.ID
+			override.RecurrenceID = baseTime.AddDate(0, 0, 2)
+			override, err = a.CreateEvent(ctx, "user", override)
+			require.NoError(t, err)
+
+			_, err = a.InviteAttendee(ctx, "user", override.ID, "alice")
+			require.ErrorIs(t, err, ErrInvalidAttendee)
+			_, err = a.InviteAttendee(ctx, "user", series.ID, "alice")
+			require.NoError(t, err)
+			_, err = a.RespondToInvitation(ctx, "alice", override.ID, storage.StatusAccepted)
+			require.ErrorIs(t, err, ErrInvalidAttendee)
+			_, err = a.RespondToInvitation(ctx, "alice", series.ID, storage.StatusTentative)
+			require.NoError(t, err)
+
+			events, err := a.ListEventsForDay(ctx, "alice", baseTime.AddDate(0, 0, 2))
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			require.Equal(t, override.ID, events[0].ID)
+			require.Equal(t, []storage.Attendee{{UserID: "alice", Status: storage.StatusTentative}}, events[0].Attendees)
+
+			// new overrides get the attendees of the series as well
+			another := newEvent("moved again", baseTime.AddDate(0, 0, 3).Add(time.Hour))
+			another.SeriesID = series.ID
+			another.RecurrenceID = baseTime.AddDate(0, 0, 3)
+			another, err = a.CreateEvent(ctx, "user", another)
+			require.NoError(t, err)
+			require.Equal(t, events[0].Attendees, another.Attendees)
+		})
+	})
+}
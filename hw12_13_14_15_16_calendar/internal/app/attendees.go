@@ -0,0 +1,171 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	maxAttendees = 100
+	// attendeeAttempts limits attempts to change attendees of an event being updated concurrently.
+	attendeeAttempts = 3
+)
+
+var ErrInvalidAttendee = errors.New("invalid attendee")
+
+// InviteAttendee adds the user to the attendees of the event, the invitation needs an action
+// of the attendee then. Only the owner invites, inviting an attendee again changes nothing.
+func (a *App) InviteAttendee(ctx context.Context, userID, id, attendeeID string) (storage.Event, error) {
+	switch {
+	case attendeeID == "":
+		return storage.Event{}, fmt.Errorf("%w: user id of the attendee is empty", ErrInvalidAttendee)
+	case attendeeID == userID:
+		return storage.Event{}, fmt.Errorf("%w: the owner can't attend the own event", ErrInvalidAttendee)
+	}
+
+	event, err := a.ownEvent(ctx, userID, id)
+	if err != nil {
+		return storage.Event{}, err
+	}
+	event, err = a.changeAttendees(ctx, userID, event, func(attendees []storage.Attendee) ([]storage.Attendee, error) {
+		if slices.ContainsFunc(attendees, isAttendee(attendeeID)) {
+			return attendees, nil
+		}
+		if len(attendees) >= maxAttendees {
+			return nil, fmt.Errorf("%w: no more than %d attendees are allowed", ErrInvalidAttendee, maxAttendees)
+		}
+		return append(attendees, storage.Attendee{UserID: attendeeID, Status: storage.StatusNeedsAction}), nil
+	})
+	if err != nil {
+		return storage.Event{}, err
+	}
+
+	a.logger.InfoContext(ctx, "attendee invited", "event_id", id, "user_id", userID, "attendee_id", attendeeID)
+	return event, nil
+}
+
+// RemoveAttendee withdraws the invitation of the user to the event. Only the owner removes attendees.
+func (a *App) RemoveAttendee(ctx context.Context, userID, id, attendeeID string) (storage.Event, error) {
+	event, err := a.ownEvent(ctx, userID, id)
+	if err != nil {
+		return storage.Event{}, err
+	}
+	event, err = a.changeAttendees(ctx, userID, event, func(attendees []storage.Attendee) ([]storage.Attendee, error) {
+		i := slices.IndexFunc(attendees, isAttendee(attendeeID))
+		if i < 0 {
+			return nil, storage.ErrAttendeeNotFound
+		}
+		return slices.Delete(attendees, i, i+1), nil
+	})
+	if err != nil {
+		return storage.Event{}, err
+	}
+
+	a.logger.InfoContext(ctx, "attendee removed", "event_id", id, "user_id", userID, "attendee_id", attendeeID)
+	return event, nil
+}
+
+// RespondToInvitation sets the status of the participation of the user in the event.
+// The event doesn't exist for anyone but its attendees.
+func (a *App) RespondToInvitation(ctx context.Context, userID, id string, status storage.AttendeeStatus,
+) (storage.Event, error) {
+	if !status.Valid() || status == storage.StatusNeedsAction {
+		return storage.Event{}, fmt.Errorf("%w: unknown response %q", ErrInvalidAttendee, status)
+	}
+
+	event, err := a.GetEvent(ctx, userID, id)
+	if err != nil {
+		return storage.Event{}, err
+	}
+	event, err = a.changeAttendees(ctx, userID, event, func(attendees []storage.Attendee) ([]storage.Attendee, error) {
+		i := slices.IndexFunc(attendees, isAttendee(userID))
+		if i < 0 {
+			return nil, storage.ErrEventNotFound
+		}
+		attendees[i].Status = status
+		return attendees, nil
+	})
+	if err != nil {
+		return storage.Event{}, err
+	}
+
+	a.logger.InfoContext(ctx, "invitation answered", "event_id", id, "user_id", userID, "status", status)
+	return event, nil
+}
+
+// changeAttendees applies the change to the attendees of the event on behalf of the actor.
+// Overrides have the attendees of their series, so the ones of a series are copied to its overrides.
+func (a *App) changeAttendees(ctx context.Context, actorID string, event storage.Event,
+	change func(attendees []storage.Attendee) ([]storage.Attendee, error),
+) (storage.Event, error) {
+	if event.SeriesID != "" {
+		return storage.Event{}, fmt.Errorf("%w: attendees of an override are the ones of its series", ErrInvalidAttendee)
+	}
+
+	event, err := a.updateAttendees(ctx, actorID, event, func(current storage.Event) ([]storage.Attendee, error) {
+		return change(slices.Clone(current.Attendees))
+	})
+	if err != nil || !event.Recurring() {
+		return event, err
+	}
+
+	events, err := a.storage.ListUserEvents(ctx, event.UserID)
+	if err != nil {
+		return storage.Event{}, a.storageError(ctx, "list user events", err)
+	}
+	for _, override := range events {
+		if override.SeriesID != event.ID {
+			continue
+		}
+		_, err := a.updateAttendees(ctx, actorID, override, func(storage.Event) ([]storage.Attendee, error) {
+			return event.Attendees, nil
+		})
+		if err != nil {
+			return storage.Event{}, err
+		}
+	}
+	return event, nil
+}
+
+// updateAttendees stores the attendees the change returns for the event. Attendees are changed
+// by different users, so a concurrent update makes the change start over with the fresh event
+// rather than fail.
+func (a *App) updateAttendees(ctx context.Context, actorID string, event storage.Event,
+	change func(event storage.Event) ([]storage.Attendee, error),
+) (storage.Event, error) {
+	for attempt := 1; ; attempt++ {
+		attendees, err := change(event)
+		if err != nil {
+			return storage.Event{}, err
+		}
+		if slices.Equal(attendees, event.Attendees) {
+			return event, nil
+		}
+
+		updated := event
+		updated.Attendees = attendees
+		err = a.storage.UpdateEvent(ctx, event.ID, updated, newAudit(actorID))
+		if errors.Is(err, storage.ErrVersionConflict) && attempt < attendeeAttempts {
+			if event, err = a.storage.GetEvent(ctx, event.ID); err != nil {
+				return storage.Event{}, a.storageError(ctx, "get event", err)
+			}
+			continue
+		}
+		if err != nil {
+			return storage.Event{}, a.storageError(ctx, "update attendees", err)
+		}
+
+		updated.Version++
+		return updated, nil
+	}
+}
+
+func isAttendee(userID string) func(attendee storage.Attendee) bool {
+	return func(attendee storage.Attendee) bool {
+		return attendee.UserID == userID
+	}
+}
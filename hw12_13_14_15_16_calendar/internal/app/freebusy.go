@@ -0,0 +1,265 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	maxQueryUsers    = 50
+	maxQueryPeriod   = 92 * 24 * time.Hour
+	defaultSlotLimit = 10
+	maxSlotLimit     = 100
+	// slotStep aligns starts of the proposed slots relative to the beginning of working hours.
+	slotStep = 15 * time.Minute
+)
+
+var ErrInvalidQuery = errors.New("invalid query")
+
+// Interval is the interval of time [Start, End) in UTC.
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+type UserBusy struct {
+	UserID string
+	Busy   []Interval
+}
+
+// SlotQuery describes the meeting to find a time for.
+type SlotQuery struct {
+	UserIDs  []string
+	Duration time.Duration
+	From     time.Time
+	To       time.Time
+	// WorkStart and WorkEnd are the working hours as the wall clock time since midnight
+	// in Location. Zero WorkEnd means the end of the day.
+	WorkStart time.Duration
+	WorkEnd   time.Duration
+	Location  *time.Location
+	// Weekdays the meeting may take place on, any day if empty.
+	Weekdays []time.Weekday
+	// Limit is the maximum number of slots, defaultSlotLimit if zero.
+	Limit int
+}
+
+// FreeBusy returns busy intervals of the users within [from, to). Intervals of every user are
+// merged and ordered, so the owners' events themselves aren't disclosed.
+func (a *App) FreeBusy(ctx context.Context, userID string, userIDs []string, from, to time.Time) ([]UserBusy, error) {
+	if userID == "" {
+		return nil, ErrEmptyUserID
+	}
+	userIDs = uniqueStrings(userIDs)
+	if err := validatePeriod(userIDs, from, to); err != nil {
+		return nil, err
+	}
+
+	result := make([]UserBusy, 0, len(userIDs))
+	for _, id := range userIDs {
+		busy, err := a.busy(ctx, id, from, to)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, UserBusy{UserID: id, Busy: busy})
+	}
+	return result, nil
+}
+
+// FindSlots proposes the earliest slots within the query period in which all the users are free.
+// Slots don't overlap each other, several of them may be proposed in a long free interval.
+func (a *App) FindSlots(ctx context.Context, userID string, query SlotQuery) ([]Interval, error) {
+	if userID == "" {
+		return nil, ErrEmptyUserID
+	}
+	query, err := prepareSlotQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var busy []Interval
+	for _, id := range query.UserIDs {
+		userBusy, err := a.busy(ctx, id, query.From, query.To)
+		if err != nil {
+			return nil, err
+		}
+		busy = append(busy, userBusy...)
+	}
+	busy = mergeIntervals(busy)
+
+	var slots []Interval
+	first, _ := storage.DayPeriod(query.From.In(query.Location))
+	for day := first; day.Before(query.To); day = day.AddDate(0, 0, 1) {
+		if len(query.Weekdays) > 0 && !slices.Contains(query.Weekdays, day.Weekday()) {
+			continue
+		}
+
+		work := Interval{Start: wallClock(day, query.WorkStart), End: wallClock(day, query.WorkEnd)}
+		for _, free := range subtractIntervals(work, busy) {
+			// the window is clipped to the period, the alignment of slots is kept though
+			free.Start = maxTime(free.Start, query.From)
+			free.End = minTime(free.End, query.To)
+
+			start := work.Start.Add((free.Start.Sub(work.Start) + slotStep - 1) / slotStep * slotStep)
+			for ; !start.Add(query.Duration).After(free.End); start = start.Add(query.Duration) {
+				slots = append(slots, Interval{Start: start.UTC(), End: start.Add(query.Duration).UTC()})
+				if len(slots) == query.Limit {
+					return slots, nil
+				}
+			}
+		}
+	}
+	return slots, nil
+}
+
+// busy returns merged intervals occupied by events of the user clipped to [from, to),
+// including the events the user attends.
+func (a *App) busy(ctx context.Context, userID string, from, to time.Time) ([]Interval, error) {
+	events, err := a.storage.ListEvents(ctx, userID, from, to)
+	if err != nil {
+		return nil, a.storageError(ctx, "list events", err)
+	}
+
+	intervals := make([]Interval, 0, len(events))
+	for _, event := range events {
+		// invitations the user hasn't accepted don't take the time
+		if attendee, ok := event.Attendee(userID); ok &&
+			attendee.Status != storage.StatusAccepted && attendee.Status != storage.StatusTentative {
+			continue
+		}
+		intervals = append(intervals, Interval{
+			Start: maxTime(event.StartAt, from).UTC(),
+			End:   minTime(event.EndAt(), to).UTC(),
+		})
+	}
+	return mergeIntervals(intervals), nil
+}
+
+func validatePeriod(userIDs []string, from, to time.Time) error {
+	var errs []error
+
+	if len(userIDs) == 0 {
+		errs = append(errs, fmt.Errorf("%w: users are not set", ErrInvalidQuery))
+	} else if len(userIDs) > maxQueryUsers {
+		errs = append(errs, fmt.Errorf("%w: no more than %d users are allowed", ErrInvalidQuery, maxQueryUsers))
+	}
+	if slices.Contains(userIDs, "") {
+		errs = append(errs, fmt.Errorf("%w: user id is empty", ErrInvalidQuery))
+	}
+	if from.IsZero() || to.IsZero() {
+		errs = append(errs, fmt.Errorf("%w: period is not set", ErrInvalidQuery))
+	} else if !from.Before(to) {
+		errs = append(errs, fmt.Errorf("%w: period must end after it starts", ErrInvalidQuery))
+	} else if to.Sub(from) > maxQueryPeriod {
+		errs = append(errs, fmt.Errorf("%w: period is longer than %s", ErrInvalidQuery, maxQueryPeriod))
+	}
+
+	return errors.Join(errs...)
+}
+
+func prepareSlotQuery(query SlotQuery) (SlotQuery, error) {
+	query.UserIDs = uniqueStrings(query.UserIDs)
+	errs := []error{validatePeriod(query.UserIDs, query.From, query.To)}
+
+	if query.Location == nil {
+		query.Location = time.UTC
+	}
+	if query.WorkEnd == 0 {
+		query.WorkEnd = 24 * time.Hour
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultSlotLimit
+	}
+	query.Limit = min(query.Limit, maxSlotLimit)
+
+	if query.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("%w: duration must be positive", ErrInvalidQuery))
+	}
+	if query.WorkStart < 0 || query.WorkEnd > 24*time.Hour || query.WorkStart >= query.WorkEnd {
+		errs = append(errs, fmt.Errorf("%w: working hours must be within a day", ErrInvalidQuery))
+	}
+	if query.Limit < 0 {
+		errs = append(errs, fmt.Errorf("%w: limit must not be negative", ErrInvalidQuery))
+	}
+
+	return query, errors.Join(errs...)
+}
+
+// mergeIntervals orders the intervals and joins the overlapping and adjacent ones.
+func mergeIntervals(intervals []Interval) []Interval {
+	slices.SortFunc(intervals, func(a, b Interval) int {
+		return a.Start.Compare(b.Start)
+	})
+
+	merged := make([]Interval, 0, len(intervals))
+	for _, interval := range intervals {
+		if !interval.Start.Before(interval.End) {
+			continue
+		}
+		if last := len(merged) - 1; last >= 0 && !interval.Start.After(merged[last].End) {
+			merged[last].End = maxTime(merged[last].End, interval.End)
+			continue
+		}
+		merged = append(merged, interval)
+	}
+	return merged
+}
+
+// subtractIntervals returns the parts of the interval free of the merged busy intervals.
+func subtractIntervals(interval Interval, busy []Interval) []Interval {
+	var free []Interval
+	start := interval.Start
+	for _, b := range busy {
+		if !b.End.After(start) {
+			continue
+		}
+		if !b.Start.Before(interval.End) {
+			break
+		}
+		if b.Start.After(start) {
+			free = append(free, Interval{Start: start, End: b.Start})
+		}
+		start = b.End
+	}
+	if start.Before(interval.End) {
+		free = append(free, Interval{Start: start, End: interval.End})
+	}
+	return free
+}
+
+// uniqueStrings returns the values without duplicates keeping their order.
+func uniqueStrings(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if !slices.Contains(result, value) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// wallClock returns the moment the clock shows the time since midnight on the day,
+// so that working hours don't move across DST transitions.
+func wallClock(day time.Time, clock time.Duration) time.Time {
+	year, month, date := day.Date()
+	return time.Date(year, month, date, 0, 0, 0, int(clock), day.Location())
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
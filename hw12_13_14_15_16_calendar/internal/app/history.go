@@ -0,0 +1,72 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+)
+
+// EventHistory returns the audit log of the event, the earliest change first. The log outlives
+// the event, so the history of a deleted event is available to its owner as well.
+func (a *App) EventHistory(ctx context.Context, userID, id string) ([]storage.Change, error) {
+	if userID == "" {
+		return nil, ErrEmptyUserID
+	}
+
+	changes, err := a.storage.ListChanges(ctx, id)
+	if err != nil {
+		return nil, a.storageError(ctx, "list event changes", err)
+	}
+	if len(changes) == 0 || changes[0].UserID != userID {
+		return nil, storage.ErrEventNotFound
+	}
+	return changes, nil
+}
+
+// RestoreEvent brings the event back to the state it had after the given revision.
+// A deleted event is created again with the same ID. The restoration is a change
+// of its own, so it gets into the audit log as well.
+func (a *App) RestoreEvent(ctx context.Context, userID, id string, revision int64) (storage.Event, error) {
+	changes, err := a.EventHistory(ctx, userID, id)
+	if err != nil {
+		return storage.Event{}, err
+	}
+	i := slices.IndexFunc(changes, func(c storage.Change) bool { return c.Revision == revision })
+	if i < 0 {
+		return storage.Event{}, storage.ErrRevisionNotFound
+	}
+	if changes[i].Action == storage.ActionDelete {
+		return storage.Event{}, fmt.Errorf("%w: revision %d is a deletion of the event", ErrInvalidQuery, revision)
+	}
+
+	event := changes[i].Event
+	event.ID = id
+	event.UserID = userID
+	// the state is restored whatever has happened to the event since
+	event.Version = 0
+
+	_, err = a.storage.GetEvent(ctx, id)
+	switch {
+	case errors.Is(err, storage.ErrEventNotFound):
+		event, err = a.createEvent(ctx, event, revision)
+	case err != nil:
+		return storage.Event{}, a.storageError(ctx, "get event", err)
+	default:
+		event, err = a.updateEvent(ctx, userID, id, event, revision)
+	}
+	if err != nil {
+		return storage.Event{}, err
+	}
+
+	a.logger.InfoContext(ctx, "event restored", "event_id", id, "user_id", userID, "revision", revision)
+	return event, nil
+}
+
+// newAudit returns the audit of the change the actor makes now.
+func newAudit(actorID string) storage.Audit {
+	return storage.Audit{ActorID: actorID, ChangedAt: time.Now().UTC()}
+}
@@ -0,0 +1,169 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+	"github.com/google/uuid"
+)
+
+// ImportResult tells what happened to the imported events. A rejected event doesn't stop
+// the import, the reason is reported in Failed instead.
+type ImportResult struct {
+	Created int
+	Updated int
+	Failed  []ImportError
+}
+
+type ImportError struct {
+	// ID is the ID of the rejected event or, for an override, of its series.
+	ID           string
+	RecurrenceID time.Time
+	Err          error
+}
+
+type overrideKey struct {
+	seriesID     string
+	recurrenceID int64
+}
+
+func keyOf(event storage.Event) overrideKey {
+	return overrideKey{event.SeriesID, event.RecurrenceID.Unix()}
+}
+
+// ImportEvents creates the events of the user or updates them if they already exist, so importing
+// the same events again doesn't duplicate them. An event is matched by its ID and an override
+// by its series and recurrence ID, IDs of overrides being ignored. Events without an ID get new ones.
+// Reminders of an updated event keep their channels unless the imported ones have any, see keepChannels.
+func (a *App) ImportEvents(ctx context.Context, userID string, events []storage.Event) (ImportResult, error) {
+	if userID == "" {
+		return ImportResult{}, ErrEmptyUserID
+	}
+
+	existing, err := a.storage.ListUserEvents(ctx, userID)
+	if err != nil {
+		return ImportResult{}, a.storageError(ctx, "list user events", err)
+	}
+	stored := make(map[string]storage.Event, len(existing))
+	overrides := make(map[overrideKey]string)
+	for _, event := range existing {
+		stored[event.ID] = event
+		if event.SeriesID != "" {
+			overrides[keyOf(event)] = event.ID
+		}
+	}
+
+	// series go first, so their overrides can refer to them
+	events = slices.Clone(events)
+	slices.SortStableFunc(events, func(a, b storage.Event) int {
+		return compareBool(a.SeriesID != "", b.SeriesID != "")
+	})
+
+	var result ImportResult
+	for _, event := range events {
+		event.UserID = userID
+		// imported events replace the stored ones whatever their version is
+		event.Version = 0
+		switch {
+		case event.SeriesID != "":
+			event.ID = overrides[keyOf(event)]
+			if event.ID == "" {
+				event.ID = uuid.NewString()
+			}
+		case event.ID == "":
+			event.ID = uuid.NewString()
+		}
+
+		var err error
+		if old, ok := stored[event.ID]; ok {
+			event = keepChannels(event, old)
+			_, err = a.UpdateEvent(ctx, userID, event.ID, event)
+			if err == nil {
+				result.Updated++
+			}
+		} else {
+			_, err = a.createEvent(ctx, event, 0)
+			if err == nil {
+				result.Created++
+			}
+		}
+
+		switch {
+		case err == nil:
+			stored[event.ID] = event
+			if event.SeriesID != "" {
+				overrides[keyOf(event)] = event.ID
+			}
+		case errors.Is(err, ErrInvalidEvent), errors.Is(err, storage.ErrDateBusy),
+			errors.Is(err, storage.ErrEventAlreadyExists), errors.Is(err, storage.ErrEventNotFound):
+			id := event.ID
+			if event.SeriesID != "" {
+				id = event.SeriesID
+			}
+			result.Failed = append(result.Failed, ImportError{ID: id, RecurrenceID: event.RecurrenceID, Err: err})
+		default:
+			return result, err
+		}
+	}
+
+	a.logger.InfoContext(ctx, "events imported", "user_id", userID,
+		"created", result.Created, "updated", result.Updated, "failed", len(result.Failed))
+	return result, nil
+}
+
+// keepChannels gives the reminders of the imported event the channels of the stored reminders
+// with the same offsets, unless the imported reminders have channels of their own: iCalendar
+// has no counterpart for them, so clients may drop the property they are exported in.
+func keepChannels(event, stored storage.Event) storage.Event {
+	reminders := slices.Clone(event.ActiveReminders())
+	if slices.ContainsFunc(reminders, func(r storage.Reminder) bool { return r.Channel != "" }) {
+		return event
+	}
+
+	previous := stored.ActiveReminders()
+	taken := make([]bool, len(previous))
+	var changed bool
+	for i := range reminders {
+		for j, p := range previous {
+			if taken[j] || p.Channel == "" || p.Before != reminders[i].Before {
+				continue
+			}
+			taken[j] = true
+			reminders[i].Channel = p.Channel
+			changed = true
+			break
+		}
+	}
+	if changed {
+		event.NotifyBefore = 0
+		event.Reminders = reminders
+	}
+	return event
+}
+
+// ExportEvents returns all the events of the user ordered by start time. Series aren't expanded.
+func (a *App) ExportEvents(ctx context.Context, userID string) ([]storage.Event, error) {
+	if userID == "" {
+		return nil, ErrEmptyUserID
+	}
+
+	events, err := a.storage.ListUserEvents(ctx, userID)
+	if err != nil {
+		return nil, a.storageError(ctx, "list user events", err)
+	}
+	return events, nil
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
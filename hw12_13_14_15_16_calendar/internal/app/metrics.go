@@ -0,0 +1,11 @@
+package app
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var eventsCreated = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "calendar_events_created_total",
+	Help: "Number of events created through the API or imported.",
+})
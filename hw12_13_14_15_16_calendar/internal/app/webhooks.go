@@ -0,0 +1,138 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/netguard"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+	"github.com/google/uuid"
+)
+
+const (
+	maxWebhooks          = 10
+	secretSize           = 32
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// CreateWebhook registers the URL notifications of the user are posted to. The returned webhook
+// has a generated secret, which the receiver uses to check signatures of the payloads.
+func (a *App) CreateWebhook(ctx context.Context, userID, rawURL string) (storage.Webhook, error) {
+	if userID == "" {
+		return storage.Webhook{}, ErrEmptyUserID
+	}
+	if err := validateWebhookURL(rawURL); err != nil {
+		return storage.Webhook{}, err
+	}
+
+	webhooks, err := a.storage.ListWebhooks(ctx, userID)
+	if err != nil {
+		return storage.Webhook{}, a.storageError(ctx, "list webhooks", err)
+	}
+	if len(webhooks) >= maxWebhooks {
+		return storage.Webhook{}, fmt.Errorf("%w: no more than %d webhooks are allowed", ErrInvalidWebhook, maxWebhooks)
+	}
+
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return storage.Webhook{}, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	webhook := storage.Webhook{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		URL:       rawURL,
+		Secret:    hex.EncodeToString(secret),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := a.storage.CreateWebhook(ctx, webhook); err != nil {
+		return storage.Webhook{}, a.storageError(ctx, "create webhook", err)
+	}
+
+	a.logger.InfoContext(ctx, "webhook created", "webhook_id", webhook.ID, "user_id", userID)
+	return webhook, nil
+}
+
+func (a *App) ListWebhooks(ctx context.Context, userID string) ([]storage.Webhook, error) {
+	if userID == "" {
+		return nil, ErrEmptyUserID
+	}
+
+	webhooks, err := a.storage.ListWebhooks(ctx, userID)
+	if err != nil {
+		return nil, a.storageError(ctx, "list webhooks", err)
+	}
+	return webhooks, nil
+}
+
+func (a *App) DeleteWebhook(ctx context.Context, userID, id string) error {
+	if _, err := a.getWebhook(ctx, userID, id); err != nil {
+		return err
+	}
+
+	if err := a.storage.DeleteWebhook(ctx, id); err != nil {
+		return a.storageError(ctx, "delete webhook", err)
+	}
+
+	a.logger.InfoContext(ctx, "webhook deleted", "webhook_id", id, "user_id", userID)
+	return nil
+}
+
+// ListDeliveries returns the latest delivery attempts of the webhook, the latest first.
+// Zero limit means defaultDeliveryLimit.
+func (a *App) ListDeliveries(ctx context.Context, userID, webhookID string, limit int) ([]storage.Delivery, error) {
+	if _, err := a.getWebhook(ctx, userID, webhookID); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case limit < 0:
+		return nil, fmt.Errorf("%w: limit must not be negative", ErrInvalidQuery)
+	case limit == 0:
+		limit = defaultDeliveryLimit
+	}
+	deliveries, err := a.storage.ListDeliveries(ctx, webhookID, min(limit, maxDeliveryLimit))
+	if err != nil {
+		return nil, a.storageError(ctx, "list deliveries", err)
+	}
+	return deliveries, nil
+}
+
+// getWebhook returns the webhook of the user, for anyone else it doesn't exist.
+func (a *App) getWebhook(ctx context.Context, userID, id string) (storage.Webhook, error) {
+	if userID == "" {
+		return storage.Webhook{}, ErrEmptyUserID
+	}
+
+	webhook, err := a.storage.GetWebhook(ctx, id)
+	if err != nil {
+		return storage.Webhook{}, a.storageError(ctx, "get webhook", err)
+	}
+	if webhook.UserID != userID {
+		return storage.Webhook{}, storage.ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// validateWebhookURL rejects URLs which aren't absolute http(s) ones or point to the internal network.
+// Names are resolved only by the sender, which refuses internal addresses when it connects.
+func validateWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidWebhook, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	if err := netguard.CheckHost(u.Hostname()); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidWebhook, err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+// Package config loads configuration of calendar processes from YAML or TOML files
+// and environment variables. Every process describes its own configuration structure
+// composed of the sections declared here.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrUnknownFormat = errors.New("unknown configuration file format")
+
+// Validator is implemented by configurations checking their own values.
+type Validator interface {
+	Validate() error
+}
+
+// Load fills cfg, which must be a pointer to a struct, from the file at path.
+// The format of the file is chosen by its extension. Values from the file are overridden
+// by environment variables named after the keys, e.g. PREFIX_LOGGER_LEVEL for logger.level.
+// If cfg implements Validator, the result is validated.
+//
+// Load doesn't stop at the first problem, all of them are reported within the returned error.
+func Load(path, envPrefix string, cfg any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if err := decode(path, data, cfg); err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	errs := applyEnv(envPrefix, cfg, os.LookupEnv)
+	if v, ok := cfg.(Validator); ok {
+		errs = append(errs, v.Validate())
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid config:\n%w", err)
+	}
+	return nil
+}
+
+func decode(path string, data []byte, cfg any) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return err
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, 0, len(undecoded))
+			for _, key := range undecoded {
+				keys = append(keys, key.String())
+			}
+			return fmt.Errorf("unknown keys: %s", strings.Join(keys, ", "))
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, filepath.Ext(path))
+	}
+	return nil
+}
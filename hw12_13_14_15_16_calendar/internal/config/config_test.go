@@ -0,0 +1,156 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	Logger    LoggerConf    `yaml:"logger" toml:"logger"`
+	Storage   StorageConf   `yaml:"storage" toml:"storage"`
+	HTTP      ServerConf    `yaml:"http" toml:"http"`
+	Scheduler SchedulerConf `yaml:"scheduler" toml:"scheduler"`
+	Origins   []string      `yaml:"origins" toml:"origins"`
+}
+
+func (c *testConfig) Validate() error {
+	return errors.Join(
+		Section("logger", c.Logger.Validate()),
+		Section("storage", c.Storage.Validate()),
+		Section("http", c.HTTP.Validate()),
+		Section("scheduler", c.Scheduler.Validate()),
+	)
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+var expected = testConfig{
+	Logger:    LoggerConf{Level: "debug", Format: "text"},
+	Storage:   StorageConf{Type: StorageSQL, Driver: DriverSQLite, DSN: "calendar.db"},
+	HTTP:      ServerConf{Host: "localhost", Port: 8080},
+	Scheduler: SchedulerConf{ScanInterval: time.Minute},
+	Origins:   []string{"a", "b"},
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("yaml", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", `
+logger:
+  level: debug
+  format: text
+storage:
+  type: sql
+  driver: sqlite
+  dsn: calendar.db
+http:
+  host: localhost
+  port: 8080
+scheduler:
+  scan_interval: 1m
+origins: [a, b]
+`)
+		var c testConfig
+		require.NoError(t, Load(path, "TEST", &c))
+		require.Equal(t, expected, c)
+	})
+
+	t.Run("toml", func(t *testing.T) {
+		path := writeFile(t, "config.toml", `
+origins = ["a", "b"]
+
+[logger]
+level = "debug"
+format = "text"
+
+[storage]
+type = "sql"
+driver = "sqlite"
+dsn = "calendar.db"
+
+[http]
+host = "localhost"
+port = 8080
+
+[scheduler]
+scan_interval = "1m"
+`)
+		var c testConfig
+		require.NoError(t, Load(path, "TEST", &c))
+		require.Equal(t, expected, c)
+	})
+
+	t.Run("defaults are kept", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", "logger:\n  level: warn\n")
+
+		c := expected
+		require.NoError(t, Load(path, "TEST", &c))
+		require.Equal(t, "warn", c.Logger.Level)
+		require.Equal(t, expected.Storage, c.Storage)
+	})
+
+	t.Run("environment overrides file", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", "logger:\n  level: warn\n")
+		t.Setenv("TEST_LOGGER_LEVEL", "error")
+		t.Setenv("TEST_STORAGE_DSN", "postgres://localhost/calendar")
+		t.Setenv("TEST_HTTP_PORT", "9090")
+		t.Setenv("TEST_SCHEDULER_SCAN_INTERVAL", "30s")
+		t.Setenv("TEST_ORIGINS", "x, y,")
+
+		c := expected
+		require.NoError(t, Load(path, "TEST", &c))
+		require.Equal(t, "error", c.Logger.Level)
+		require.Equal(t, "postgres://localhost/calendar", c.Storage.DSN)
+		require.Equal(t, 9090, c.HTTP.Port)
+		require.Equal(t, 30*time.Second, c.Scheduler.ScanInterval)
+		require.Equal(t, []string{"x", "y"}, c.Origins)
+	})
+
+	t.Run("all errors are reported", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", `
+logger:
+  level: loud
+  format: xml
+storage:
+  type: sql
+http:
+  port: 0
+`)
+		t.Setenv("TEST_SCHEDULER_SCAN_INTERVAL", "often")
+
+		var c testConfig
+		err := Load(path, "TEST", &c)
+		require.Error(t, err)
+		for _, key := range []string{
+			"logger.level", "logger.format", "storage.driver", "storage.dsn", "http.port", "scheduler.scan_interval",
+			"TEST_SCHEDULER_SCAN_INTERVAL",
+		} {
+			require.Contains(t, err.Error(), key)
+		}
+	})
+
+	t.Run("unknown keys", func(t *testing.T) {
+		var c testConfig
+		require.ErrorContains(t, Load(writeFile(t, "config.yaml", "loger:\n  level: info\n"), "TEST", &c), "loger")
+		require.ErrorContains(t, Load(writeFile(t, "config.toml", "[loger]\nlevel = \"info\"\n"), "TEST", &c), "loger")
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		var c testConfig
+		require.ErrorIs(t, Load(writeFile(t, "config.ini", ""), "TEST", &c), ErrUnknownFormat)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		var c testConfig
+		require.ErrorIs(t, Load(filepath.Join(t.TempDir(), "config.yaml"), "TEST", &c), os.ErrNotExist)
+	})
+}
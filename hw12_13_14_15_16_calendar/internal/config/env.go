@@ -0,0 +1,125 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errNotStruct   = errors.New("config must be a pointer to a struct")
+	errUnsupported = errors.New("unsupported type")
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+type lookupFunc func(key string) (string, bool)
+
+// applyEnv overrides fields of cfg with environment variables. Names of the variables
+// are built from the prefix and the keys of the fields, which are taken from yaml tags.
+func applyEnv(prefix string, cfg any, lookup lookupFunc) []error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return []error{errNotStruct}
+	}
+	return applyEnvStruct(strings.ToUpper(prefix), "", v.Elem(), lookup)
+}
+
+func applyEnvStruct(envPrefix, keyPrefix string, v reflect.Value, lookup lookupFunc) []error {
+	var errs []error
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := fieldKey(field)
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+
+		key := name
+		if keyPrefix != "" {
+			key = keyPrefix + "." + name
+		}
+		env := envPrefix + "_" + strings.ToUpper(name)
+
+		if field.Type.Kind() == reflect.Struct {
+			errs = append(errs, applyEnvStruct(env, key, v.Field(i), lookup)...)
+			continue
+		}
+
+		value, ok := lookup(env)
+		if !ok {
+			continue
+		}
+		if err := setValue(v.Field(i), value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid value %q of %s: %w", key, value, env, err))
+		}
+	}
+
+	return errs
+}
+
+func fieldKey(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+func setValue(v reflect.Value, value string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() { //nolint:exhaustive
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return errUnsupported
+		}
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items).Convert(v.Type()))
+	default:
+		return errUnsupported
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	StorageMemory = "memory"
+	StorageSQL    = "sql"
+
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+
+	QueueRabbit = "rabbit"
+	QueueLocal  = "local"
+)
+
+var (
+	errRequired = errors.New("is required")
+	errInvalid  = errors.New("is invalid")
+)
+
+type LoggerConf struct {
+	Level  string `yaml:"level" toml:"level"`   // debug | info | warn | error
+	Format string `yaml:"format" toml:"format"` // json | text
+}
+
+func (c LoggerConf) Validate() error {
+	var errs []error
+
+	switch strings.ToLower(c.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("level %w: %q is not one of debug, info, warn, error", errInvalid, c.Level))
+	}
+
+	switch strings.ToLower(c.Format) {
+	case "json", "text":
+	default:
+		errs = append(errs, fmt.Errorf("format %w: %q is not one of json, text", errInvalid, c.Format))
+	}
+
+	return errors.Join(errs...)
+}
+
+type StorageConf struct {
+	Type   string `yaml:"type" toml:"type"`     // memory | sql
+	Driver string `yaml:"driver" toml:"driver"` // postgres | sqlite
+	DSN    string `yaml:"dsn" toml:"dsn"`
+}
+
+func (c StorageConf) Validate() error {
+	switch c.Type {
+	case StorageMemory:
+		return nil
+	case StorageSQL:
+	default:
+		return fmt.Errorf("type %w: %q is not one of %s, %s", errInvalid, c.Type, StorageMemory, StorageSQL)
+	}
+
+	var errs []error
+	if c.Driver != DriverPostgres && c.Driver != DriverSQLite {
+		errs = append(errs, fmt.Errorf("driver %w: %q is not one of %s, %s",
+			errInvalid, c.Driver, DriverPostgres, DriverSQLite))
+	}
+	if c.DSN == "" {
+		errs = append(errs, fmt.Errorf("dsn %w", errRequired))
+	}
+	return errors.Join(errs...)
+}
+
+// ServerConf describes a listening address of a server.
+type ServerConf struct {
+	Host string `yaml:"host" toml:"host"`
+	Port int    `yaml:"port" toml:"port"`
+}
+
+func (c ServerConf) Addr() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+func (c ServerConf) Validate() error {
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("port %w: %d is out of range", errInvalid, c.Port)
+	}
+	return nil
+}
+
+// RateLimitConf limits requests of every client of the API to RPS per second with bursts
+// of Burst requests. Zero RPS disables the limit.
+type RateLimitConf struct {
+	RPS   float64 `yaml:"rps" toml:"rps"`
+	Burst int     `yaml:"burst" toml:"burst"`
+}
+
+func (c RateLimitConf) Validate() error {
+	var errs []error
+	if c.RPS < 0 {
+		errs = append(errs, fmt.Errorf("rps %w: must not be negative", errInvalid))
+	}
+	if c.RPS > 0 && c.Burst <= 0 {
+		errs = append(errs, fmt.Errorf("burst %w: must be positive", errInvalid))
+	}
+	return errors.Join(errs...)
+}
+
+// CORSConf lists origins browsers may call the API from, "*" allows any origin.
+type CORSConf struct {
+	Origins []string `yaml:"origins" toml:"origins"`
+}
+
+func (c CORSConf) Validate() error {
+	for _, origin := range c.Origins {
+		if origin == "" {
+			return fmt.Errorf("origins %w: origin must not be empty", errInvalid)
+		}
+	}
+	return nil
+}
+
+type SchedulerConf struct {
+	ScanInterval time.Duration `yaml:"scan_interval" toml:"scan_interval"`
+}
+
+func (c SchedulerConf) Validate() error {
+	if c.ScanInterval <= 0 {
+		return fmt.Errorf("scan_interval %w: must be positive", errInvalid)
+	}
+	return nil
+}
+
+// RetentionConf describes the purge of old events. Events which ended more than Age ago
+// are deleted every Interval in batches of BatchSize. DryRun only reports their number.
+type RetentionConf struct {
+	Age       time.Duration `yaml:"age" toml:"age"`
+	Interval  time.Duration `yaml:"interval" toml:"interval"`
+	BatchSize int           `yaml:"batch_size" toml:"batch_size"`
+	DryRun    bool          `yaml:"dry_run" toml:"dry_run"`
+}
+
+func (c RetentionConf) Validate() error {
+	var errs []error
+	if c.Age <= 0 {
+		errs = append(errs, fmt.Errorf("age %w: must be positive", errInvalid))
+	}
+	if c.Interval <= 0 {
+		errs = append(errs, fmt.Errorf("interval %w: must be positive", errInvalid))
+	}
+	if c.BatchSize <= 0 {
+		errs = append(errs, fmt.Errorf("batch_size %w: must be positive", errInvalid))
+	}
+	return errors.Join(errs...)
+}
+
+type QueueConf struct {
+	Driver string `yaml:"driver" toml:"driver"` // rabbit | local
+	URL    string `yaml:"url" toml:"url"`
+	Name   string `yaml:"name" toml:"name"`
+	// Dir is the directory of the local queue shared by the processes on the host.
+	Dir string `yaml:"dir" toml:"dir"`
+	// Prefetch limits the number of unacknowledged messages of a consumer, zero means no limit.
+	Prefetch int `yaml:"prefetch" toml:"prefetch"`
+}
+
+func (c QueueConf) Validate() error {
+	var errs []error
+	switch c.Driver {
+	case QueueRabbit:
+		if c.URL == "" {
+			errs = append(errs, fmt.Errorf("url %w", errRequired))
+		}
+		if c.Name == "" {
+			errs = append(errs, fmt.Errorf("name %w", errRequired))
+		}
+	case QueueLocal:
+		if c.Dir == "" {
+			errs = append(errs, fmt.Errorf("dir %w", errRequired))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("driver %w: %q is not one of %s, %s", errInvalid, c.Driver, QueueRabbit, QueueLocal))
+	}
+	if c.Prefetch < 0 {
+		errs = append(errs, fmt.Errorf("prefetch %w: must not be negative", errInvalid))
+	}
+	return errors.Join(errs...)
+}
+
+type SenderConf struct {
+	Channels    []string    `yaml:"channels" toml:"channels"` // log | stdout | webhook
+	MaxAttempts int         `yaml:"max_attempts" toml:"max_attempts"`
+	Webhook     WebhookConf `yaml:"webhook" toml:"webhook"`
+}
+
+func (c SenderConf) Validate() error {
+	var errs []error
+	if len(c.Channels) == 0 {
+		errs = append(errs, fmt.Errorf("channels %w", errRequired))
+	}
+	if c.MaxAttempts <= 0 {
+		errs = append(errs, fmt.Errorf("max_attempts %w: must be positive", errInvalid))
+	}
+	errs = append(errs, Section("webhook", c.Webhook.Validate()))
+	return errors.Join(errs...)
+}
+
+// WebhookConf describes delivery of notifications to webhooks. A failed request is retried
+// MaxRetries times with exponential backoff from MinBackoff to MaxBackoff.
+type WebhookConf struct {
+	Timeout    time.Duration `yaml:"timeout" toml:"timeout"`
+	MaxRetries int           `yaml:"max_retries" toml:"max_retries"`
+	MinBackoff time.Duration `yaml:"min_backoff" toml:"min_backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff" toml:"max_backoff"`
+	// AllowPrivateAddresses lets webhooks reach the internal network, e.g. in development.
+	AllowPrivateAddresses bool `yaml:"allow_private_addresses" toml:"allow_private_addresses"`
+}
+
+func (c WebhookConf) Validate() error {
+	var errs []error
+	if c.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("timeout %w: must be positive", errInvalid))
+	}
+	if c.MaxRetries < 0 {
+		errs = append(errs, fmt.Errorf("max_retries %w: must not be negative", errInvalid))
+	}
+	if c.MinBackoff <= 0 {
+		errs = append(errs, fmt.Errorf("min_backoff %w: must be positive", errInvalid))
+	}
+	if c.MaxBackoff < c.MinBackoff {
+		errs = append(errs, fmt.Errorf("max_backoff %w: must not be less than min_backoff", errInvalid))
+	}
+	return errors.Join(errs...)
+}
+
+// Section prefixes every error joined in err with the name of the section.
+func Section(name string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error }) //nolint:errorlint
+	if !ok {
+		return fmt.Errorf("%s.%w", name, err)
+	}
+
+	errs := joined.Unwrap()
+	prefixed := make([]error, 0, len(errs))
+	for _, err := range errs {
+		prefixed = append(prefixed, Section(name, err))
+	}
+	return errors.Join(prefixed...)
+}
@@ -0,0 +1,340 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+)
+
+const maxContentLine = 1 << 20
+
+// Decode reads events from the iCalendar data. The UID of an event becomes its ID, while
+// an override of an occurrence gets the UID as its series ID and no ID at all.
+// Times with TZID are resolved with the time zone database or, if the zone is unknown there,
+// with the standard offset of the VTIMEZONE of the same name. Only the zones known
+// to the database become time zones of events. Floating times are taken as UTC.
+// All-day events start at midnight UTC.
+func Decode(r io.Reader) ([]storage.Event, error) {
+	root, err := parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []storage.Event
+	for _, calendar := range root.components {
+		if calendar.name != "VCALENDAR" {
+			return nil, fmt.Errorf("%w: unexpected component %s", ErrInvalidCalendar, calendar.name)
+		}
+
+		zones, err := timeZones(calendar)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range calendar.components {
+			if c.name != "VEVENT" {
+				continue
+			}
+			event, err := decodeEvent(c, zones)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// parse reads the content lines and builds the tree of components under an unnamed root.
+func parse(r io.Reader) (*component, error) {
+	root := &component{}
+	stack := []*component{root}
+
+	err := readLines(r, func(line string, number int) error {
+		p, err := parseProperty(line, number)
+		if err != nil {
+			return err
+		}
+
+		current := stack[len(stack)-1]
+		switch p.name {
+		case "BEGIN":
+			c := &component{name: strings.ToUpper(p.value)}
+			current.components = append(current.components, c)
+			stack = append(stack, c)
+		case "END":
+			if len(stack) == 1 || !strings.EqualFold(p.value, current.name) {
+				return p.errorf("unexpected end of %s", p.value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 1 {
+				return p.errorf("property outside of any component")
+			}
+			current.properties = append(current.properties, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("%w: %s is not closed", ErrInvalidCalendar, stack[len(stack)-1].name)
+	}
+	if len(root.components) == 0 {
+		return nil, fmt.Errorf("%w: no calendar found", ErrInvalidCalendar)
+	}
+	return root, nil
+}
+
+// readLines calls fn for every unfolded content line along with the number of its first physical line.
+func readLines(r io.Reader, fn func(line string, number int) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxContentLine)
+
+	var line strings.Builder
+	var start, number int
+	flush := func() error {
+		if line.Len() == 0 {
+			return nil
+		}
+		err := fn(line.String(), start)
+		line.Reset()
+		return err
+	}
+
+	for scanner.Scan() {
+		number++
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t") {
+			line.WriteString(text[1:])
+			continue
+		}
+
+		if err := flush(); err != nil {
+			return err
+		}
+		start = number
+		line.WriteString(text)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read calendar: %w", err)
+	}
+	return flush()
+}
+
+// timeZones returns the standard offsets of the time zones defined in the calendar.
+func timeZones(calendar *component) (map[string]*time.Location, error) {
+	zones := make(map[string]*time.Location)
+	for _, c := range calendar.components {
+		if c.name != "VTIMEZONE" {
+			continue
+		}
+		id, ok := c.property("TZID")
+		if !ok {
+			continue
+		}
+
+		for _, rule := range c.components {
+			if rule.name != "STANDARD" {
+				continue
+			}
+			p, ok := rule.property("TZOFFSETTO")
+			if !ok {
+				continue
+			}
+			offset, err := parseOffset(p.value)
+			if err != nil {
+				return nil, p.errorf("%s", err)
+			}
+			zones[id.value] = time.FixedZone(id.value, offset)
+			break
+		}
+	}
+	return zones, nil
+}
+
+// parseOffset parses UTC offset such as +0300 or -053000 into seconds.
+func parseOffset(value string) (int, error) {
+	if len(value) != 5 && len(value) != 7 || value[0] != '+' && value[0] != '-' {
+		return 0, fmt.Errorf("malformed offset %q", value)
+	}
+
+	var hours, minutes, seconds int
+	if _, err := fmt.Sscanf(value[1:5], "%02d%02d", &hours, &minutes); err != nil {
+		return 0, fmt.Errorf("malformed offset %q", value)
+	}
+	if len(value) == 7 {
+		if _, err := fmt.Sscanf(value[5:], "%02d", &seconds); err != nil {
+			return 0, fmt.Errorf("malformed offset %q", value)
+		}
+	}
+
+	offset := hours*3600 + minutes*60 + seconds
+	if value[0] == '-' {
+		offset = -offset
+	}
+	return offset, nil
+}
+
+func decodeEvent(c *component, zones map[string]*time.Location) (storage.Event, error) {
+	var event storage.Event
+	var uid string
+	var end time.Time
+	var duration *time.Duration
+	var allDay bool
+	var hasStart bool
+
+	for _, p := range c.properties {
+		var err error
+		switch p.name {
+		case "UID":
+			uid = p.value
+		case "SUMMARY":
+			event.Title = unescapeText(p.value)
+		case "DESCRIPTION":
+			event.Description = unescapeText(p.value)
+		case "DTSTART":
+			event.StartAt, allDay, err = parseTime(p, p.value, zones)
+			hasStart = true
+			if tzid := p.params["TZID"]; tzid != "" {
+				if _, zoneErr := storage.LoadLocation(tzid); zoneErr == nil {
+					event.TimeZone = tzid
+				}
+			}
+		case "DTEND":
+			end, _, err = parseTime(p, p.value, zones)
+		case "DURATION":
+			d, parseErr := parseDuration(p.value)
+			if parseErr != nil {
+				err = p.errorf("%s", parseErr)
+			}
+			duration = &d
+		case "RRULE":
+			event.RRule = p.value
+		case "EXDATE":
+			for _, value := range strings.Split(p.value, ",") {
+				var exdate time.Time
+				if exdate, _, err = parseTime(p, value, zones); err != nil {
+					break
+				}
+				event.ExDates = append(event.ExDates, exdate)
+			}
+		case "RECURRENCE-ID":
+			event.RecurrenceID, _, err = parseTime(p, p.value, zones)
+		}
+		if err != nil {
+			return storage.Event{}, err
+		}
+	}
+
+	if !hasStart {
+		return storage.Event{}, fmt.Errorf("%w: event %q has no DTSTART", ErrInvalidCalendar, uid)
+	}
+	switch {
+	case !end.IsZero():
+		event.Duration = end.Sub(event.StartAt)
+	case duration != nil:
+		event.Duration = *duration
+	case allDay:
+		event.Duration = 24 * time.Hour
+	}
+
+	if event.RecurrenceID.IsZero() {
+		event.ID = uid
+	} else {
+		event.SeriesID = uid
+	}
+
+	var reminders []storage.Reminder
+	for _, alarm := range c.components {
+		if alarm.name != "VALARM" {
+			continue
+		}
+		before, err := notifyBefore(alarm, event)
+		if err != nil {
+			return storage.Event{}, err
+		}
+		reminder := storage.Reminder{Before: before}
+		if p, ok := alarm.property(channelProperty); ok {
+			reminder.Channel = p.value
+		}
+		if before > 0 && !slices.Contains(reminders, reminder) {
+			reminders = append(reminders, reminder)
+		}
+	}
+	// a single alarm for all the channels is what the notification offset stands for
+	if len(reminders) == 1 && reminders[0].Channel == "" {
+		event.NotifyBefore = reminders[0].Before
+	} else {
+		event.Reminders = reminders
+	}
+	return event, nil
+}
+
+// parseTime parses a DATE or DATE-TIME value of the property.
+// It reports whether the value is a date, i.e. the event lasts all day.
+func parseTime(p property, value string, zones map[string]*time.Location) (time.Time, bool, error) {
+	if p.params["VALUE"] == "DATE" || len(value) == len(dateLayout) {
+		t, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return time.Time{}, false, p.errorf("malformed date %q", value)
+		}
+		return t, true, nil
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(utcLayout, value)
+		if err != nil {
+			return time.Time{}, false, p.errorf("malformed date-time %q", value)
+		}
+		return t, false, nil
+	}
+
+	location := time.UTC
+	if tzid, ok := p.params["TZID"]; ok {
+		var err error
+		if location, err = storage.LoadLocation(tzid); err != nil {
+			if location, ok = zones[tzid]; !ok {
+				return time.Time{}, false, p.errorf("unknown time zone %q", tzid)
+			}
+		}
+	}
+
+	t, err := time.ParseInLocation(dateTimeLayout, value, location)
+	if err != nil {
+		return time.Time{}, false, p.errorf("malformed date-time %q", value)
+	}
+	return t.UTC(), false, nil
+}
+
+// notifyBefore returns how long before the start of the event the alarm goes off.
+// Alarms going off after the start are of no use for notifications and give a non-positive result.
+func notifyBefore(alarm *component, event storage.Event) (time.Duration, error) {
+	p, ok := alarm.property("TRIGGER")
+	if !ok {
+		return 0, nil
+	}
+
+	if p.params["VALUE"] == "DATE-TIME" {
+		t, _, err := parseTime(p, p.value, nil)
+		if err != nil {
+			return 0, err
+		}
+		return event.StartAt.Sub(t), nil
+	}
+
+	offset, err := parseDuration(p.value)
+	if err != nil {
+		return 0, p.errorf("%s", err)
+	}
+	if p.params["RELATED"] == "END" {
+		offset += event.Duration
+	}
+	return -offset, nil
+}
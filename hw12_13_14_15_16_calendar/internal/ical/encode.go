@@ -0,0 +1,126 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+)
+
+const productID = "-//fixme_my_friend//calendar//EN"
+
+// Encode writes the events as a calendar. Series are written with their rules, so they shouldn't
+// be expanded, and overrides of their occurrences share the UID of the series.
+// stamp is the moment the calendar is created at. Times of events with a time zone are written
+// as local times with TZID, which clients resolve with their own time zone databases.
+func Encode(w io.Writer, events []storage.Event, stamp time.Time) error {
+	e := &encoder{w: bufio.NewWriter(w)}
+
+	e.line("BEGIN", "VCALENDAR")
+	e.line("VERSION", "2.0")
+	e.line("PRODID", productID)
+	e.line("CALSCALE", "GREGORIAN")
+	for _, event := range events {
+		e.event(event, stamp)
+	}
+	e.line("END", "VCALENDAR")
+
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+type encoder struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (e *encoder) event(event storage.Event, stamp time.Time) {
+	e.line("BEGIN", "VEVENT")
+
+	if event.SeriesID != "" {
+		e.line("UID", event.SeriesID)
+		e.time("RECURRENCE-ID", event, event.RecurrenceID)
+	} else {
+		e.line("UID", event.ID)
+	}
+	e.line("DTSTAMP", formatTime(stamp))
+	e.time("DTSTART", event, event.StartAt)
+	e.time("DTEND", event, event.EndAt())
+	e.line("SUMMARY", escapeText(event.Title))
+	if event.Description != "" {
+		e.line("DESCRIPTION", escapeText(event.Description))
+	}
+	if event.Recurring() {
+		e.line("RRULE", event.RRule)
+	}
+	if len(event.ExDates) > 0 {
+		e.time("EXDATE", event, event.ExDates...)
+	}
+
+	for _, reminder := range event.ActiveReminders() {
+		e.line("BEGIN", "VALARM")
+		e.line("ACTION", "DISPLAY")
+		e.line("DESCRIPTION", escapeText(event.Title))
+		e.line("TRIGGER", "-"+formatDuration(reminder.Before))
+		if reminder.Channel != "" {
+			e.line(channelProperty, reminder.Channel)
+		}
+		e.line("END", "VALARM")
+	}
+
+	e.line("END", "VEVENT")
+}
+
+// time writes the property with the times in the time zone of the event.
+func (e *encoder) time(name string, event storage.Event, times ...time.Time) {
+	values := make([]string, 0, len(times))
+	for _, t := range times {
+		if event.TimeZone == "" {
+			values = append(values, formatTime(t))
+		} else {
+			values = append(values, t.In(event.Location()).Format(dateTimeLayout))
+		}
+	}
+
+	if event.TimeZone != "" {
+		name += ";TZID=" + event.TimeZone
+	}
+	e.line(name, strings.Join(values, ","))
+}
+
+// line writes the content line folding it, so that no physical line is longer than
+// maxLineLength octets. Multi-octet characters are never split.
+func (e *encoder) line(name, value string) {
+	if e.err != nil {
+		return
+	}
+
+	line := name + ":" + value
+	limit := maxLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		e.write(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// the leading space of a continuation line counts too
+		limit = maxLineLength - 1
+	}
+	e.write(line + "\r\n")
+}
+
+func (e *encoder) write(s string) {
+	if e.err == nil {
+		_, e.err = e.w.WriteString(s)
+	}
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(utcLayout)
+}
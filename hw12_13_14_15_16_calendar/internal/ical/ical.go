@@ -0,0 +1,205 @@
+// Package ical converts events to and from iCalendar data (RFC 5545).
+// Only the VEVENT components are taken into account along with their VALARM ones,
+// which become the reminders of the events.
+package ical
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCalendar = errors.New("invalid calendar")
+
+const (
+	// channelProperty keeps the channel of a reminder in its VALARM, as iCalendar has no counterpart.
+	channelProperty = "X-CALENDAR-CHANNEL"
+
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102T150405"
+	utcLayout      = "20060102T150405Z"
+
+	// maxLineLength is the limit of a content line in octets, longer lines are folded.
+	maxLineLength = 75
+)
+
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+	line   int
+}
+
+func (p property) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: line %d: %s: %s", ErrInvalidCalendar, p.line, p.name, fmt.Sprintf(format, args...))
+}
+
+type component struct {
+	name       string
+	properties []property
+	components []*component
+}
+
+// property returns the first property with the given name.
+func (c *component) property(name string) (property, bool) {
+	for _, p := range c.properties {
+		if p.name == name {
+			return p, true
+		}
+	}
+	return property{}, false
+}
+
+// parseProperty parses a content line: NAME;PARAM=value;PARAM="quoted value":value.
+func parseProperty(line string, number int) (property, error) {
+	p := property{line: number}
+
+	end := strings.IndexAny(line, ";:")
+	if end <= 0 {
+		return property{}, fmt.Errorf("%w: line %d: malformed content line", ErrInvalidCalendar, number)
+	}
+	p.name = strings.ToUpper(line[:end])
+	line = line[end:]
+
+	for line[0] == ';' {
+		line = line[1:]
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			return property{}, p.errorf("malformed parameter")
+		}
+		name := strings.ToUpper(line[:eq])
+		line = line[eq+1:]
+
+		var value string
+		if strings.HasPrefix(line, `"`) {
+			closing := strings.IndexByte(line[1:], '"')
+			if closing < 0 {
+				return property{}, p.errorf("unterminated quoted parameter %s", name)
+			}
+			value, line = line[1:closing+1], line[closing+2:]
+		} else {
+			end := strings.IndexAny(line, ";:")
+			if end < 0 {
+				return property{}, p.errorf("malformed parameter %s", name)
+			}
+			value, line = line[:end], line[end:]
+		}
+		if p.params == nil {
+			p.params = make(map[string]string)
+		}
+		p.params[name] = value
+
+		if line == "" {
+			return property{}, p.errorf("value is missing")
+		}
+	}
+
+	if line[0] != ':' {
+		return property{}, p.errorf("value is missing")
+	}
+	p.value = line[1:]
+	return p, nil
+}
+
+func unescapeText(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i == len(value)-1 {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "")
+
+func escapeText(value string) string {
+	return textEscaper.Replace(value)
+}
+
+// parseDuration parses a duration value such as -P1DT2H30M or P2W.
+func parseDuration(value string) (time.Duration, error) {
+	s := value
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign, s = -1, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, fmt.Errorf("malformed duration %q", value)
+	}
+	s = s[1:]
+
+	var d time.Duration
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour}
+	for s != "" {
+		if s[0] == 'T' {
+			if len(s) == 1 {
+				return 0, fmt.Errorf("malformed duration %q", value)
+			}
+			units = map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+			s = s[1:]
+			continue
+		}
+
+		end := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+		if end <= 0 {
+			return 0, fmt.Errorf("malformed duration %q", value)
+		}
+		n, err := strconv.Atoi(s[:end])
+		if err != nil {
+			return 0, fmt.Errorf("malformed duration %q", value)
+		}
+		unit, ok := units[s[end]]
+		if !ok {
+			return 0, fmt.Errorf("malformed duration %q", value)
+		}
+		d += time.Duration(n) * unit
+		s = s[end+1:]
+	}
+	return sign * d, nil
+}
+
+// formatDuration formats a non-negative duration truncated to seconds.
+func formatDuration(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	if d == 0 {
+		return "PT0S"
+	}
+
+	var b strings.Builder
+	b.WriteByte('P')
+	if days := d / (24 * time.Hour); days > 0 {
+		fmt.Fprintf(&b, "%dD", days)
+		d -= days * 24 * time.Hour
+	}
+	if d > 0 {
+		b.WriteByte('T')
+	}
+	for _, u := range []struct {
+		unit time.Duration
+		name string
+	}{{time.Hour, "H"}, {time.Minute, "M"}, {time.Second, "S"}} {
+		if n := d / u.unit; n > 0 {
+			fmt.Fprintf(&b, "%d%s", n, u.name)
+			d -= n * u.unit
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,242 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+var baseTime = time.Date(2024, time.March, 11, 10, 0, 0, 0, time.UTC)
+
+func calendar(lines ...string) string {
+	return strings.Join(append(append([]string{"BEGIN:VCALENDAR", "VERSION:2.0"}, lines...), "END:VCALENDAR"), "\r\n")
+}
+
+func TestDecode(t *testing.T) {
+	data := calendar(
+		"BEGIN:VTIMEZONE",
+		"TZID:Russian Standard Time",
+		"BEGIN:STANDARD",
+		"DTSTART:16010101T000000",
+		"TZOFFSETFROM:+0300",
+		"TZOFFSETTO:+0300",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:single@example.com",
+		"DTSTAMP:20240301T000000Z",
+		"DTSTART:20240311T100000Z",
+		"DTEND:20240311T113000Z",
+		"SUMMARY:Planning\\, Q2",
+		"DESCRIPTION:first line\\nsecond \\; line with a very long text which is fol",
+		" ded by the client",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"TRIGGER:-PT15M",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:series@example.com",
+		"DTSTART;TZID=Europe/Moscow:20240311T130000",
+		"DURATION:PT30M",
+		"SUMMARY:Standup",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,WE",
+		"EXDATE;TZID=\"Russian Standard Time\":20240313T130000,20240318T130000",
+		"BEGIN:VALARM",
+		"TRIGGER;RELATED=END:-PT1H",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:series@example.com",
+		"RECURRENCE-ID:20240320T100000Z",
+		"DTSTART:20240320T140000Z",
+		"DTEND:20240320T143000Z",
+		"SUMMARY:Late standup",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:holiday@example.com",
+		"DTSTART;VALUE=DATE:20240308",
+		"SUMMARY:Holiday",
+		"BEGIN:VALARM",
+		"TRIGGER;VALUE=DATE-TIME:20240307T120000Z",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"UID:todo@example.com",
+		"END:VTODO",
+	)
+
+	events, err := Decode(strings.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, []storage.Event{
+		{
+			ID:           "single@example.com",
+			Title:        "Planning, Q2",
+			StartAt:      baseTime,
+			Duration:     90 * time.Minute,
+			Description:  "first line\nsecond ; line with a very long text which is folded by the client",
+			NotifyBefore: 15 * time.Minute,
+		},
+		{
+			ID:           "series@example.com",
+			Title:        "Standup",
+			StartAt:      baseTime,
+			Duration:     30 * time.Minute,
+			NotifyBefore: 30 * time.Minute,
+			RRule:        "FREQ=WEEKLY;BYDAY=MO,WE",
+			ExDates:      []time.Time{baseTime.AddDate(0, 0, 2), baseTime.AddDate(0, 0, 7)},
+			TimeZone:     "Europe/Moscow",
+		},
+		{
+			Title:        "Late standup",
+			StartAt:      baseTime.AddDate(0, 0, 9).Add(4 * time.Hour),
+			Duration:     30 * time.Minute,
+			SeriesID:     "series@example.com",
+			RecurrenceID: baseTime.AddDate(0, 0, 9),
+		},
+		{
+			ID:           "holiday@example.com",
+			Title:        "Holiday",
+			StartAt:      time.Date(2024, time.March, 8, 0, 0, 0, 0, time.UTC),
+			Duration:     24 * time.Hour,
+			NotifyBefore: 12 * time.Hour,
+		},
+	}, events)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", ""},
+		{"not a calendar", "BEGIN:VCARD\r\nEND:VCARD"},
+		{"not closed", "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VEVENT"},
+		{"mismatched end", calendar("BEGIN:VEVENT", "END:VTODO")},
+		{"property outside", "VERSION:2.0"},
+		{"no value", calendar("BEGIN:VEVENT", "SUMMARY", "END:VEVENT")},
+		{"unterminated quote", calendar("BEGIN:VEVENT", `DTSTART;TZID="Moscow:20240311T100000`, "END:VEVENT")},
+		{"no start", calendar("BEGIN:VEVENT", "UID:1", "END:VEVENT")},
+		{"malformed start", calendar("BEGIN:VEVENT", "DTSTART:2024-03-11", "END:VEVENT")},
+		{"unknown zone", calendar("BEGIN:VEVENT", "DTSTART;TZID=Mars/Olympus:20240311T100000", "END:VEVENT")},
+		{"malformed duration", calendar("BEGIN:VEVENT", "DTSTART:20240311T100000Z", "DURATION:1H", "END:VEVENT")},
+		{"malformed trigger", calendar(
+			"BEGIN:VEVENT", "DTSTART:20240311T100000Z", "BEGIN:VALARM", "TRIGGER:-15M", "END:VALARM", "END:VEVENT",
+		)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Decode(strings.NewReader(tc.data))
+			require.ErrorIs(t, err, ErrInvalidCalendar)
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	events := []storage.Event{
+		{
+			ID:           "1",
+			Title:        "Planning; Q2, \\ draft",
+			StartAt:      baseTime,
+			Duration:     90 * time.Minute,
+			Description:  strings.Repeat("длинное описание\n", 10),
+			NotifyBefore: 26 * time.Hour,
+		},
+		{
+			ID:       "2",
+			Title:    "Standup",
+			StartAt:  baseTime,
+			Duration: 15 * time.Minute,
+			RRule:    "FREQ=DAILY;COUNT=10",
+			ExDates:  []time.Time{baseTime.AddDate(0, 0, 1), baseTime.AddDate(0, 0, 2)},
+		},
+		{
+			Title:        "Late standup",
+			StartAt:      baseTime.AddDate(0, 0, 3).Add(time.Hour),
+			Duration:     15 * time.Minute,
+			SeriesID:     "2",
+			RecurrenceID: baseTime.AddDate(0, 0, 3),
+		},
+		{
+			ID:       "3",
+			Title:    "New York standup",
+			StartAt:  baseTime,
+			Duration: 15 * time.Minute,
+			RRule:    "FREQ=WEEKLY",
+			ExDates:  []time.Time{baseTime.AddDate(0, 0, 7)},
+			TimeZone: "America/New_York",
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, events, baseTime))
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		require.LessOrEqual(t, len(line), maxLineLength)
+	}
+	require.Contains(t, buf.String(), "DTSTAMP:20240311T100000Z\r\n")
+	require.Contains(t, buf.String(), "TRIGGER:-P1DT2H\r\n")
+	require.Contains(t, buf.String(), "DTSTART;TZID=America/New_York:20240311T060000\r\n")
+	require.Contains(t, buf.String(), "EXDATE;TZID=America/New_York:20240318T060000\r\n")
+
+	decoded, err := Decode(&buf)
+	require.NoError(t, err)
+	require.Equal(t, events, decoded)
+}
+
+func TestEncodeReminders(t *testing.T) {
+	event := storage.Event{
+		ID:       "1",
+		Title:    "Planning",
+		StartAt:  baseTime,
+		Duration: time.Hour,
+		Reminders: []storage.Reminder{
+			{Before: 24 * time.Hour, Channel: "webhook"},
+			{Before: 10 * time.Minute},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, []storage.Event{event}, baseTime))
+	require.Equal(t, 2, strings.Count(buf.String(), "BEGIN:VALARM\r\n"))
+	require.Contains(t, buf.String(), "TRIGGER:-P1D\r\nX-CALENDAR-CHANNEL:webhook\r\n")
+	require.Contains(t, buf.String(), "TRIGGER:-PT10M\r\n")
+
+	decoded, err := Decode(&buf)
+	require.NoError(t, err)
+	require.Len(t, decoded, 1)
+	require.Equal(t, event.Reminders, decoded[0].Reminders)
+	require.Zero(t, decoded[0].NotifyBefore)
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		value    string
+		duration time.Duration
+	}{
+		{"PT0S", 0},
+		{"PT15M", 15 * time.Minute},
+		{"P1DT2H3M4S", 26*time.Hour + 3*time.Minute + 4*time.Second},
+		{"P2D", 48 * time.Hour},
+	}
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			d, err := parseDuration(tc.value)
+			require.NoError(t, err)
+			require.Equal(t, tc.duration, d)
+			require.Equal(t, tc.value, formatDuration(tc.duration))
+		})
+	}
+
+	d, err := parseDuration("-P1W")
+	require.NoError(t, err)
+	require.Equal(t, -7*24*time.Hour, d)
+
+	for _, value := range []string{"", "P", "PT", "P1H", "PT1D", "P1.5D", "T1H"} {
+		_, err := parseDuration(value)
+		require.Error(t, err, value)
+	}
+}
@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+type contextKey struct{}
+
+// ContextWith returns a copy of ctx carrying the key-value pairs. They are added
+// to every record logged with that context, e.g. to mark all lines of a request
+// with its ID.
+func ContextWith(ctx context.Context, args ...any) context.Context {
+	parent, _ := ctx.Value(contextKey{}).([]slog.Attr)
+
+	attrs := make([]slog.Attr, 0, len(parent)+len(args)/2)
+	attrs = append(attrs, parent...)
+	attrs = append(attrs, argsToAttrs(args)...)
+
+	return context.WithValue(ctx, contextKey{}, attrs)
+}
+
+func argsToAttrs(args []any) []slog.Attr {
+	var attrs []slog.Attr
+	for len(args) > 0 {
+		switch key := args[0].(type) {
+		case slog.Attr:
+			attrs = append(attrs, key)
+			args = args[1:]
+		case string:
+			if len(args) == 1 {
+				attrs = append(attrs, slog.Any("!BADKEY", key))
+				return attrs
+			}
+			attrs = append(attrs, slog.Any(key, args[1]))
+			args = args[2:]
+		default:
+			attrs = append(attrs, slog.Any("!BADKEY", key))
+			args = args[1:]
+		}
+	}
+	return attrs
+}
+
+// contextHandler adds the attributes stored by ContextWith to records.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if attrs, ok := ctx.Value(contextKey{}).([]slog.Attr); ok {
+		record = record.Clone()
+		record.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -1,20 +1,120 @@
 package logger
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
 
-type Logger struct { // TODO
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+var (
+	ErrUnknownLevel  = errors.New("unknown log level")
+	ErrUnknownFormat = errors.New("unknown log format")
+)
+
+// Logger writes structured records with key-value fields. Loggers derived
+// with With share the level with their parent, so it can be switched at runtime
+// for all of them at once. Methods with Context suffix also add the fields
+// stored in the context by ContextWith.
+type Logger struct {
+	logger *slog.Logger
+	level  *slog.LevelVar
+}
+
+func New(level, format string, out io.Writer) (*Logger, error) {
+	l, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	levelVar := &slog.LevelVar{}
+	levelVar.Set(l)
+	opts := &slog.HandlerOptions{Level: levelVar}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case FormatJSON, "":
+		handler = slog.NewJSONHandler(out, opts)
+	case FormatText:
+		handler = slog.NewTextHandler(out, opts)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+
+	return &Logger{
+		logger: slog.New(contextHandler{handler}),
+		level:  levelVar,
+	}, nil
+}
+
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("%w: %q", ErrUnknownLevel, level)
+	}
+}
+
+// SetLevel changes the minimal level of records written by the logger and all loggers derived from it.
+func (l *Logger) SetLevel(level string) error {
+	parsed, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	l.level.Set(parsed)
+	return nil
 }
 
-func New(level string) *Logger {
-	return &Logger{}
+// With returns a logger adding the key-value pairs to every record.
+func (l *Logger) With(args ...any) *Logger {
+	return &Logger{
+		logger: l.logger.With(args...),
+		level:  l.level,
+	}
 }
 
-func (l Logger) Info(msg string) {
-	fmt.Println(msg)
+func (l *Logger) Debug(msg string, args ...any) {
+	l.logger.Debug(msg, args...)
 }
 
-func (l Logger) Error(msg string) {
-	// TODO
+func (l *Logger) Info(msg string, args ...any) {
+	l.logger.Info(msg, args...)
 }
 
-// TODO
+func (l *Logger) Warn(msg string, args ...any) {
+	l.logger.Warn(msg, args...)
+}
+
+func (l *Logger) Error(msg string, args ...any) {
+	l.logger.Error(msg, args...)
+}
+
+func (l *Logger) DebugContext(ctx context.Context, msg string, args ...any) {
+	l.logger.DebugContext(ctx, msg, args...)
+}
+
+func (l *Logger) InfoContext(ctx context.Context, msg string, args ...any) {
+	l.logger.InfoContext(ctx, msg, args...)
+}
+
+func (l *Logger) WarnContext(ctx context.Context, msg string, args ...any) {
+	l.logger.WarnContext(ctx, msg, args...)
+}
+
+func (l *Logger) ErrorContext(ctx context.Context, msg string, args ...any) {
+	l.logger.ErrorContext(ctx, msg, args...)
+}
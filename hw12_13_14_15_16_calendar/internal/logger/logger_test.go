@@ -1,7 +1,120 @@
 package logger
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func records(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var result []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		result = append(result, record)
+	}
+	buf.Reset()
+	return result
+}
 
 func TestLogger(t *testing.T) {
-	// TODO
+	t.Run("levels", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		l, err := New("WARN", FormatJSON, buf)
+		require.NoError(t, err)
+
+		l.Debug("debug")
+		l.Info("info")
+		l.Warn("warn")
+		l.Error("error", "error", errors.New("failure"))
+
+		logged := records(t, buf)
+		require.Len(t, logged, 2)
+		require.Equal(t, "WARN", logged[0]["level"])
+		require.Equal(t, "warn", logged[0]["msg"])
+		require.Equal(t, "ERROR", logged[1]["level"])
+		require.Equal(t, "failure", logged[1]["error"])
+	})
+
+	t.Run("fields", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		l, err := New("info", FormatJSON, buf)
+		require.NoError(t, err)
+
+		requestLogger := l.With("request_id", "42", "user_id", "user")
+		requestLogger.Info("event created", "event_id", "1")
+		l.Info("plain")
+
+		logged := records(t, buf)
+		require.Len(t, logged, 2)
+		require.Equal(t, "42", logged[0]["request_id"])
+		require.Equal(t, "user", logged[0]["user_id"])
+		require.Equal(t, "1", logged[0]["event_id"])
+		require.NotContains(t, logged[1], "request_id")
+	})
+
+	t.Run("context fields", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		l, err := New("info", FormatJSON, buf)
+		require.NoError(t, err)
+
+		ctx := ContextWith(context.Background(), "request_id", "42")
+		ctx = ContextWith(ctx, "user_id", "user")
+		l.With("component", "app").InfoContext(ctx, "event created", "event_id", "1")
+		l.InfoContext(context.Background(), "unrelated")
+		l.Info("no context")
+
+		logged := records(t, buf)
+		require.Len(t, logged, 3)
+		require.Equal(t, "42", logged[0]["request_id"])
+		require.Equal(t, "user", logged[0]["user_id"])
+		require.Equal(t, "app", logged[0]["component"])
+		require.Equal(t, "1", logged[0]["event_id"])
+		require.NotContains(t, logged[1], "request_id")
+		require.NotContains(t, logged[2], "request_id")
+	})
+
+	t.Run("level switching", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		l, err := New("info", FormatJSON, buf)
+		require.NoError(t, err)
+		derived := l.With("component", "test")
+
+		derived.Debug("hidden")
+		require.NoError(t, l.SetLevel("debug"))
+		derived.Debug("shown")
+		require.ErrorIs(t, l.SetLevel("verbose"), ErrUnknownLevel)
+		derived.Debug("still shown")
+
+		logged := records(t, buf)
+		require.Len(t, logged, 2)
+		require.Equal(t, "shown", logged[0]["msg"])
+	})
+
+	t.Run("text format", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		l, err := New("info", FormatText, buf)
+		require.NoError(t, err)
+
+		l.Info("hello", "user_id", "user")
+		require.Contains(t, buf.String(), "level=INFO msg=hello user_id=user")
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := New("loud", FormatJSON, &bytes.Buffer{})
+		require.ErrorIs(t, err, ErrUnknownLevel)
+
+		_, err = New("info", "xml", &bytes.Buffer{})
+		require.ErrorIs(t, err, ErrUnknownFormat)
+	})
 }
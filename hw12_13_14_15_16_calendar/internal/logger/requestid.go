@@ -0,0 +1,27 @@
+package logger
+
+import "github.com/google/uuid"
+
+// MaxRequestIDLength limits request IDs taken from clients.
+const MaxRequestIDLength = 128
+
+// RequestID returns the request ID sent by the client, if any, or a new one. The ID is logged
+// and sent back to the client, so only short ones of printable ASCII characters are taken.
+func RequestID(id string) string {
+	if !validRequestID(id) {
+		return uuid.NewString()
+	}
+	return id
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > MaxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
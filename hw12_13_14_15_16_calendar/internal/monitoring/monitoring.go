@@ -0,0 +1,98 @@
+// Package monitoring serves health checks and Prometheus metrics of the calendar processes.
+// Metrics themselves are declared by the packages which collect them.
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const checkTimeout = 3 * time.Second
+
+// Check reports whether a dependency of the process, e.g. the storage or the queue, is available.
+type Check func(ctx context.Context) error
+
+var buildInfo = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "calendar_build_info",
+	Help: "Version of the running process, the value is always 1.",
+}, []string{"process", "release", "build_date", "git_hash"})
+
+// SetBuildInfo exposes the version of the process as calendar_build_info metric.
+func SetBuildInfo(process, release, buildDate, gitHash string) {
+	buildInfo.WithLabelValues(process, release, buildDate, gitHash).Set(1)
+}
+
+// Register adds the monitoring endpoints to mux:
+//   - /healthz responds 200 as long as the process serves requests;
+//   - /readyz runs the checks and responds 503 if any of them fails;
+//   - /metrics exposes the metrics in Prometheus format.
+func Register(mux *http.ServeMux, checks map[string]Check) {
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		writeStatus(w, http.StatusOK, map[string]string{})
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		results, ok := runChecks(r.Context(), checks)
+		status := http.StatusOK
+		if !ok {
+			status = http.StatusServiceUnavailable
+		}
+		writeStatus(w, status, results)
+	})
+	mux.Handle("GET /metrics", promhttp.Handler())
+}
+
+// runChecks runs the checks concurrently and returns "ok" or the error of every check.
+func runChecks(ctx context.Context, checks map[string]Check) (map[string]string, bool) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		ok      = true
+		results = make(map[string]string, len(checks))
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := "ok"
+			err := check(ctx)
+			if err != nil {
+				result = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[name] = result
+			ok = ok && err == nil
+		}()
+	}
+	wg.Wait()
+	return results, ok
+}
+
+// statusResponse lists results of the checks by their names.
+type statusResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+func writeStatus(w http.ResponseWriter, status int, results map[string]string) {
+	response := statusResponse{Status: "ok", Checks: results}
+	if status != http.StatusOK {
+		response.Status = "unavailable"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
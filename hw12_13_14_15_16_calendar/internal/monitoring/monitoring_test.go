@@ -0,0 +1,65 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, handler http.Handler, path string) (int, string) {
+	t.Helper()
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, path, nil))
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	return response.Code, string(body)
+}
+
+func TestRegister(t *testing.T) {
+	var queueErr error
+	mux := http.NewServeMux()
+	Register(mux, map[string]Check{
+		"storage": func(context.Context) error { return nil },
+		"queue":   func(context.Context) error { return queueErr },
+	})
+
+	t.Run("health", func(t *testing.T) {
+		code, _ := get(t, mux, "/healthz")
+		require.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("ready", func(t *testing.T) {
+		code, body := get(t, mux, "/readyz")
+		require.Equal(t, http.StatusOK, code)
+		require.JSONEq(t, `{"status":"ok","checks":{"storage":"ok","queue":"ok"}}`, body)
+	})
+
+	t.Run("not ready", func(t *testing.T) {
+		queueErr = errors.New("connection refused")
+		defer func() { queueErr = nil }()
+
+		code, body := get(t, mux, "/readyz")
+		require.Equal(t, http.StatusServiceUnavailable, code)
+
+		var response statusResponse
+		require.NoError(t, json.Unmarshal([]byte(body), &response))
+		require.Equal(t, "unavailable", response.Status)
+		require.Equal(t, map[string]string{"storage": "ok", "queue": "connection refused"}, response.Checks)
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		SetBuildInfo("test", "v1.0.0", "2024-03-11", "abcdef")
+
+		code, body := get(t, mux, "/metrics")
+		require.Equal(t, http.StatusOK, code)
+		require.Contains(t, body,
+			`calendar_build_info{build_date="2024-03-11",git_hash="abcdef",process="test",release="v1.0.0"} 1`)
+	})
+}
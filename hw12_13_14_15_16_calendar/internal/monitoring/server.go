@@ -0,0 +1,56 @@
+package monitoring
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+const (
+	readHeaderTimeout = 5 * time.Second
+	stopTimeout       = 3 * time.Second
+)
+
+type Logger interface {
+	Info(msg string, args ...any)
+}
+
+// Server serves only the monitoring endpoints, it's used by the processes without an API.
+type Server struct {
+	logger Logger
+	server *http.Server
+}
+
+func NewServer(logger Logger, addr string, checks map[string]Check) *Server {
+	mux := http.NewServeMux()
+	Register(mux, checks)
+
+	return &Server{
+		logger: logger,
+		server: &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: readHeaderTimeout,
+		},
+	}
+}
+
+// Run serves requests until ctx is done.
+func (s *Server) Run(ctx context.Context) error {
+	s.logger.Info("monitoring server is listening", "addr", s.server.Addr)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+	return s.server.Shutdown(stopCtx)
+}
@@ -0,0 +1,65 @@
+// Package netguard keeps requests made on behalf of users, e.g. to their webhooks,
+// away from the internal network of the service.
+package netguard
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"syscall"
+)
+
+var ErrForbiddenAddress = errors.New("address is not allowed")
+
+// forbidden lists the networks not covered by the netip.Addr methods: "this" network,
+// the shared address space of carrier-grade NAT, which some clouds use for metadata,
+// and the IETF protocol assignments.
+var forbidden = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+}
+
+// Allowed reports whether the address is a public unicast one. Loopback, private, link-local,
+// including cloud metadata endpoints, unspecified and multicast addresses are not.
+func Allowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range forbidden {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckHost rejects a host which is known to be internal without resolving it: an IP address
+// which isn't allowed or a localhost name. Other names are checked by Control when dialed.
+func CheckHost(host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !Allowed(addr) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
+
+// Control is a net.Dialer Control function refusing connections to addresses which aren't allowed.
+// It's called with the resolved address, so a name resolving to an internal address
+// after it has been checked doesn't get through.
+func Control(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+	if !Allowed(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addrPort.Addr())
+	}
+	return nil
+}
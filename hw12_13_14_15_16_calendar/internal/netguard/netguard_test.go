@@ -0,0 +1,41 @@
+package netguard
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowed(t *testing.T) {
+	for _, addr := range []string{"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946", "8.8.8.8"} {
+		require.True(t, Allowed(netip.MustParseAddr(addr)), addr)
+	}
+	for _, addr := range []string{
+		"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "0.0.0.0", "100.100.100.200",
+		"224.0.0.1", "::1", "::", "fe80::1", "fd00:ec2::254", "::ffff:127.0.0.1", "::ffff:169.254.169.254",
+	} {
+		require.False(t, Allowed(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	for _, host := range []string{"example.com", "93.184.216.34"} {
+		require.NoError(t, CheckHost(host), host)
+	}
+	for _, host := range []string{"localhost", "LOCALHOST.", "api.localhost", "127.0.0.1", "::1", "169.254.169.254"} {
+		require.ErrorIs(t, CheckHost(host), ErrForbiddenAddress, host)
+	}
+}
+
+func TestControl(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	dialer := net.Dialer{Control: Control}
+	_, err = dialer.DialContext(context.Background(), "tcp", listener.Addr().String())
+	require.ErrorIs(t, err, ErrForbiddenAddress)
+}
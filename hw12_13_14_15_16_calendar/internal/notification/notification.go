@@ -0,0 +1,57 @@
+// Package notification describes messages the scheduler sends to the sender through the queue.
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+)
+
+// Names of the channels the sender delivers notifications through.
+const (
+	ChannelLog     = "log"
+	ChannelStdout  = "stdout"
+	ChannelWebhook = "webhook"
+)
+
+// KnownChannel reports whether the name is one of the channels.
+func KnownChannel(name string) bool {
+	return slices.Contains([]string{ChannelLog, ChannelStdout, ChannelWebhook}, name)
+}
+
+// Notification is a transient entity, it's never stored in the database.
+type Notification struct {
+	EventID string    `json:"eventId"`
+	Title   string    `json:"title"`
+	Date    time.Time `json:"date"`
+	UserID  string    `json:"userId"`
+	// PublishedAt is the time the scheduler put the notification to the queue,
+	// it's zero for notifications published by older versions.
+	PublishedAt time.Time `json:"publishedAt"`
+	// Channel is the only channel to deliver the notification through, empty means all of them.
+	Channel string `json:"channel,omitempty"`
+}
+
+func FromEvent(event storage.Event) Notification {
+	return Notification{
+		EventID: event.ID,
+		Title:   event.Title,
+		Date:    event.StartAt,
+		UserID:  event.UserID,
+	}
+}
+
+func (n Notification) Marshal() ([]byte, error) {
+	return json.Marshal(n)
+}
+
+func Unmarshal(data []byte) (Notification, error) {
+	var n Notification
+	if err := json.Unmarshal(data, &n); err != nil {
+		return Notification{}, fmt.Errorf("failed to decode notification: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,59 @@
+// Package queuefactory creates the queue described by the configuration,
+// so the scheduler and the sender pick it the same way.
+package queuefactory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/config"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/queue"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/queue/local"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/queue/rabbit"
+)
+
+var ErrUnknownQueue = errors.New("unknown queue driver")
+
+// Publisher is a publisher whose connection to the queue can be checked.
+type Publisher interface {
+	queue.Publisher
+	Ping(ctx context.Context) error
+}
+
+// NewPublisher creates the publisher configured by conf. The returned function must be called
+// to release the publisher resources.
+func NewPublisher(ctx context.Context, conf config.QueueConf) (Publisher, func(context.Context) error, error) {
+	switch conf.Driver {
+	case config.QueueRabbit:
+		p := rabbit.NewPublisher(conf.URL, conf.Name)
+		if err := p.Connect(ctx); err != nil {
+			return nil, nil, err
+		}
+		return p, p.Close, nil
+	case config.QueueLocal:
+		q, err := local.NewDir(conf.Dir, conf.Prefetch)
+		if err != nil {
+			return nil, nil, err
+		}
+		return q, func(context.Context) error { return nil }, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: %q", ErrUnknownQueue, conf.Driver)
+	}
+}
+
+// NewConsumer creates the consumer configured by conf.
+func NewConsumer(conf config.QueueConf) (queue.Consumer, error) {
+	switch conf.Driver {
+	case config.QueueRabbit:
+		return rabbit.NewConsumer(conf.URL, conf.Name, conf.Prefetch), nil
+	case config.QueueLocal:
+		q, err := local.NewDir(conf.Dir, conf.Prefetch)
+		if err != nil {
+			return nil, err
+		}
+		return q, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownQueue, conf.Driver)
+	}
+}
@@ -0,0 +1,77 @@
+package local
+
+import (
+	"slices"
+)
+
+type consumer struct {
+	queue *Queue
+
+	// unacked and closed are guarded by queue.mu
+	unacked map[uint64]struct{}
+	closed  bool
+}
+
+// close returns unacknowledged messages to the head of the queue in their original order,
+// like a broker does when a connection is lost. Their attempt numbers are kept.
+func (c *consumer) close() {
+	q := c.queue
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	c.closed = true
+	if len(c.unacked) == 0 {
+		return
+	}
+
+	messages := make([]*message, 0, len(c.unacked))
+	for id := range c.unacked {
+		messages = append(messages, q.unacked[id])
+		delete(q.unacked, id)
+	}
+	sortByID(messages)
+	q.ready = append(messages, q.ready...)
+	c.unacked = nil
+
+	// the messages are still in the file, so there is nothing to save
+	q.notify()
+}
+
+// settle removes the message from the unacknowledged ones and, if requeue is set,
+// puts it back to the tail of the queue.
+func (c *consumer) settle(m *message, requeue bool) error {
+	q := c.queue
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if c.closed {
+		return ErrConsumerClosed
+	}
+	if _, ok := c.unacked[m.ID]; !ok {
+		return ErrAlreadySettled
+	}
+
+	delete(c.unacked, m.ID)
+	delete(q.unacked, m.ID)
+	if requeue {
+		q.ready = append(q.ready, &message{ID: m.ID, Body: m.Body, Attempt: m.Attempt + 1})
+	}
+	if err := q.save(); err != nil {
+		return err
+	}
+	q.notify()
+	return nil
+}
+
+func sortByID(messages []*message) {
+	slices.SortFunc(messages, func(a, b *message) int {
+		switch {
+		case a.ID < b.ID:
+			return -1
+		case a.ID > b.ID:
+			return 1
+		default:
+			return 0
+		}
+	})
+}
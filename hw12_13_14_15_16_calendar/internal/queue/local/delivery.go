@@ -0,0 +1,37 @@
+package local
+
+import "errors"
+
+var ErrAlreadySettled = errors.New("message is already acknowledged")
+
+type delivery struct {
+	consumer *consumer
+	msg      *message
+}
+
+func (d *delivery) Body() []byte {
+	return d.msg.Body
+}
+
+func (d *delivery) Attempt() int {
+	return d.msg.Attempt
+}
+
+func (d *delivery) Ack() error {
+	return d.consumer.settle(d.msg, false)
+}
+
+func (d *delivery) Nack(requeue bool) error {
+	if requeue {
+		return d.consumer.settle(d.msg, true)
+	}
+
+	// the message is put to the dead-letter queue before it's removed from this one,
+	// so a failure in between leads to a duplicate rather than a loss
+	if dead := d.consumer.queue.dead; dead != nil {
+		if err := dead.push(&message{Body: d.msg.Body, Attempt: d.msg.Attempt}); err != nil {
+			return err
+		}
+	}
+	return d.consumer.settle(d.msg, false)
+}
@@ -0,0 +1,420 @@
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/queue"
+)
+
+const (
+	// dirPoll is how often consumers look for messages published by other processes.
+	dirPoll = 100 * time.Millisecond
+	// claimLease is how long the claims of a consumer that stopped refreshing them are kept,
+	// after that they are returned to the queue.
+	claimLease = time.Minute
+)
+
+// Dir is a queue kept in a directory, a file per message, so processes sharing the directory,
+// e.g. the scheduler and the sender, pass messages through it. Messages are moved between
+// the subdirectories by renames, so every message is delivered to one consumer at a time:
+//
+//	ready/         messages waiting for delivery in the order of their names
+//	claimed/<id>/  messages delivered to a consumer and not settled yet
+//	tmp/           messages being written
+//	dead/          the dead-letter queue of the same layout
+//
+// Every consumer gets at most prefetch unacknowledged messages at a time, zero means no limit.
+// Messages unacknowledged by a consumer are returned to the queue when its context is done,
+// or when the consumer stops refreshing its claims for claimLease, e.g. after a crash.
+// A consumer which fails to keep its claims stops, like one which has lost the connection to a broker.
+type Dir struct {
+	path     string
+	prefetch int
+	dead     *Dir
+	seq      atomic.Uint64
+}
+
+// NewDir creates a queue in the directory at path along with its dead-letter queue in path/dead.
+func NewDir(path string, prefetch int) (*Dir, error) {
+	dead, err := newDir(filepath.Join(path, "dead"), prefetch, nil)
+	if err != nil {
+		return nil, err
+	}
+	return newDir(path, prefetch, dead)
+}
+
+func newDir(path string, prefetch int, dead *Dir) (*Dir, error) {
+	for _, sub := range []string{"ready", "claimed", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(path, sub), 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create queue: %w", err)
+		}
+	}
+	return &Dir{path: path, prefetch: prefetch, dead: dead}, nil
+}
+
+// DeadLetters returns the queue rejected messages are moved to.
+// It has no dead-letter queue of its own, so messages rejected there are dropped.
+func (q *Dir) DeadLetters() *Dir {
+	return q.dead
+}
+
+// Ping checks that the queue directory is still there.
+func (q *Dir) Ping(_ context.Context) error {
+	info, err := os.Stat(q.dir("ready"))
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", q.dir("ready"))
+	}
+	return nil
+}
+
+func (q *Dir) Publish(_ context.Context, body []byte) error {
+	return q.push(&message{Body: body, Attempt: 1})
+}
+
+// push writes the message aside and then renames it into ready/, so consumers never see it half-written.
+// Names start with the time of the push, so messages are delivered in the order they are pushed.
+func (q *Dir) push(m *message) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	name := fmt.Sprintf("%020d-%d-%d", time.Now().UnixNano(), os.Getpid(), q.seq.Add(1))
+	tmp := filepath.Join(q.dir("tmp"), name)
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(q.dir("ready"), name)); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+func (q *Dir) Consume(ctx context.Context) (<-chan queue.Delivery, error) {
+	claims, err := os.MkdirTemp(q.dir("claimed"), "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to register consumer: %w", err)
+	}
+
+	c := &dirConsumer{
+		queue:   q,
+		claims:  claims,
+		unacked: make(map[string]struct{}),
+		settled: make(chan struct{}, 1),
+	}
+	if err := c.refresh(); err != nil {
+		_ = returnClaims(claims, q.dir("ready"))
+		return nil, err
+	}
+	deliveries := make(chan queue.Delivery)
+	ctx, stop := context.WithCancel(ctx)
+
+	// the lease is kept apart from the deliveries, which wait for the messages to be taken
+	var refreshing sync.WaitGroup
+	refreshing.Add(1)
+	go func() {
+		defer refreshing.Done()
+		c.keepLease(ctx, stop)
+	}()
+
+	go func() {
+		defer close(deliveries)
+		defer c.close()
+		defer refreshing.Wait()
+		defer stop()
+
+		ticker := time.NewTicker(dirPoll)
+		defer ticker.Stop()
+
+		for {
+			d, err := c.claim()
+			if err != nil {
+				// the consumer stops as if the connection to a broker was lost
+				return
+			}
+			if d != nil {
+				select {
+				case deliveries <- d:
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+
+			select {
+			case <-c.settled:
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return deliveries, nil
+}
+
+func (q *Dir) dir(sub string) string {
+	return filepath.Join(q.path, sub)
+}
+
+type dirConsumer struct {
+	queue  *Dir
+	claims string
+
+	mu      sync.Mutex
+	unacked map[string]struct{}
+	closed  bool
+	// settled wakes up the consumer when a message is settled, so it doesn't wait for the poll.
+	settled chan struct{}
+}
+
+// claim moves the first ready message to the claims of the consumer, unless prefetch is exhausted.
+// A message claimed by another consumer in between is skipped, so is one still held by this consumer,
+// which another one has returned to the queue after taking the lease for expired.
+func (c *dirConsumer) claim() (*dirDelivery, error) {
+	q := c.queue
+	c.mu.Lock()
+	full := q.prefetch > 0 && len(c.unacked) >= q.prefetch
+	c.mu.Unlock()
+	if full {
+		return nil, nil
+	}
+	if err := c.register(); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(q.dir("ready"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+	for _, e := range entries {
+		name := e.Name()
+		c.mu.Lock()
+		_, held := c.unacked[name]
+		c.mu.Unlock()
+		if held {
+			continue
+		}
+		claimed := filepath.Join(c.claims, name)
+		err := os.Rename(filepath.Join(q.dir("ready"), name), claimed)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim message: %w", err)
+		}
+
+		var m message
+		data, err := os.ReadFile(claimed)
+		if err == nil {
+			err = json.Unmarshal(data, &m)
+		}
+		if err != nil {
+			// a message which can't be read would be delivered forever otherwise
+			if q.dead != nil && os.Rename(claimed, filepath.Join(q.dead.dir("ready"), name)) == nil {
+				continue
+			}
+			if err := os.Remove(claimed); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("failed to drop malformed message: %w", err)
+			}
+			continue
+		}
+
+		c.mu.Lock()
+		c.unacked[name] = struct{}{}
+		c.mu.Unlock()
+		return &dirDelivery{consumer: c, name: name, msg: &m}, nil
+	}
+	return nil, nil
+}
+
+// register creates the claims directory of the consumer again if another consumer has returned
+// its claims, taking the lease for expired. The messages are delivered again then anyway.
+func (c *dirConsumer) register() error {
+	if err := os.Mkdir(c.claims, 0o700); err != nil && !errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("failed to register consumer: %w", err)
+	}
+	return nil
+}
+
+// keepLease refreshes the claims every claimLease/4 until ctx is done. If the claims
+// can't be kept, the consumer is stopped by stop.
+func (c *dirConsumer) keepLease(ctx context.Context, stop func()) {
+	ticker := time.NewTicker(claimLease / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		if err := c.refresh(); err != nil {
+			stop()
+			return
+		}
+	}
+}
+
+// refresh extends the lease of the consumer claims and returns the claims of consumers
+// whose lease has expired to the queue.
+func (c *dirConsumer) refresh() error {
+	q := c.queue
+	now := time.Now()
+	if err := c.register(); err != nil {
+		return err
+	}
+	if err := os.Chtimes(c.claims, now, now); err != nil {
+		return fmt.Errorf("failed to extend lease: %w", err)
+	}
+
+	entries, err := os.ReadDir(q.dir("claimed"))
+	if err != nil {
+		return fmt.Errorf("failed to list claims: %w", err)
+	}
+	var errs []error
+	for _, e := range entries {
+		path := filepath.Join(q.dir("claimed"), e.Name())
+		if path == c.claims {
+			continue
+		}
+		info, err := e.Info()
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to check lease: %w", err))
+			continue
+		}
+		if now.Sub(info.ModTime()) < claimLease {
+			continue
+		}
+
+		// the claims are taken over first, so neither another consumer returns them at the same time
+		// nor the consumer they belong to claims more messages into them, it registers anew instead;
+		// the taken ones keep the expired lease, so they're returned by someone if this one fails
+		taken := fmt.Sprintf("%s.%d-%d", path, os.Getpid(), q.seq.Add(1))
+		if err := os.Rename(path, taken); err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("failed to take expired claims: %w", err))
+			}
+			continue
+		}
+		if err := returnClaims(taken, q.dir("ready")); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// close returns unacknowledged messages to the queue. They keep their names,
+// so they are delivered before the messages published later, like a broker does.
+func (c *dirConsumer) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	// the claims left are taken over after the lease expires otherwise
+	_ = returnClaims(c.claims, c.queue.dir("ready"))
+	c.unacked = nil
+}
+
+// settle removes the claimed message after push, if any, has put it elsewhere,
+// so a failure in between leads to a duplicate rather than a loss.
+func (c *dirConsumer) settle(name string, push func() error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrConsumerClosed
+	}
+	if _, ok := c.unacked[name]; !ok {
+		return ErrAlreadySettled
+	}
+
+	if push != nil {
+		if err := push(); err != nil {
+			return err
+		}
+	}
+	// the message may be gone if the lease has expired, then it's delivered again anyway
+	if err := os.Remove(filepath.Join(c.claims, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove message: %w", err)
+	}
+	delete(c.unacked, name)
+
+	select {
+	case c.settled <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// returnClaims moves the claimed messages back to ready and removes the claims directory.
+// Messages which are gone have been settled or returned by someone else meanwhile.
+func returnClaims(claims, ready string) error {
+	entries, err := os.ReadDir(claims)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list claims: %w", err)
+	}
+
+	var errs []error
+	for _, e := range entries {
+		err := os.Rename(filepath.Join(claims, e.Name()), filepath.Join(ready, e.Name()))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("failed to return message: %w", err))
+		}
+	}
+	if err := os.Remove(claims); err != nil && !errors.Is(err, os.ErrNotExist) {
+		errs = append(errs, fmt.Errorf("failed to remove claims: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+type dirDelivery struct {
+	consumer *dirConsumer
+	name     string
+	msg      *message
+}
+
+func (d *dirDelivery) Body() []byte {
+	return d.msg.Body
+}
+
+func (d *dirDelivery) Attempt() int {
+	return d.msg.Attempt
+}
+
+func (d *dirDelivery) Ack() error {
+	return d.consumer.settle(d.name, nil)
+}
+
+func (d *dirDelivery) Nack(requeue bool) error {
+	q := d.consumer.queue
+	if requeue {
+		return d.consumer.settle(d.name, func() error {
+			return q.push(&message{Body: d.msg.Body, Attempt: d.msg.Attempt + 1})
+		})
+	}
+	if q.dead == nil {
+		return d.consumer.settle(d.name, nil)
+	}
+	return d.consumer.settle(d.name, func() error {
+		return q.dead.push(&message{Body: d.msg.Body, Attempt: d.msg.Attempt})
+	})
+}
@@ -0,0 +1,195 @@
+// Package local implements the queue without a broker: Queue within the process, optionally keeping
+// messages in a file so they survive restarts, and Dir shared by processes through a directory.
+// Both follow the delivery semantics of the AMQP implementation, so the whole notification
+// pipeline can run without a broker.
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/queue"
+)
+
+var ErrConsumerClosed = errors.New("consumer is closed")
+
+type message struct {
+	ID      uint64 `json:"id,omitempty"`
+	Body    []byte `json:"body"`
+	Attempt int    `json:"attempt"`
+}
+
+// Queue is safe for concurrent use by any number of publishers and consumers.
+// Every consumer gets at most prefetch unacknowledged messages at a time, zero means no limit.
+// Messages unacknowledged by a consumer are returned to the queue when its context is done.
+type Queue struct {
+	path     string
+	prefetch int
+	dead     *Queue
+
+	mu      sync.Mutex
+	nextID  uint64
+	ready   []*message
+	unacked map[uint64]*message
+	// changed is closed and replaced whenever there may be messages to dispatch.
+	changed chan struct{}
+}
+
+// New creates a queue along with its dead-letter queue. If path isn't empty, messages are
+// kept in the file at path and the dead-letter ones in path.dead; the messages found there
+// are delivered again.
+func New(path string, prefetch int) (*Queue, error) {
+	dead, err := newQueue(deadLetterPath(path), prefetch, nil)
+	if err != nil {
+		return nil, err
+	}
+	return newQueue(path, prefetch, dead)
+}
+
+func newQueue(path string, prefetch int, dead *Queue) (*Queue, error) {
+	q := &Queue{
+		path:     path,
+		prefetch: prefetch,
+		dead:     dead,
+		unacked:  make(map[uint64]*message),
+		changed:  make(chan struct{}),
+	}
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// DeadLetters returns the queue rejected messages are moved to.
+// It has no dead-letter queue of its own, so messages rejected there are dropped.
+func (q *Queue) DeadLetters() *Queue {
+	return q.dead
+}
+
+func (q *Queue) Publish(_ context.Context, body []byte) error {
+	return q.push(&message{Body: body, Attempt: 1})
+}
+
+func (q *Queue) push(m *message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.nextID++
+	m.ID = q.nextID
+	q.ready = append(q.ready, m)
+	if err := q.save(); err != nil {
+		q.ready = q.ready[:len(q.ready)-1]
+		return err
+	}
+	q.notify()
+	return nil
+}
+
+func (q *Queue) Consume(ctx context.Context) (<-chan queue.Delivery, error) {
+	c := &consumer{queue: q, unacked: make(map[uint64]struct{})}
+	deliveries := make(chan queue.Delivery)
+
+	go func() {
+		defer close(deliveries)
+		defer c.close()
+
+		for {
+			q.mu.Lock()
+			var m *message
+			if len(q.ready) > 0 && (q.prefetch <= 0 || len(c.unacked) < q.prefetch) {
+				m = q.ready[0]
+				q.ready = q.ready[1:]
+				q.unacked[m.ID] = m
+				c.unacked[m.ID] = struct{}{}
+			}
+			changed := q.changed
+			q.mu.Unlock()
+
+			if m != nil {
+				select {
+				case deliveries <- &delivery{consumer: c, msg: m}:
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return deliveries, nil
+}
+
+// notify wakes up consumers waiting for messages. q.mu must be held.
+func (q *Queue) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+func (q *Queue) load() error {
+	if q.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(q.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read queue: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &q.ready); err != nil {
+		return fmt.Errorf("failed to decode queue %s: %w", q.path, err)
+	}
+	for _, m := range q.ready {
+		q.nextID = max(q.nextID, m.ID)
+	}
+	return nil
+}
+
+// save writes all the messages not acknowledged yet, both ready and delivered ones,
+// as the latter have to be delivered again after a restart. q.mu must be held.
+func (q *Queue) save() error {
+	if q.path == "" {
+		return nil
+	}
+
+	messages := make([]*message, 0, len(q.unacked)+len(q.ready))
+	for _, m := range q.unacked {
+		messages = append(messages, m)
+	}
+	sortByID(messages)
+	messages = append(messages, q.ready...)
+
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return fmt.Errorf("failed to encode queue: %w", err)
+	}
+
+	// replace the file atomically, so a crash never leaves it half-written
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write queue: %w", err)
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		return fmt.Errorf("failed to write queue: %w", err)
+	}
+	return nil
+}
+
+func deadLetterPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	return path + ".dead"
+}
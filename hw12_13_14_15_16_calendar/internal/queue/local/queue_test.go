@@ -0,0 +1,191 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/queue/queuetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueue(t *testing.T) {
+	queuetest.Run(t, func(t *testing.T, prefetch int) queuetest.Queue {
+		t.Helper()
+		q, err := New("", prefetch)
+		require.NoError(t, err)
+		return queuetest.Queue{Publisher: q, Consumer: q, DeadLetters: q.DeadLetters()}
+	})
+}
+
+func TestFileQueue(t *testing.T) {
+	queuetest.Run(t, func(t *testing.T, prefetch int) queuetest.Queue {
+		t.Helper()
+		q, err := New(filepath.Join(t.TempDir(), "queue.json"), prefetch)
+		require.NoError(t, err)
+		return queuetest.Queue{Publisher: q, Consumer: q, DeadLetters: q.DeadLetters()}
+	})
+}
+
+func TestRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	q, err := New(path, 10)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, body := range []string{"acked", "unacked", "retried", "dead", "ready"} {
+		require.NoError(t, q.Publish(ctx, []byte(body)))
+	}
+
+	deliveries, err := q.Consume(ctx)
+	require.NoError(t, err)
+	require.NoError(t, queuetest.Receive(t, deliveries).Ack())
+	queuetest.Receive(t, deliveries)
+	require.NoError(t, queuetest.Receive(t, deliveries).Nack(true))
+	require.NoError(t, queuetest.Receive(t, deliveries).Nack(false))
+
+	// the process dies without closing anything
+	q, err = New(path, 10)
+	require.NoError(t, err)
+
+	deliveries, err = q.Consume(ctx)
+	require.NoError(t, err)
+	var bodies []string
+	for range 3 {
+		d := queuetest.Receive(t, deliveries)
+		bodies = append(bodies, string(d.Body()))
+		require.NoError(t, d.Ack())
+	}
+	require.Equal(t, []string{"unacked", "ready", "retried"}, bodies)
+
+	dead, err := q.DeadLetters().Consume(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "dead", string(queuetest.Receive(t, dead).Body()))
+}
+
+func TestSettleTwice(t *testing.T) {
+	q, err := New("", 10)
+	require.NoError(t, err)
+	require.NoError(t, q.Publish(context.Background(), []byte("a")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	deliveries, err := q.Consume(ctx)
+	require.NoError(t, err)
+
+	d := queuetest.Receive(t, deliveries)
+	require.NoError(t, d.Ack())
+	require.ErrorIs(t, d.Ack(), ErrAlreadySettled)
+
+	require.NoError(t, q.Publish(context.Background(), []byte("b")))
+	d = queuetest.Receive(t, deliveries)
+	cancel()
+	for range deliveries { //nolint:revive
+	}
+	require.ErrorIs(t, d.Ack(), ErrConsumerClosed)
+}
+
+func TestDirQueue(t *testing.T) {
+	queuetest.Run(t, func(t *testing.T, prefetch int) queuetest.Queue {
+		t.Helper()
+		q, err := NewDir(t.TempDir(), prefetch)
+		require.NoError(t, err)
+		return queuetest.Queue{Publisher: q, Consumer: q, DeadLetters: q.DeadLetters()}
+	})
+}
+
+func TestDirProcesses(t *testing.T) {
+	path := t.TempDir()
+	publisher, err := NewDir(path, 10)
+	require.NoError(t, err)
+	consumer, err := NewDir(path, 10)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	deliveries, err := consumer.Consume(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, publisher.Publish(ctx, []byte("a")))
+	d := queuetest.Receive(t, deliveries)
+	require.Equal(t, "a", string(d.Body()))
+	require.NoError(t, d.Nack(false))
+
+	dead, err := publisher.DeadLetters().Consume(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "a", string(queuetest.Receive(t, dead).Body()))
+}
+
+func TestDirExpiredClaims(t *testing.T) {
+	path := t.TempDir()
+	q, err := NewDir(path, 10)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, q.Publish(ctx, []byte("a")))
+
+	// a consumer process claimed the message and died without returning it
+	ready, err := os.ReadDir(filepath.Join(path, "ready"))
+	require.NoError(t, err)
+	require.Len(t, ready, 1)
+	claims := filepath.Join(path, "claimed", "dead-consumer")
+	require.NoError(t, os.Mkdir(claims, 0o700))
+	name := ready[0].Name()
+	require.NoError(t, os.Rename(filepath.Join(path, "ready", name), filepath.Join(claims, name)))
+
+	deliveries, err := q.Consume(ctx)
+	require.NoError(t, err)
+	queuetest.NoDelivery(t, deliveries)
+	cancel()
+	for range deliveries { //nolint:revive
+	}
+
+	expired := time.Now().Add(-2 * claimLease)
+	require.NoError(t, os.Chtimes(claims, expired, expired))
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	deliveries, err = q.Consume(ctx)
+	require.NoError(t, err)
+	d := queuetest.Receive(t, deliveries)
+	require.Equal(t, "a", string(d.Body()))
+	require.Equal(t, 1, d.Attempt())
+}
+
+func TestDirTakenClaims(t *testing.T) {
+	path := t.TempDir()
+	q, err := NewDir(path, 10)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	deliveries, err := q.Consume(ctx)
+	require.NoError(t, err)
+	require.NoError(t, q.Publish(ctx, []byte("a")))
+	held := queuetest.Receive(t, deliveries)
+
+	// another process takes the lease of the consumer for expired and returns its claims
+	claimed, err := os.ReadDir(filepath.Join(path, "claimed"))
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	expired := time.Now().Add(-2 * claimLease)
+	require.NoError(t, os.Chtimes(filepath.Join(path, "claimed", claimed[0].Name()), expired, expired))
+
+	other, err := NewDir(path, 10)
+	require.NoError(t, err)
+	otherCtx, otherCancel := context.WithCancel(ctx)
+	otherDeliveries, err := other.Consume(otherCtx)
+	require.NoError(t, err)
+	d := queuetest.Receive(t, otherDeliveries)
+	require.Equal(t, "a", string(d.Body()))
+	require.NoError(t, d.Ack())
+	otherCancel()
+	for range otherDeliveries { //nolint:revive
+	}
+
+	// the consumer keeps receiving messages after that
+	require.NoError(t, held.Ack())
+	require.NoError(t, q.Publish(ctx, []byte("b")))
+	require.Equal(t, "b", string(queuetest.Receive(t, deliveries).Body()))
+}
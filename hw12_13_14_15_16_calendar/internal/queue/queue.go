@@ -0,0 +1,29 @@
+// Package queue declares the message queue used to pass notifications between processes.
+// Implementations live in subpackages.
+package queue
+
+import "context"
+
+// Publisher sends messages to the queue. Publish returns only after the queue
+// has taken responsibility for the message.
+type Publisher interface {
+	Publish(ctx context.Context, body []byte) error
+}
+
+// Delivery is a message received from the queue. Exactly one of Ack and Nack must be called
+// after the message is processed.
+type Delivery interface {
+	Body() []byte
+	// Attempt returns the number of the delivery attempt starting from 1.
+	Attempt() int
+	Ack() error
+	// Nack returns the message to the queue for another attempt if requeue is set,
+	// otherwise the message is moved to the dead-letter queue.
+	Nack(requeue bool) error
+}
+
+// Consumer receives messages from the queue. The channel returned by Consume is closed
+// when ctx is done or the connection to the queue is lost.
+type Consumer interface {
+	Consume(ctx context.Context) (<-chan Delivery, error)
+}
@@ -0,0 +1,151 @@
+// Package queuetest contains delivery semantics tests shared by all queue implementations.
+package queuetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/queue"
+	"github.com/stretchr/testify/require"
+)
+
+type Queue struct {
+	queue.Publisher
+	queue.Consumer
+	// DeadLetters consumes messages rejected from the queue.
+	DeadLetters queue.Consumer
+}
+
+// Receive waits for the next delivery.
+func Receive(t *testing.T, deliveries <-chan queue.Delivery) queue.Delivery {
+	t.Helper()
+
+	select {
+	case d, ok := <-deliveries:
+		require.True(t, ok, "deliveries channel is closed")
+		return d
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no message is delivered")
+		return nil
+	}
+}
+
+// NoDelivery checks that nothing is delivered for a while.
+func NoDelivery(t *testing.T, deliveries <-chan queue.Delivery) {
+	t.Helper()
+
+	select {
+	case d := <-deliveries:
+		require.FailNow(t, "unexpected delivery", "body: %s", d.Body())
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// Run checks that the queue returned by newQueue behaves as expected.
+// Every subtest gets a fresh empty queue.
+func Run(t *testing.T, newQueue func(t *testing.T, prefetch int) Queue) {
+	t.Helper()
+
+	consume := func(t *testing.T, c queue.Consumer) (<-chan queue.Delivery, context.CancelFunc) {
+		t.Helper()
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+
+		deliveries, err := c.Consume(ctx)
+		require.NoError(t, err)
+		return deliveries, cancel
+	}
+
+	publish := func(t *testing.T, q Queue, bodies ...string) {
+		t.Helper()
+		for _, body := range bodies {
+			require.NoError(t, q.Publish(context.Background(), []byte(body)))
+		}
+	}
+
+	t.Run("ack", func(t *testing.T) {
+		q := newQueue(t, 10)
+		publish(t, q, "a", "b")
+		deliveries, _ := consume(t, q)
+
+		for _, body := range []string{"a", "b"} {
+			d := Receive(t, deliveries)
+			require.Equal(t, body, string(d.Body()))
+			require.Equal(t, 1, d.Attempt())
+			require.NoError(t, d.Ack())
+		}
+		NoDelivery(t, deliveries)
+	})
+
+	t.Run("nack with requeue", func(t *testing.T) {
+		q := newQueue(t, 10)
+		publish(t, q, "a")
+		deliveries, _ := consume(t, q)
+
+		d := Receive(t, deliveries)
+		require.NoError(t, d.Nack(true))
+
+		d = Receive(t, deliveries)
+		require.Equal(t, "a", string(d.Body()))
+		require.Equal(t, 2, d.Attempt())
+		require.NoError(t, d.Ack())
+		NoDelivery(t, deliveries)
+	})
+
+	t.Run("nack to dead letters", func(t *testing.T) {
+		q := newQueue(t, 10)
+		publish(t, q, "a")
+		deliveries, _ := consume(t, q)
+
+		require.NoError(t, Receive(t, deliveries).Nack(false))
+		NoDelivery(t, deliveries)
+
+		dead, _ := consume(t, q.DeadLetters)
+		d := Receive(t, dead)
+		require.Equal(t, "a", string(d.Body()))
+		require.NoError(t, d.Ack())
+	})
+
+	t.Run("prefetch", func(t *testing.T) {
+		q := newQueue(t, 1)
+		publish(t, q, "a", "b")
+		deliveries, _ := consume(t, q)
+
+		d := Receive(t, deliveries)
+		require.Equal(t, "a", string(d.Body()))
+		NoDelivery(t, deliveries)
+
+		require.NoError(t, d.Ack())
+		require.Equal(t, "b", string(Receive(t, deliveries).Body()))
+	})
+
+	t.Run("redelivery after consumer is gone", func(t *testing.T) {
+		q := newQueue(t, 10)
+		publish(t, q, "a")
+
+		deliveries, cancel := consume(t, q)
+		require.Equal(t, "a", string(Receive(t, deliveries).Body()))
+		cancel()
+		for range deliveries { //nolint:revive
+			// wait for the consumer to stop
+		}
+
+		deliveries, _ = consume(t, q)
+		d := Receive(t, deliveries)
+		require.Equal(t, "a", string(d.Body()))
+		require.NoError(t, d.Ack())
+	})
+
+	t.Run("competing consumers", func(t *testing.T) {
+		q := newQueue(t, 1)
+		publish(t, q, "a", "b")
+		first, _ := consume(t, q)
+		second, _ := consume(t, q)
+
+		d1, d2 := Receive(t, first), Receive(t, second)
+		require.ElementsMatch(t, []string{"a", "b"}, []string{string(d1.Body()), string(d2.Body())})
+		require.NoError(t, d1.Ack())
+		require.NoError(t, d2.Ack())
+	})
+}
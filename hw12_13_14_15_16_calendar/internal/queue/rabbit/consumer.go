@@ -0,0 +1,152 @@
+package rabbit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/queue"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type Consumer struct {
+	url      string
+	queue    string
+	source   string
+	prefetch int
+}
+
+// NewConsumer creates a consumer receiving at most prefetch unacknowledged messages at a time,
+// zero means no limit.
+func NewConsumer(url, queue string, prefetch int) *Consumer {
+	return &Consumer{
+		url:      url,
+		queue:    queue,
+		source:   queue,
+		prefetch: prefetch,
+	}
+}
+
+// NewDeadLetterConsumer creates a consumer of messages rejected from the queue.
+// Messages rejected from the dead-letter queue itself are dropped.
+func NewDeadLetterConsumer(url, queue string, prefetch int) *Consumer {
+	c := NewConsumer(url, queue, prefetch)
+	c.source = DeadLetterQueue(queue)
+	return c
+}
+
+// Consume opens a new connection for every call, which is closed along with the returned channel.
+func (c *Consumer) Consume(ctx context.Context) (<-chan queue.Delivery, error) {
+	conn, err := amqp.Dial(c.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to broker: %w", err)
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+	// confirms are needed to retry messages safely, see delivery.Nack
+	if err := ch.Confirm(false); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+	if err := declare(ch, c.queue); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := ch.Qos(c.prefetch, 0, false); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to set prefetch: %w", err)
+	}
+
+	messages, err := ch.ConsumeWithContext(ctx, c.source, "", false, false, false, false, nil)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to start consuming: %w", err)
+	}
+
+	deliveries := make(chan queue.Delivery)
+	go func() {
+		defer close(deliveries)
+		defer conn.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				select {
+				case deliveries <- &delivery{ctx: ctx, ch: ch, queue: c.source, msg: msg}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return deliveries, nil
+}
+
+type delivery struct {
+	ctx   context.Context
+	ch    *amqp.Channel
+	queue string
+	msg   amqp.Delivery
+}
+
+func (d *delivery) Body() []byte {
+	return d.msg.Body
+}
+
+func (d *delivery) Attempt() int {
+	switch attempt := d.msg.Headers[attemptHeader].(type) {
+	case int32:
+		return int(attempt)
+	case int64:
+		return int(attempt)
+	default:
+		return 1
+	}
+}
+
+func (d *delivery) Ack() error {
+	return d.msg.Ack(false)
+}
+
+// Nack with requeue publishes a copy of the message with the increased attempt number
+// to the end of the queue and only then acknowledges the original one. A plain requeue
+// would lose the number of attempts.
+func (d *delivery) Nack(requeue bool) error {
+	if !requeue {
+		return d.msg.Nack(false, false)
+	}
+
+	headers := amqp.Table{}
+	for k, v := range d.msg.Headers {
+		headers[k] = v
+	}
+	headers[attemptHeader] = int32(d.Attempt() + 1) //nolint:gosec
+
+	confirmation, err := d.ch.PublishWithDeferredConfirmWithContext(d.ctx, "", d.queue, false, false, amqp.Publishing{
+		Headers:      headers,
+		ContentType:  d.msg.ContentType,
+		DeliveryMode: amqp.Persistent,
+		Body:         d.msg.Body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to requeue message: %w", err)
+	}
+	acked, err := confirmation.WaitContext(d.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to wait for confirmation: %w", err)
+	}
+	if !acked {
+		return ErrNotConfirmed
+	}
+
+	return d.msg.Ack(false)
+}
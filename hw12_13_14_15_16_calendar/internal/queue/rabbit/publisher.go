@@ -0,0 +1,149 @@
+package rabbit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var (
+	ErrNotConfirmed = errors.New("message is not confirmed by the broker")
+	ErrNotConnected = errors.New("not connected to the broker")
+)
+
+// Publisher sends persistent messages to a durable queue and waits for publisher confirms.
+// A lost connection is re-established on the next Publish.
+type Publisher struct {
+	url   string
+	queue string
+
+	mu     sync.Mutex
+	conn   *amqp.Connection
+	ch     *amqp.Channel
+	closed bool
+
+	// current mirrors ch, so Ping doesn't wait for a Publish holding mu.
+	current      atomic.Pointer[amqp.Channel]
+	reconnecting atomic.Bool
+}
+
+func NewPublisher(url, queue string) *Publisher {
+	return &Publisher{
+		url:   url,
+		queue: queue,
+	}
+}
+
+// Connect establishes the connection beforehand, so configuration errors show up at start.
+func (p *Publisher) Connect(_ context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.connect()
+}
+
+// Ping checks the connection to the broker without waiting for anything. A lost connection
+// is re-established in the background, so a later Ping succeeds even if nothing is published.
+func (p *Publisher) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if ch := p.current.Load(); ch != nil && !ch.IsClosed() {
+		return nil
+	}
+
+	if p.reconnecting.CompareAndSwap(false, true) {
+		go func() {
+			defer p.reconnecting.Store(false)
+
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			if !p.closed && (p.ch == nil || p.ch.IsClosed()) {
+				p.connect() //nolint:errcheck
+			}
+		}()
+	}
+	return ErrNotConnected
+}
+
+func (p *Publisher) Publish(ctx context.Context, body []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ch == nil || p.ch.IsClosed() {
+		if err := p.connect(); err != nil {
+			return err
+		}
+	}
+
+	confirmation, err := p.ch.PublishWithDeferredConfirmWithContext(ctx, "", p.queue, false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Body:         body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to wait for confirmation: %w", err)
+	}
+	if !acked {
+		return ErrNotConfirmed
+	}
+	return nil
+}
+
+func (p *Publisher) Close(_ context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	return p.close()
+}
+
+func (p *Publisher) connect() error {
+	p.close() //nolint:errcheck
+
+	conn, err := amqp.Dial(p.url)
+	if err != nil {
+		return fmt.Errorf("failed to connect to broker: %w", err)
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to open channel: %w", err)
+	}
+	if err := ch.Confirm(false); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+	if err := declare(ch, p.queue); err != nil {
+		conn.Close()
+		return err
+	}
+
+	p.conn = conn
+	p.ch = ch
+	p.current.Store(ch)
+	return nil
+}
+
+func (p *Publisher) close() error {
+	if p.conn == nil {
+		return nil
+	}
+	conn := p.conn
+	p.conn, p.ch = nil, nil
+	p.current.Store(nil)
+	if conn.IsClosed() {
+		return nil
+	}
+	return conn.Close()
+}
@@ -0,0 +1,33 @@
+// Package rabbit implements the queue on top of RabbitMQ (AMQP 0-9-1).
+package rabbit
+
+import (
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// attemptHeader keeps the number of the delivery attempt, as classic queues don't count them.
+const attemptHeader = "attempt"
+
+// DeadLetterQueue returns the name of the queue rejected messages of the queue are moved to.
+func DeadLetterQueue(queue string) string {
+	return queue + ".dead"
+}
+
+// declare creates the durable queue along with its dead-letter queue. Both publishers
+// and consumers declare it, so it doesn't matter which of them starts first.
+func declare(ch *amqp.Channel, queue string) error {
+	if _, err := ch.QueueDeclare(DeadLetterQueue(queue), true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare dead-letter queue: %w", err)
+	}
+
+	_, err := ch.QueueDeclare(queue, true, false, false, false, amqp.Table{
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": DeadLetterQueue(queue),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to declare queue: %w", err)
+	}
+	return nil
+}
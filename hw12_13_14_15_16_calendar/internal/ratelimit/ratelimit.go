@@ -0,0 +1,105 @@
+// Package ratelimit limits the rate of requests of every client of the API separately.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// idleTimeout is the time after which a client without requests is forgotten,
+// its bucket is full again by then for any sane limit.
+const idleTimeout = 10 * time.Minute
+
+// maxClients caps the number of clients tracked at a time, so the memory isn't exhausted
+// by requests from many addresses within idleTimeout.
+const maxClients = 100_000
+
+type client struct {
+	limiter *rate.Limiter
+	seen    time.Time
+}
+
+// Limiter keeps a token bucket for every client. It's safe for concurrent use,
+// and the limit can be changed while requests are served.
+type Limiter struct {
+	mu         sync.Mutex
+	limit      rate.Limit
+	burst      int
+	clients    map[string]*client
+	maxClients int
+	lastSweep  time.Time
+
+	now func() time.Time
+}
+
+// New creates a limiter allowing rps requests per second with bursts of the given size
+// to every client. Zero rps disables the limit.
+func New(rps float64, burst int) *Limiter {
+	l := &Limiter{maxClients: maxClients, now: time.Now}
+	l.Configure(rps, burst)
+	return l
+}
+
+// Configure changes the limit. Clients start over with full buckets.
+func (l *Limiter) Configure(rps float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = rate.Limit(rps)
+	l.burst = burst
+	l.clients = make(map[string]*client)
+}
+
+// Allow reports whether the client may make a request now. A nil limiter allows everything.
+func (l *Limiter) Allow(key string) bool {
+	if l == nil {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limit <= 0 {
+		return true
+	}
+
+	now := l.now()
+	l.sweep(now)
+
+	c, ok := l.clients[key]
+	if !ok {
+		l.evict()
+		c = &client{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[key] = c
+	}
+	c.seen = now
+	return c.limiter.AllowN(now, 1)
+}
+
+// sweep forgets idle clients, so that the memory isn't exhausted by one-off clients.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleTimeout {
+		return
+	}
+	l.lastSweep = now
+
+	for key, c := range l.clients {
+		if now.Sub(c.seen) >= idleTimeout {
+			delete(l.clients, key)
+		}
+	}
+}
+
+// evict makes room for a new client by forgetting a random one if there are too many of them.
+// The forgotten client starts over with a full bucket, which is the price of the bounded memory.
+func (l *Limiter) evict() {
+	if len(l.clients) < l.maxClients {
+		return
+	}
+	for key := range l.clients {
+		delete(l.clients, key)
+		return
+	}
+}
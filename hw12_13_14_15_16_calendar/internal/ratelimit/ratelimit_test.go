@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2024, time.March, 11, 10, 0, 0, 0, time.UTC)
+	l := New(1, 2)
+	l.now = func() time.Time { return now }
+
+	require.True(t, l.Allow("alice"))
+	require.True(t, l.Allow("alice"))
+	require.False(t, l.Allow("alice"))
+	// clients are limited separately
+	require.True(t, l.Allow("bob"))
+
+	now = now.Add(time.Second)
+	require.True(t, l.Allow("alice"))
+	require.False(t, l.Allow("alice"))
+
+	now = now.Add(idleTimeout)
+	require.True(t, l.Allow("alice"))
+	require.Len(t, l.clients, 1)
+
+	t.Run("reconfigured", func(t *testing.T) {
+		l.Configure(0, 0)
+		for i := 0; i < 10; i++ {
+			require.True(t, l.Allow("alice"))
+		}
+
+		l.Configure(1, 1)
+		require.True(t, l.Allow("alice"))
+		require.False(t, l.Allow("alice"))
+	})
+
+	t.Run("too many clients", func(t *testing.T) {
+		l := New(1, 1)
+		l.maxClients = 2
+		for _, key := range []string{"alice", "bob", "carol", "dave"} {
+			require.True(t, l.Allow(key))
+		}
+		require.Len(t, l.clients, 2)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		var l *Limiter
+		require.True(t, l.Allow("alice"))
+	})
+}
@@ -0,0 +1,458 @@
+// Package rrule implements the subset of RFC 5545 recurrence rules used by the calendar:
+// FREQ (DAILY, WEEKLY, MONTHLY), INTERVAL, BYDAY, BYMONTHDAY, COUNT and UNTIL.
+// Weeks start on Monday.
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+// maxPeriods stops rules which never produce occurrences, e.g. the 5th Monday falling on the 1st.
+const maxPeriods = 100_000
+
+type Frequency int
+
+const (
+	Daily Frequency = iota + 1
+	Weekly
+	Monthly
+)
+
+var frequencies = map[string]Frequency{
+	"DAILY":   Daily,
+	"WEEKLY":  Weekly,
+	"MONTHLY": Monthly,
+}
+
+func (f Frequency) String() string {
+	for name, freq := range frequencies {
+		if freq == f {
+			return name
+		}
+	}
+	return "UNKNOWN"
+}
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// WeekdayNum is an element of BYDAY. N selects the N-th weekday of the month counting
+// from the end if negative, zero means every such weekday.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+func (w WeekdayNum) String() string {
+	day := strings.ToUpper(w.Day.String()[:2])
+	if w.N == 0 {
+		return day
+	}
+	return strconv.Itoa(w.N) + day
+}
+
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	// Count limits the number of occurrences, zero means no limit.
+	Count int
+	// Until is the last moment an occurrence may start at, zero means no limit.
+	Until time.Time
+}
+
+// Parse parses the value of RRULE property, the "RRULE:" prefix is optional.
+func Parse(s string) (Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	r := Rule{Interval: 1}
+
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(name)
+		if !ok || value == "" {
+			return Rule{}, fmt.Errorf("%w: malformed part %q", ErrInvalidRule, part)
+		}
+		if seen[name] {
+			return Rule{}, fmt.Errorf("%w: %s is repeated", ErrInvalidRule, name)
+		}
+		seen[name] = true
+
+		if err := r.set(name, strings.ToUpper(value)); err != nil {
+			return Rule{}, fmt.Errorf("%w: %s: %w", ErrInvalidRule, name, err)
+		}
+	}
+
+	if err := r.Validate(); err != nil {
+		return Rule{}, err
+	}
+	return r, nil
+}
+
+func (r *Rule) set(name, value string) error {
+	var err error
+	switch name {
+	case "FREQ":
+		freq, ok := frequencies[value]
+		if !ok {
+			return fmt.Errorf("%q is not supported", value)
+		}
+		r.Freq = freq
+	case "INTERVAL":
+		r.Interval, err = strconv.Atoi(value)
+	case "COUNT":
+		r.Count, err = strconv.Atoi(value)
+	case "UNTIL":
+		r.Until, err = parseUntil(value)
+	case "BYDAY":
+		for _, v := range strings.Split(value, ",") {
+			day, ok := weekdays[v[max(len(v)-2, 0):]]
+			if !ok {
+				return fmt.Errorf("unknown weekday %q", v)
+			}
+			var n int
+			if prefix := strings.TrimPrefix(v[:len(v)-2], "+"); prefix != "" {
+				if n, err = strconv.Atoi(prefix); err != nil {
+					return err
+				}
+			}
+			r.ByDay = append(r.ByDay, WeekdayNum{N: n, Day: day})
+		}
+	case "BYMONTHDAY":
+		for _, v := range strings.Split(value, ",") {
+			day, err := strconv.Atoi(v)
+			if err != nil {
+				return err
+			}
+			r.ByMonthDay = append(r.ByMonthDay, day)
+		}
+	case "WKST":
+		if value != "MO" {
+			return errors.New("only MO is supported")
+		}
+	default:
+		return errors.New("is not supported")
+	}
+	return err
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	// a date means the whole day
+	t, err := time.Parse("20060102", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("malformed time %q", value)
+	}
+	return t.Add(24*time.Hour - time.Second), nil
+}
+
+func (r Rule) Validate() error {
+	var errs []error
+
+	if r.Freq == 0 {
+		errs = append(errs, errors.New("FREQ is required"))
+	}
+	if r.Interval <= 0 {
+		errs = append(errs, errors.New("INTERVAL must be positive"))
+	}
+	if r.Count < 0 {
+		errs = append(errs, errors.New("COUNT must not be negative"))
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		errs = append(errs, errors.New("COUNT and UNTIL are mutually exclusive"))
+	}
+	for _, day := range r.ByDay {
+		if day.N != 0 && r.Freq != Monthly {
+			errs = append(errs, fmt.Errorf("BYDAY %s: numbered weekdays are allowed only with MONTHLY", day))
+		}
+		if day.N < -5 || day.N > 5 {
+			errs = append(errs, fmt.Errorf("BYDAY %s: number is out of range", day))
+		}
+	}
+	for _, day := range r.ByMonthDay {
+		if day == 0 || day < -31 || day > 31 {
+			errs = append(errs, fmt.Errorf("BYMONTHDAY %d is out of range", day))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidRule, err)
+	}
+	return nil
+}
+
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq.String()}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			days = append(days, day.String())
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, 0, len(r.ByMonthDay))
+		for _, day := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(day))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Between returns the starts of occurrences within [from, to) of the series
+// whose first occurrence starts at start.
+func (r Rule) Between(start, from, to time.Time) []time.Time {
+	var result []time.Time
+	r.iterate(start, from, to, func(t time.Time) bool {
+		if !t.Before(from) {
+			result = append(result, t)
+		}
+		return true
+	})
+	return result
+}
+
+// Includes reports whether an occurrence of the series starts at t.
+func (r Rule) Includes(start, t time.Time) bool {
+	occurrences := r.Between(start, t, t.Add(time.Nanosecond))
+	return len(occurrences) == 1
+}
+
+// Last returns the start of the last occurrence, ok is false if the series is infinite.
+func (r Rule) Last(start time.Time) (last time.Time, ok bool) {
+	if r.Count == 0 && r.Until.IsZero() {
+		return time.Time{}, false
+	}
+
+	last = start
+	r.iterate(start, start, time.Time{}, func(t time.Time) bool {
+		last = t
+		return true
+	})
+	return last, true
+}
+
+// After returns the start of the first occurrence after t, ok is false if there is none.
+func (r Rule) After(start, t time.Time) (next time.Time, ok bool) {
+	r.iterate(start, t, time.Time{}, func(o time.Time) bool {
+		if o.After(t) {
+			next, ok = o, true
+		}
+		return !ok
+	})
+	return next, ok
+}
+
+// iterate calls fn for occurrences in chronological order until it returns false. Occurrences before
+// from may be skipped unless they are needed to count COUNT. Zero to means no upper bound, which is
+// allowed only for finite rules or when fn stops the iteration.
+func (r Rule) iterate(start, from, to time.Time, fn func(t time.Time) bool) {
+	base := r.periodStart(start)
+	period := 0
+	if r.Count == 0 && from.After(start) {
+		// the occurrences before from don't matter, so jump to the period containing from
+		period = max(r.periodsBetween(base, from)-1, 0)
+	}
+
+	count := 0
+	for last := period + maxPeriods; period < last; period++ {
+		begin := r.nthPeriod(base, period)
+		if !to.IsZero() && !begin.Before(to) {
+			return
+		}
+		if !r.Until.IsZero() && begin.After(r.Until) {
+			return
+		}
+
+		for _, t := range r.candidates(start, begin) {
+			switch {
+			case t.Before(start):
+				continue
+			case !r.Until.IsZero() && t.After(r.Until):
+				return
+			case !to.IsZero() && !t.Before(to):
+				return
+			}
+
+			if !fn(t) {
+				return
+			}
+			count++
+			if r.Count > 0 && count == r.Count {
+				return
+			}
+		}
+	}
+}
+
+func (r Rule) periodStart(start time.Time) time.Time {
+	y, m, d := start.Date()
+	switch r.Freq {
+	case Weekly:
+		offset := (int(start.Weekday()) - int(time.Monday) + 7) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, start.Location())
+	case Monthly:
+		return time.Date(y, m, 1, 0, 0, 0, 0, start.Location())
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, start.Location())
+	}
+}
+
+func (r Rule) nthPeriod(base time.Time, n int) time.Time {
+	switch r.Freq {
+	case Weekly:
+		return base.AddDate(0, 0, 7*n*r.Interval)
+	case Monthly:
+		return base.AddDate(0, n*r.Interval, 0)
+	default:
+		return base.AddDate(0, 0, n*r.Interval)
+	}
+}
+
+// periodsBetween estimates the number of whole periods from base to t.
+func (r Rule) periodsBetween(base, t time.Time) int {
+	switch r.Freq {
+	case Monthly:
+		months := (t.Year()-base.Year())*12 + int(t.Month()) - int(base.Month())
+		return months / r.Interval
+	case Weekly:
+		return int(t.Sub(base).Hours()/24) / 7 / r.Interval
+	default:
+		return int(t.Sub(base).Hours()/24) / r.Interval
+	}
+}
+
+// candidates returns the occurrences within the period beginning at begin, sorted.
+func (r Rule) candidates(start, begin time.Time) []time.Time {
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		days = []time.Time{begin}
+	case Weekly:
+		byDay := r.ByDay
+		if len(byDay) == 0 {
+			byDay = []WeekdayNum{{Day: start.Weekday()}}
+		}
+		for _, day := range byDay {
+			offset := (int(day.Day) - int(time.Monday) + 7) % 7
+			days = append(days, begin.AddDate(0, 0, offset))
+		}
+	case Monthly:
+		days = r.monthDays(start, begin)
+	}
+
+	result := make([]time.Time, 0, len(days))
+	for _, day := range days {
+		if !r.matches(day) {
+			continue
+		}
+		y, m, d := day.Date()
+		result = append(result, time.Date(y, m, d,
+			start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location()))
+	}
+
+	slices.SortFunc(result, func(a, b time.Time) int { return a.Compare(b) })
+	return slices.CompactFunc(result, func(a, b time.Time) bool { return a.Equal(b) })
+}
+
+func (r Rule) monthDays(start, begin time.Time) []time.Time {
+	daysInMonth := begin.AddDate(0, 1, -1).Day()
+	var days []time.Time
+
+	switch {
+	case len(r.ByMonthDay) > 0:
+		// BYDAY only limits BYMONTHDAY, see matches
+		for _, day := range r.ByMonthDay {
+			if day < 0 {
+				day = daysInMonth + day + 1
+			}
+			if day >= 1 && day <= daysInMonth {
+				days = append(days, begin.AddDate(0, 0, day-1))
+			}
+		}
+	case len(r.ByDay) > 0:
+		for d := 0; d < daysInMonth; d++ {
+			day := begin.AddDate(0, 0, d)
+			if matchesByDay(day, r.ByDay, true) {
+				days = append(days, day)
+			}
+		}
+	default:
+		if start.Day() <= daysInMonth {
+			days = append(days, begin.AddDate(0, 0, start.Day()-1))
+		}
+	}
+	return days
+}
+
+// matches applies the BYxxx parts limiting the frequency.
+func (r Rule) matches(day time.Time) bool {
+	switch r.Freq {
+	case Daily:
+		if len(r.ByDay) > 0 && !matchesByDay(day, r.ByDay, false) {
+			return false
+		}
+	case Monthly:
+		if len(r.ByMonthDay) > 0 && len(r.ByDay) > 0 && !matchesByDay(day, r.ByDay, true) {
+			return false
+		}
+	case Weekly:
+	}
+
+	if len(r.ByMonthDay) > 0 && r.Freq != Monthly {
+		daysInMonth := day.AddDate(0, 1, -day.Day()).Day()
+		if !slices.ContainsFunc(r.ByMonthDay, func(d int) bool {
+			return d == day.Day() || d == day.Day()-daysInMonth-1
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesByDay reports whether the day is one of byDay. Numbers are taken into account
+// within the month.
+func matchesByDay(day time.Time, byDay []WeekdayNum, numbered bool) bool {
+	daysInMonth := day.AddDate(0, 1, -day.Day()).Day()
+	fromStart := (day.Day()-1)/7 + 1
+	fromEnd := -((daysInMonth-day.Day())/7 + 1)
+
+	for _, w := range byDay {
+		if w.Day != day.Weekday() {
+			continue
+		}
+		if !numbered || w.N == 0 || w.N == fromStart || w.N == fromEnd {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package rrule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// start is Monday.
+var start = time.Date(2024, time.January, 1, 9, 30, 0, 0, time.UTC)
+
+func dates(times []time.Time) []string {
+	result := make([]string, 0, len(times))
+	for _, t := range times {
+		result = append(result, t.Format("2006-01-02"))
+	}
+	return result
+}
+
+func TestParse(t *testing.T) {
+	r, err := Parse("RRULE:FREQ=MONTHLY;INTERVAL=2;BYDAY=-1FR,+2MO;COUNT=5")
+	require.NoError(t, err)
+	require.Equal(t, Rule{
+		Freq:     Monthly,
+		Interval: 2,
+		ByDay:    []WeekdayNum{{N: -1, Day: time.Friday}, {N: 2, Day: time.Monday}},
+		Count:    5,
+	}, r)
+	require.Equal(t, "FREQ=MONTHLY;INTERVAL=2;BYDAY=-1FR,2MO;COUNT=5", r.String())
+
+	r, err = Parse("FREQ=DAILY;UNTIL=20240105")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, time.January, 5, 23, 59, 59, 0, time.UTC), r.Until)
+	require.Equal(t, "FREQ=DAILY;UNTIL=20240105T235959Z", r.String())
+
+	for _, s := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;COUNT=2;UNTIL=20240105",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=DAILY;BYHOUR=10",
+		"FREQ=DAILY;UNTIL=tomorrow",
+	} {
+		_, err := Parse(s)
+		require.ErrorIs(t, err, ErrInvalidRule, s)
+	}
+}
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		rule     string
+		from, to time.Time
+		expected []string
+	}{
+		{
+			rule:     "FREQ=DAILY;COUNT=3",
+			to:       start.AddDate(1, 0, 0),
+			expected: []string{"2024-01-01", "2024-01-02", "2024-01-03"},
+		},
+		{
+			rule:     "FREQ=DAILY;INTERVAL=2",
+			from:     start.AddDate(0, 0, 3),
+			to:       start.AddDate(0, 0, 9),
+			expected: []string{"2024-01-05", "2024-01-07", "2024-01-09"},
+		},
+		{
+			rule:     "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR;UNTIL=20240109T093000Z",
+			to:       start.AddDate(1, 0, 0),
+			expected: []string{"2024-01-01", "2024-01-02", "2024-01-03", "2024-01-04", "2024-01-05", "2024-01-08", "2024-01-09"},
+		},
+		{
+			rule:     "FREQ=WEEKLY",
+			to:       start.AddDate(0, 0, 15),
+			expected: []string{"2024-01-01", "2024-01-08", "2024-01-15"},
+		},
+		{
+			rule:     "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=5",
+			to:       start.AddDate(1, 0, 0),
+			expected: []string{"2024-01-01", "2024-01-04", "2024-01-15", "2024-01-18", "2024-01-29"},
+		},
+		{
+			rule:     "FREQ=MONTHLY",
+			from:     start.AddDate(0, 5, 0),
+			to:       start.AddDate(0, 8, 0),
+			expected: []string{"2024-06-01", "2024-07-01", "2024-08-01"},
+		},
+		{
+			rule:     "FREQ=MONTHLY;BYMONTHDAY=1,-1;COUNT=4",
+			to:       start.AddDate(1, 0, 0),
+			expected: []string{"2024-01-01", "2024-01-31", "2024-02-01", "2024-02-29"},
+		},
+		{
+			rule:     "FREQ=MONTHLY;BYMONTHDAY=31",
+			to:       start.AddDate(0, 5, 0),
+			expected: []string{"2024-01-31", "2024-03-31", "2024-05-31"},
+		},
+		{
+			rule:     "FREQ=MONTHLY;BYDAY=1MO,-1FR;COUNT=4",
+			to:       start.AddDate(1, 0, 0),
+			expected: []string{"2024-01-01", "2024-01-26", "2024-02-05", "2024-02-23"},
+		},
+		{
+			// Friday the 13th
+			rule:     "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13;COUNT=3",
+			to:       start.AddDate(5, 0, 0),
+			expected: []string{"2024-09-13", "2024-12-13", "2025-06-13"},
+		},
+		{
+			rule:     "FREQ=MONTHLY;BYDAY=5MO;BYMONTHDAY=1;COUNT=1",
+			to:       start.AddDate(1, 0, 0),
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.rule, func(t *testing.T) {
+			r, err := Parse(tc.rule)
+			require.NoError(t, err)
+
+			from := tc.from
+			if from.IsZero() {
+				from = start
+			}
+			occurrences := r.Between(start, from, tc.to)
+			require.Equal(t, tc.expected, dates(occurrences))
+			for _, occurrence := range occurrences {
+				require.Equal(t, "09:30", occurrence.Format("15:04"))
+			}
+		})
+	}
+}
+
+func TestIncludes(t *testing.T) {
+	r, err := Parse("FREQ=WEEKLY;BYDAY=MO,WE")
+	require.NoError(t, err)
+
+	require.True(t, r.Includes(start, start))
+	require.True(t, r.Includes(start, start.AddDate(0, 0, 2)))
+	require.True(t, r.Includes(start, start.AddDate(0, 0, 7*150+2)))
+	require.False(t, r.Includes(start, start.AddDate(0, 0, 1)))
+	require.False(t, r.Includes(start, start.AddDate(0, 0, 2).Add(time.Minute)))
+	require.False(t, r.Includes(start, start.AddDate(0, 0, -7)))
+}
+
+func TestLast(t *testing.T) {
+	r, err := Parse("FREQ=WEEKLY;COUNT=3")
+	require.NoError(t, err)
+	last, ok := r.Last(start)
+	require.True(t, ok)
+	require.Equal(t, start.AddDate(0, 0, 14), last)
+
+	r, err = Parse("FREQ=DAILY;UNTIL=20240110T000000Z")
+	require.NoError(t, err)
+	last, ok = r.Last(start)
+	require.True(t, ok)
+	require.Equal(t, start.AddDate(0, 0, 8), last)
+
+	r, err = Parse("FREQ=DAILY")
+	require.NoError(t, err)
+	_, ok = r.Last(start)
+	require.False(t, ok)
+}
+
+func TestAfter(t *testing.T) {
+	r, err := Parse("FREQ=WEEKLY;BYDAY=MO,WE")
+	require.NoError(t, err)
+	next, ok := r.After(start, start)
+	require.True(t, ok)
+	require.Equal(t, start.AddDate(0, 0, 2), next)
+
+	next, ok = r.After(start, start.AddDate(1, 0, 0))
+	require.True(t, ok)
+	require.True(t, next.After(start.AddDate(1, 0, 0)))
+	require.True(t, r.Includes(start, next))
+
+	next, ok = r.After(start, start.Add(-time.Minute))
+	require.True(t, ok)
+	require.Equal(t, start, next)
+
+	r, err = Parse("FREQ=DAILY;COUNT=3")
+	require.NoError(t, err)
+	_, ok = r.After(start, start.AddDate(0, 0, 2))
+	require.False(t, ok)
+}
@@ -0,0 +1,17 @@
+package scheduler
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	notificationsPublished = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "calendar_notifications_published_total",
+		Help: "Number of notifications put to the queue.",
+	})
+	publishFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "calendar_notification_publish_failures_total",
+		Help: "Number of failed attempts to put a notification to the queue.",
+	})
+)
@@ -0,0 +1,88 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+)
+
+type RetentionStorage interface {
+	DeleteEventsBefore(ctx context.Context, before time.Time, limit int) (int, error)
+	CountEventsBefore(ctx context.Context, before time.Time) (int, error)
+}
+
+// Retention periodically deletes events which ended more than age ago. Their audit logs are deleted
+// along with them rather than getting a deletion recorded, and so are the logs of the events
+// deleted more than age ago, so nothing outlives the retention.
+type Retention struct {
+	logger    Logger
+	storage   RetentionStorage
+	age       time.Duration
+	interval  time.Duration
+	batchSize int
+	dryRun    bool
+
+	now func() time.Time
+}
+
+// NewRetention creates the job deleting events in batches of batchSize, so a single
+// statement never locks too many rows. In dry-run mode it only reports how many events
+// would be deleted.
+func NewRetention(
+	logger Logger, storage RetentionStorage, age, interval time.Duration, batchSize int, dryRun bool,
+) *Retention {
+	return &Retention{
+		logger:    logger,
+		storage:   storage,
+		age:       age,
+		interval:  interval,
+		batchSize: batchSize,
+		dryRun:    dryRun,
+		now:       time.Now,
+	}
+}
+
+// Run purges old events right away and then every interval until ctx is done.
+func (r *Retention) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Retention) purge(ctx context.Context) {
+	before := r.now().Add(-r.age)
+
+	if r.dryRun {
+		count, err := r.storage.CountEventsBefore(ctx, before)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "failed to count old events", "error", err)
+			return
+		}
+		r.logger.InfoContext(ctx, "old events would be deleted", "count", count, "before", before)
+		return
+	}
+
+	var total int
+	for ctx.Err() == nil {
+		deleted, err := r.storage.DeleteEventsBefore(ctx, before, r.batchSize)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "failed to delete old events", "error", err, "deleted", total)
+			return
+		}
+		total += deleted
+		if deleted < r.batchSize {
+			break
+		}
+		r.logger.DebugContext(ctx, "batch of old events deleted", "count", deleted)
+	}
+
+	r.logger.InfoContext(ctx, "old events deleted", "count", total, "before", before)
+}
@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+type countingStorage struct {
+	*memorystorage.Storage
+	batches []int
+}
+
+func (s *countingStorage) DeleteEventsBefore(ctx context.Context, before time.Time, limit int) (int, error) {
+	deleted, err := s.Storage.DeleteEventsBefore(ctx, before, limit)
+	s.batches = append(s.batches, deleted)
+	return deleted, err
+}
+
+func TestRetention(t *testing.T) {
+	ctx := context.Background()
+	year := 365 * 24 * time.Hour
+
+	newStorage := func(t *testing.T) *countingStorage {
+		t.Helper()
+		s := &countingStorage{Storage: memorystorage.New()}
+		for i, startAt := range []time.Time{
+			now.Add(-year - 5*time.Hour),
+			now.Add(-year - 4*time.Hour),
+			now.Add(-year - 3*time.Hour),
+			now.Add(-year),
+			now,
+		} {
+			event := storage.Event{
+				ID: string(rune('a' + i)), Title: "event", StartAt: startAt, Duration: time.Hour, UserID: "user",
+			}
+			require.NoError(t, s.CreateEvent(ctx, event, storage.Audit{}))
+		}
+		return s
+	}
+
+	t.Run("batches", func(t *testing.T) {
+		s := newStorage(t)
+		retention := NewRetention(nopLogger{}, s, year, time.Hour, 2, false)
+		retention.now = func() time.Time { return now }
+
+		retention.purge(ctx)
+		require.Equal(t, []int{2, 1}, s.batches)
+
+		count, err := s.CountEventsBefore(ctx, now.Add(year))
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		s := newStorage(t)
+		retention := NewRetention(nopLogger{}, s, year, time.Hour, 2, true)
+		retention.now = func() time.Time { return now }
+
+		retention.purge(ctx)
+		require.Empty(t, s.batches)
+
+		count, err := s.CountEventsBefore(ctx, now.Add(year))
+		require.NoError(t, err)
+		require.Equal(t, 5, count)
+	})
+}
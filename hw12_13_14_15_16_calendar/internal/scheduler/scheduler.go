@@ -0,0 +1,167 @@
+// Package scheduler periodically looks for reminders about events which are due
+// and publishes notifications about them to the queue.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/notification"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/queue"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+)
+
+type Logger interface {
+	DebugContext(ctx context.Context, msg string, args ...any)
+	InfoContext(ctx context.Context, msg string, args ...any)
+	ErrorContext(ctx context.Context, msg string, args ...any)
+}
+
+type Storage interface {
+	ListDueReminders(ctx context.Context, now time.Time) ([]storage.DueReminder, error)
+	MarkReminderFired(ctx context.Context, due storage.DueReminder) error
+}
+
+type Scheduler struct {
+	logger    Logger
+	storage   Storage
+	publisher queue.Publisher
+	interval  time.Duration
+
+	// published holds the recipients already notified about the due reminders not marked as fired yet,
+	// so a retry after a failure notifies only the rest of them.
+	published map[dueKey]map[string]struct{}
+
+	now func() time.Time
+}
+
+// dueKey identifies a due reminder of an event or of an occurrence of a series.
+type dueKey struct {
+	eventID  string
+	reminder int
+	startAt  int64
+}
+
+func keyOf(due storage.DueReminder) dueKey {
+	return dueKey{eventID: due.Event.ID, reminder: due.Index, startAt: due.Event.StartAt.UnixNano()}
+}
+
+func New(logger Logger, storage Storage, publisher queue.Publisher, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		logger:    logger,
+		storage:   storage,
+		publisher: publisher,
+		interval:  interval,
+		published: make(map[dueKey]map[string]struct{}),
+		now:       time.Now,
+	}
+}
+
+// Run scans the storage right away and then every interval until ctx is done.
+// A failed scan is logged and retried on the next tick.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.scan(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan publishes notifications about due reminders. A reminder is marked as fired only after
+// its notifications are accepted by the queue, so a failure leads to a retry rather than a loss.
+// The retry skips the recipients notified before the failure, the price being a possible duplicate
+// only if the scheduler restarts in between. Occurrences of a series are marked by their start,
+// so once a reminder of one of them fails the same reminder of the later ones waits for the next scan too.
+func (s *Scheduler) scan(ctx context.Context) {
+	reminders, err := s.storage.ListDueReminders(ctx, s.now())
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to list due reminders", "error", err)
+		return
+	}
+
+	type key struct {
+		eventID  string
+		reminder int
+	}
+
+	// the recipients of the reminders which aren't due anymore, e.g. of deleted events, are forgotten
+	published := make(map[dueKey]map[string]struct{})
+	for _, due := range reminders {
+		if recipients, ok := s.published[keyOf(due)]; ok {
+			published[keyOf(due)] = recipients
+		}
+	}
+	s.published = published
+
+	var sent int
+	failed := make(map[key]struct{})
+	for _, due := range reminders {
+		if ctx.Err() != nil {
+			return
+		}
+		k := key{eventID: due.Event.ID, reminder: due.Index}
+		if _, ok := failed[k]; ok {
+			continue
+		}
+
+		recipients, ok := s.published[keyOf(due)]
+		if !ok {
+			recipients = make(map[string]struct{})
+			s.published[keyOf(due)] = recipients
+		}
+		if err := s.publish(ctx, due, recipients); err != nil {
+			s.logger.ErrorContext(ctx, "failed to publish notification", "event_id", due.Event.ID, "error", err)
+			publishFailures.Inc()
+			failed[k] = struct{}{}
+			continue
+		}
+		if err := s.storage.MarkReminderFired(ctx, due); err != nil {
+			s.logger.ErrorContext(ctx, "failed to mark reminder fired", "event_id", due.Event.ID, "error", err)
+			failed[k] = struct{}{}
+			continue
+		}
+		delete(s.published, keyOf(due))
+
+		s.logger.DebugContext(ctx, "notification published",
+			"event_id", due.Event.ID, "user_id", due.Event.UserID, "channel", due.Reminder().Channel)
+		sent++
+	}
+
+	if sent > 0 {
+		s.logger.InfoContext(ctx, "notifications published", "count", sent)
+	}
+}
+
+// publish puts a notification of the reminder to the queue for every user to be notified:
+// the owner and the attendees who have accepted the invitation, except the ones in published.
+// Those the notification is published for are added there. The notification is meant
+// only for the channel of the reminder, if it has one.
+func (s *Scheduler) publish(ctx context.Context, due storage.DueReminder, published map[string]struct{}) error {
+	for _, userID := range due.Event.Recipients() {
+		if _, ok := published[userID]; ok {
+			continue
+		}
+		n := notification.FromEvent(due.Event)
+		n.UserID = userID
+		n.Channel = due.Reminder().Channel
+		n.PublishedAt = s.now()
+		body, err := n.Marshal()
+		if err != nil {
+			return fmt.Errorf("failed to encode notification: %w", err)
+		}
+		if err := s.publisher.Publish(ctx, body); err != nil {
+			return fmt.Errorf("user %s: %w", userID, err)
+		}
+		published[userID] = struct{}{}
+		notificationsPublished.Inc()
+	}
+	return nil
+}
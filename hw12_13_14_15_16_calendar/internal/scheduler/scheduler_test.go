@@ -0,0 +1,248 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/notification"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) DebugContext(context.Context, string, ...any) {}
+func (nopLogger) InfoContext(context.Context, string, ...any)  {}
+func (nopLogger) ErrorContext(context.Context, string, ...any) {}
+
+type fakePublisher struct {
+	mu       sync.Mutex
+	messages []notification.Notification
+	err      error
+	// failures is the number of calls to fail before publishing succeeds.
+	failures int
+	// failAfter is the number of calls to succeed before the next one fails, zero means none.
+	failAfter int
+}
+
+func (p *fakePublisher) Publish(_ context.Context, body []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("publish failed")
+	}
+	if p.failAfter > 0 {
+		p.failAfter--
+		if p.failAfter == 0 {
+			p.failures = 1
+		}
+	}
+	n, err := notification.Unmarshal(body)
+	if err != nil {
+		return err
+	}
+	p.messages = append(p.messages, n)
+	return nil
+}
+
+func (p *fakePublisher) eventIDs() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ids := make([]string, 0, len(p.messages))
+	for _, n := range p.messages {
+		ids = append(ids, n.EventID)
+	}
+	return ids
+}
+
+var now = time.Date(2024, time.March, 11, 10, 0, 0, 0, time.UTC)
+
+func newEvent(id string, startAt time.Time, notifyBefore time.Duration) storage.Event {
+	return storage.Event{
+		ID:           id,
+		Title:        "event " + id,
+		StartAt:      startAt,
+		Duration:     time.Hour,
+		UserID:       "user",
+		NotifyBefore: notifyBefore,
+	}
+}
+
+func newScheduler(t *testing.T, publisher *fakePublisher, events ...storage.Event) *Scheduler {
+	t.Helper()
+
+	s := memorystorage.New()
+	for _, event := range events {
+		require.NoError(t, s.CreateEvent(context.Background(), event, storage.Audit{}))
+	}
+
+	scheduler := New(nopLogger{}, s, publisher, time.Minute)
+	scheduler.now = func() time.Time { return now }
+	return scheduler
+}
+
+func TestScan(t *testing.T) {
+	ctx := context.Background()
+	publisher := &fakePublisher{}
+	scheduler := newScheduler(t, publisher,
+		newEvent("due", now.Add(time.Hour), 2*time.Hour),
+		newEvent("later", now.Add(3*time.Hour), time.Hour),
+		newEvent("silent", now.Add(5*time.Hour), 0),
+	)
+
+	scheduler.scan(ctx)
+	require.Equal(t, []notification.Notification{{
+		EventID:     "due",
+		Title:       "event due",
+		Date:        now.Add(time.Hour),
+		UserID:      "user",
+		PublishedAt: now,
+	}}, publisher.messages)
+
+	// already notified events are skipped
+	scheduler.scan(ctx)
+	require.Equal(t, []string{"due"}, publisher.eventIDs())
+
+	scheduler.now = func() time.Time { return now.Add(2 * time.Hour) }
+	scheduler.scan(ctx)
+	require.Equal(t, []string{"due", "later"}, publisher.eventIDs())
+}
+
+func TestScanRetriesFailedPublish(t *testing.T) {
+	ctx := context.Background()
+	publisher := &fakePublisher{err: errors.New("broker is down")}
+	scheduler := newScheduler(t, publisher, newEvent("due", now.Add(time.Hour), 2*time.Hour))
+
+	scheduler.scan(ctx)
+	require.Empty(t, publisher.eventIDs())
+
+	publisher.err = nil
+	scheduler.scan(ctx)
+	require.Equal(t, []string{"due"}, publisher.eventIDs())
+}
+
+func TestScanAttendees(t *testing.T) {
+	ctx := context.Background()
+	event := newEvent("due", now.Add(time.Hour), 2*time.Hour)
+	event.Attendees = []storage.Attendee{
+		{UserID: "alice", Status: storage.StatusAccepted},
+		{UserID: "bob", Status: storage.StatusDeclined},
+		{UserID: "carol", Status: storage.StatusTentative},
+	}
+	publisher := &fakePublisher{}
+	scheduler := newScheduler(t, publisher, event)
+
+	scheduler.scan(ctx)
+	users := make([]string, 0, len(publisher.messages))
+	for _, n := range publisher.messages {
+		require.Equal(t, "due", n.EventID)
+		users = append(users, n.UserID)
+	}
+	require.Equal(t, []string{"user", "alice"}, users)
+}
+
+func TestScanRetriesFailedRecipients(t *testing.T) {
+	ctx := context.Background()
+	event := newEvent("due", now.Add(time.Hour), 2*time.Hour)
+	event.Attendees = []storage.Attendee{
+		{UserID: "alice", Status: storage.StatusAccepted},
+		{UserID: "bob", Status: storage.StatusAccepted},
+	}
+	publisher := &fakePublisher{failAfter: 1}
+	scheduler := newScheduler(t, publisher, event)
+
+	// the owner is notified, alice isn't and bob waits for the next scan
+	scheduler.scan(ctx)
+	require.Len(t, publisher.messages, 1)
+
+	scheduler.scan(ctx)
+	users := make([]string, 0, len(publisher.messages))
+	for _, n := range publisher.messages {
+		users = append(users, n.UserID)
+	}
+	require.Equal(t, []string{"user", "alice", "bob"}, users)
+
+	scheduler.scan(ctx)
+	require.Len(t, publisher.messages, 3)
+	require.Empty(t, scheduler.published)
+}
+
+func TestScanSeries(t *testing.T) {
+	ctx := context.Background()
+	series := newEvent("series", now.Add(time.Hour), 48*time.Hour)
+	series.RRule = "FREQ=DAILY"
+	publisher := &fakePublisher{failures: 1}
+	scheduler := newScheduler(t, publisher, series)
+
+	// the second occurrence isn't marked while the first one fails
+	scheduler.scan(ctx)
+	require.Empty(t, publisher.messages)
+
+	scheduler.scan(ctx)
+	require.Len(t, publisher.messages, 2)
+	require.True(t, now.Add(time.Hour).Equal(publisher.messages[0].Date))
+	require.True(t, now.Add(25*time.Hour).Equal(publisher.messages[1].Date))
+
+	scheduler.scan(ctx)
+	require.Len(t, publisher.messages, 2)
+
+	scheduler.now = func() time.Time { return now.Add(24 * time.Hour) }
+	scheduler.scan(ctx)
+	require.Equal(t, []string{"series", "series", "series"}, publisher.eventIDs())
+}
+
+func TestScanReminders(t *testing.T) {
+	ctx := context.Background()
+	event := newEvent("due", now.Add(time.Hour), 0)
+	event.Reminders = []storage.Reminder{
+		{Before: 24 * time.Hour, Channel: notification.ChannelWebhook},
+		{Before: 10 * time.Minute, Channel: notification.ChannelLog},
+	}
+	publisher := &fakePublisher{}
+	scheduler := newScheduler(t, publisher, event)
+
+	scheduler.scan(ctx)
+	scheduler.scan(ctx)
+	require.Len(t, publisher.messages, 1)
+	require.Equal(t, notification.ChannelWebhook, publisher.messages[0].Channel)
+
+	scheduler.now = func() time.Time { return now.Add(55 * time.Minute) }
+	scheduler.scan(ctx)
+	require.Len(t, publisher.messages, 2)
+	require.Equal(t, notification.ChannelLog, publisher.messages[1].Channel)
+}
+
+func TestRun(t *testing.T) {
+	publisher := &fakePublisher{}
+	scheduler := newScheduler(t, publisher, newEvent("due", now.Add(time.Hour), 2*time.Hour))
+	scheduler.interval = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scheduler.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(publisher.eventIDs()) == 1
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler didn't stop")
+	}
+	require.Equal(t, []string{"due"}, publisher.eventIDs())
+}
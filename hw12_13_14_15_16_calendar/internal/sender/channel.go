@@ -0,0 +1,83 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/notification"
+)
+
+const (
+	ChannelLog    = notification.ChannelLog
+	ChannelStdout = notification.ChannelStdout
+)
+
+var ErrUnknownChannel = errors.New("unknown channel")
+
+// Channel delivers notifications to users in some way.
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, n notification.Notification) error
+}
+
+// LogChannel writes notifications to the log.
+type LogChannel struct {
+	logger Logger
+}
+
+func NewLogChannel(logger Logger) *LogChannel {
+	return &LogChannel{logger: logger}
+}
+
+func (c *LogChannel) Name() string {
+	return ChannelLog
+}
+
+func (c *LogChannel) Send(ctx context.Context, n notification.Notification) error {
+	c.logger.InfoContext(ctx, "notification",
+		"event_id", n.EventID, "title", n.Title, "date", n.Date, "user_id", n.UserID)
+	return nil
+}
+
+// WriterChannel prints notifications as lines of text, e.g. to STDOUT.
+type WriterChannel struct {
+	out io.Writer
+}
+
+func NewWriterChannel(out io.Writer) *WriterChannel {
+	return &WriterChannel{out: out}
+}
+
+func (c *WriterChannel) Name() string {
+	return ChannelStdout
+}
+
+func (c *WriterChannel) Send(_ context.Context, n notification.Notification) error {
+	_, err := fmt.Fprintf(c.out, "%s: %q starts at %s (event %s)\n",
+		n.UserID, n.Title, n.Date.Format(time.RFC3339), n.EventID)
+	return err
+}
+
+// NewChannels creates channels by their names. webhook is needed only for the webhook channel.
+func NewChannels(names []string, logger Logger, stdout io.Writer, webhook *WebhookChannel) ([]Channel, error) {
+	channels := make([]Channel, 0, len(names))
+	for _, name := range names {
+		switch name {
+		case ChannelLog:
+			channels = append(channels, NewLogChannel(logger))
+		case ChannelStdout:
+			channels = append(channels, NewWriterChannel(stdout))
+		case ChannelWebhook:
+			if webhook == nil {
+				return nil, fmt.Errorf("%s channel is not configured", ChannelWebhook)
+			}
+			channels = append(channels, webhook)
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrUnknownChannel, name)
+		}
+	}
+	return channels, nil
+}
@@ -0,0 +1,23 @@
+package sender
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+var (
+	notificationsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "calendar_notifications_sent_total",
+		Help: "Number of attempts to send a notification through a channel by their result.",
+	}, []string{"channel", "result"})
+	queueLag = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "calendar_notification_queue_lag_seconds",
+		Help:    "Time notifications spend in the queue from publishing by the scheduler till receiving.",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 3600},
+	})
+)
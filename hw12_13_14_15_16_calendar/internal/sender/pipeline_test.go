@@ -0,0 +1,91 @@
+package sender_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/notification"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/queue/local"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/scheduler"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/sender"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) DebugContext(context.Context, string, ...any) {}
+func (nopLogger) InfoContext(context.Context, string, ...any)  {}
+func (nopLogger) WarnContext(context.Context, string, ...any)  {}
+func (nopLogger) ErrorContext(context.Context, string, ...any) {}
+
+type recorder struct {
+	mu   sync.Mutex
+	sent []notification.Notification
+}
+
+func (r *recorder) Name() string { return "recorder" }
+
+func (r *recorder) Send(_ context.Context, n notification.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, n)
+	return nil
+}
+
+func (r *recorder) eventIDs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]string, 0, len(r.sent))
+	for _, n := range r.sent {
+		ids = append(ids, n.EventID)
+	}
+	return ids
+}
+
+// TestPipeline runs the scheduler and the sender connected by the in-process queue.
+func TestPipeline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := memorystorage.New()
+	startAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	for _, event := range []storage.Event{
+		{ID: "due", Title: "due", StartAt: startAt, Duration: time.Hour, UserID: "user", NotifyBefore: 2 * time.Hour},
+		{ID: "later", Title: "later", StartAt: startAt.Add(24 * time.Hour), Duration: time.Hour, UserID: "user"},
+	} {
+		require.NoError(t, s.CreateEvent(ctx, event, storage.Audit{}))
+	}
+
+	q, err := local.New("", 1)
+	require.NoError(t, err)
+	channel := &recorder{}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		scheduler.New(nopLogger{}, s, q, 10*time.Millisecond).Run(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		sender.New(nopLogger{}, q, []sender.Channel{channel}, 3).Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(channel.eventIDs()) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// give the scheduler a few more scans to make sure nothing is sent twice
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	require.Equal(t, []string{"due"}, channel.eventIDs())
+	require.Equal(t, "user", channel.sent[0].UserID)
+	require.True(t, startAt.Equal(channel.sent[0].Date))
+}
@@ -0,0 +1,151 @@
+// Package sender consumes notifications from the queue and delivers them through channels.
+package sender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/notification"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/queue"
+)
+
+const reconnectDelay = 5 * time.Second
+
+var ErrNotConsuming = errors.New("not connected to the queue")
+
+type Logger interface {
+	InfoContext(ctx context.Context, msg string, args ...any)
+	WarnContext(ctx context.Context, msg string, args ...any)
+	ErrorContext(ctx context.Context, msg string, args ...any)
+}
+
+type Sender struct {
+	logger      Logger
+	consumer    queue.Consumer
+	channels    []Channel
+	maxAttempts int
+
+	reconnectDelay time.Duration
+	consuming      atomic.Bool
+}
+
+// New creates a sender delivering every notification to all the channels, or to the one
+// the notification names. A notification which fails maxAttempts times is moved to the dead-letter queue.
+func New(logger Logger, consumer queue.Consumer, channels []Channel, maxAttempts int) *Sender {
+	return &Sender{
+		logger:         logger,
+		consumer:       consumer,
+		channels:       channels,
+		maxAttempts:    maxAttempts,
+		reconnectDelay: reconnectDelay,
+	}
+}
+
+// Run processes notifications until ctx is done. A lost connection to the queue is
+// re-established after a delay.
+func (s *Sender) Run(ctx context.Context) {
+	for {
+		deliveries, err := s.consumer.Consume(ctx)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to consume notifications", "error", err)
+		} else {
+			s.consuming.Store(true)
+			for d := range deliveries {
+				s.handle(ctx, d)
+			}
+			s.consuming.Store(false)
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+		s.logger.WarnContext(ctx, "queue connection is lost, reconnecting", "delay", s.reconnectDelay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.reconnectDelay):
+		}
+	}
+}
+
+// Ready reports whether the sender is receiving notifications from the queue.
+func (s *Sender) Ready(_ context.Context) error {
+	if !s.consuming.Load() {
+		return ErrNotConsuming
+	}
+	return nil
+}
+
+// handle acknowledges the notification only after every channel it's meant for has delivered it.
+// Note that a retry sends the notification to all the channels again.
+func (s *Sender) handle(ctx context.Context, d queue.Delivery) {
+	n, err := notification.Unmarshal(d.Body())
+	if err != nil {
+		// a malformed message will never succeed
+		s.logger.ErrorContext(ctx, "dropping malformed notification", "error", err)
+		s.settle(ctx, d.Nack(false))
+		return
+	}
+	if n.Channel != "" && !s.hasChannel(n.Channel) {
+		// the channel is disabled in this sender, so the notification would never be delivered
+		s.logger.ErrorContext(ctx, "dropping notification for a disabled channel",
+			"event_id", n.EventID, "channel", n.Channel)
+		s.settle(ctx, d.Nack(false))
+		return
+	}
+	// redelivered notifications would add the time of the previous attempts
+	if !n.PublishedAt.IsZero() && d.Attempt() == 1 {
+		queueLag.Observe(time.Since(n.PublishedAt).Seconds())
+	}
+
+	if err := s.send(ctx, n); err != nil {
+		if d.Attempt() >= s.maxAttempts {
+			s.logger.ErrorContext(ctx, "giving up on notification",
+				"event_id", n.EventID, "attempt", d.Attempt(), "error", err)
+			s.settle(ctx, d.Nack(false))
+			return
+		}
+
+		s.logger.WarnContext(ctx, "failed to send notification, will retry",
+			"event_id", n.EventID, "attempt", d.Attempt(), "error", err)
+		s.settle(ctx, d.Nack(true))
+		return
+	}
+
+	s.settle(ctx, d.Ack())
+}
+
+func (s *Sender) send(ctx context.Context, n notification.Notification) error {
+	var errs []error
+	for _, channel := range s.channels {
+		if n.Channel != "" && channel.Name() != n.Channel {
+			continue
+		}
+		if err := channel.Send(ctx, n); err != nil {
+			notificationsSent.WithLabelValues(channel.Name(), resultFailure).Inc()
+			errs = append(errs, fmt.Errorf("%s: %w", channel.Name(), err))
+			continue
+		}
+		notificationsSent.WithLabelValues(channel.Name(), resultSuccess).Inc()
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Sender) hasChannel(name string) bool {
+	for _, channel := range s.channels {
+		if channel.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Sender) settle(ctx context.Context, err error) {
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to settle notification", "error", err)
+	}
+}
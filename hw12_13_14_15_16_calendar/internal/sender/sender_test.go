@@ -0,0 +1,200 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/notification"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/queue"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) InfoContext(context.Context, string, ...any)  {}
+func (nopLogger) WarnContext(context.Context, string, ...any)  {}
+func (nopLogger) ErrorContext(context.Context, string, ...any) {}
+
+type fakeDelivery struct {
+	body    []byte
+	attempt int
+	result  string
+}
+
+func (d *fakeDelivery) Body() []byte { return d.body }
+func (d *fakeDelivery) Attempt() int { return d.attempt }
+func (d *fakeDelivery) Ack() error   { d.result = "ack"; return nil }
+
+func (d *fakeDelivery) Nack(requeue bool) error {
+	d.result = "dead"
+	if requeue {
+		d.result = "retry"
+	}
+	return nil
+}
+
+type fakeConsumer struct {
+	mu       sync.Mutex
+	batches  [][]queue.Delivery
+	consumed int
+}
+
+// Consume returns the next batch of deliveries and closes the channel as if the connection was lost.
+func (c *fakeConsumer) Consume(ctx context.Context) (<-chan queue.Delivery, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.consumed == len(c.batches) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	deliveries := make(chan queue.Delivery, len(c.batches[c.consumed]))
+	for _, d := range c.batches[c.consumed] {
+		deliveries <- d
+	}
+	close(deliveries)
+	c.consumed++
+	return deliveries, nil
+}
+
+type fakeChannel struct {
+	name string
+	sent []notification.Notification
+	err  error
+}
+
+func (c *fakeChannel) Name() string {
+	if c.name == "" {
+		return "fake"
+	}
+	return c.name
+}
+
+func (c *fakeChannel) Send(_ context.Context, n notification.Notification) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.sent = append(c.sent, n)
+	return nil
+}
+
+var n = notification.Notification{
+	EventID:     "1",
+	Title:       "meeting",
+	Date:        time.Date(2024, time.March, 11, 10, 0, 0, 0, time.UTC),
+	UserID:      "user",
+	PublishedAt: time.Date(2024, time.March, 11, 9, 0, 0, 0, time.UTC),
+}
+
+func newDelivery(t *testing.T, attempt int) *fakeDelivery {
+	t.Helper()
+	body, err := n.Marshal()
+	require.NoError(t, err)
+	return &fakeDelivery{body: body, attempt: attempt}
+}
+
+func TestHandle(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("delivered", func(t *testing.T) {
+		channel := &fakeChannel{}
+		sent := testutil.ToFloat64(notificationsSent.WithLabelValues("fake", resultSuccess))
+		d := newDelivery(t, 1)
+		New(nopLogger{}, nil, []Channel{channel}, 3).handle(ctx, d)
+		require.Equal(t, "ack", d.result)
+		require.Equal(t, []notification.Notification{n}, channel.sent)
+		require.Equal(t, sent+1, testutil.ToFloat64(notificationsSent.WithLabelValues("fake", resultSuccess)))
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		channel := &fakeChannel{err: errors.New("unavailable")}
+		s := New(nopLogger{}, nil, []Channel{&fakeChannel{}, channel}, 3)
+
+		d := newDelivery(t, 2)
+		s.handle(ctx, d)
+		require.Equal(t, "retry", d.result)
+
+		d = newDelivery(t, 3)
+		s.handle(ctx, d)
+		require.Equal(t, "dead", d.result)
+	})
+
+	t.Run("channel", func(t *testing.T) {
+		logChannel, webhookChannel := &fakeChannel{name: ChannelLog}, &fakeChannel{name: ChannelWebhook}
+		s := New(nopLogger{}, nil, []Channel{logChannel, webhookChannel}, 3)
+
+		targeted := n
+		targeted.Channel = ChannelWebhook
+		body, err := targeted.Marshal()
+		require.NoError(t, err)
+		d := &fakeDelivery{body: body, attempt: 1}
+		s.handle(ctx, d)
+		require.Equal(t, "ack", d.result)
+		require.Empty(t, logChannel.sent)
+		require.Equal(t, []notification.Notification{targeted}, webhookChannel.sent)
+
+		// none of the channels of the sender can deliver it
+		targeted.Channel = ChannelStdout
+		body, err = targeted.Marshal()
+		require.NoError(t, err)
+		d = &fakeDelivery{body: body, attempt: 1}
+		s.handle(ctx, d)
+		require.Equal(t, "dead", d.result)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		d := &fakeDelivery{body: []byte("{"), attempt: 1}
+		New(nopLogger{}, nil, []Channel{&fakeChannel{}}, 3).handle(ctx, d)
+		require.Equal(t, "dead", d.result)
+	})
+}
+
+func TestRunReconnects(t *testing.T) {
+	first, second := newDelivery(t, 1), newDelivery(t, 1)
+	consumer := &fakeConsumer{batches: [][]queue.Delivery{{first}, {second}}}
+	channel := &fakeChannel{}
+	s := New(nopLogger{}, consumer, []Channel{channel}, 3)
+	s.reconnectDelay = time.Millisecond
+	require.ErrorIs(t, s.Ready(context.Background()), ErrNotConsuming)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		consumer.mu.Lock()
+		defer consumer.mu.Unlock()
+		return consumer.consumed == 2
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	require.Equal(t, "ack", first.result)
+	require.Equal(t, "ack", second.result)
+	require.ErrorIs(t, s.Ready(context.Background()), ErrNotConsuming)
+}
+
+func TestNewChannels(t *testing.T) {
+	var out bytes.Buffer
+	channels, err := NewChannels([]string{ChannelLog, ChannelStdout}, nopLogger{}, &out, nil)
+	require.NoError(t, err)
+	require.Len(t, channels, 2)
+
+	require.NoError(t, channels[1].Send(context.Background(), n))
+	require.Equal(t, "user: \"meeting\" starts at 2024-03-11T10:00:00Z (event 1)\n", out.String())
+
+	_, err = NewChannels([]string{"pigeon"}, nopLogger{}, &out, nil)
+	require.ErrorIs(t, err, ErrUnknownChannel)
+
+	_, err = NewChannels([]string{ChannelWebhook}, nopLogger{}, &out, nil)
+	require.Error(t, err)
+}
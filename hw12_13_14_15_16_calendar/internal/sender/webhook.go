@@ -0,0 +1,241 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/netguard"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/notification"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+	"github.com/google/uuid"
+)
+
+const (
+	ChannelWebhook = notification.ChannelWebhook
+
+	// SignatureHeader contains "sha256=" followed by the hex encoded HMAC-SHA256 of the timestamp
+	// from TimestampHeader, a dot and the body, the key being the secret of the webhook.
+	SignatureHeader = "X-Calendar-Signature"
+	// TimestampHeader contains the Unix time the payload was signed at, so that receivers
+	// can reject replayed requests.
+	TimestampHeader = "X-Calendar-Timestamp"
+	// DeliveryHeader identifies the notification, it's the same for all the attempts to deliver it,
+	// so that receivers can skip duplicates.
+	DeliveryHeader = "X-Calendar-Delivery"
+
+	maxResponseSize = 64 << 10
+)
+
+// WebhookStorage provides webhooks of users and records attempts to deliver notifications to them.
+type WebhookStorage interface {
+	ListWebhooks(ctx context.Context, userID string) ([]storage.Webhook, error)
+	AddDelivery(ctx context.Context, delivery storage.Delivery) error
+}
+
+type WebhookOptions struct {
+	// Timeout limits a single request.
+	Timeout time.Duration
+	// MaxRetries is the number of retries after the first failed attempt.
+	MaxRetries int
+	// The delay before the n-th retry is MinBackoff*2^(n-1) limited by MaxBackoff,
+	// a random half of which is added as jitter.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// AllowPrivateAddresses lets webhooks reach loopback, private and link-local addresses,
+	// which are refused otherwise, so users can't make the sender call internal services.
+	AllowPrivateAddresses bool
+}
+
+// WebhookChannel posts notifications as JSON to the webhooks of their users. Network errors,
+// 5xx and 429 responses are retried, other responses except 2xx and refused addresses
+// fail the delivery at once.
+// Every attempt is recorded in the storage.
+type WebhookChannel struct {
+	logger  Logger
+	storage WebhookStorage
+	client  *http.Client
+	options WebhookOptions
+}
+
+func NewWebhookChannel(logger Logger, storage WebhookStorage, options WebhookOptions) *WebhookChannel {
+	return &WebhookChannel{
+		logger:  logger,
+		storage: storage,
+		client: &http.Client{
+			Timeout:   options.Timeout,
+			Transport: webhookTransport(options.AllowPrivateAddresses),
+			// a redirected POST turns into GET, so redirects are treated as failures
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		options: options,
+	}
+}
+
+// webhookTransport checks the address of every connection, as the name of a webhook
+// may resolve to another address than it did when the webhook was registered.
+// Proxies are bypassed, as the check would apply to the proxy otherwise.
+func webhookTransport(allowPrivate bool) http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if allowPrivate {
+		return transport
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: netguard.Control}
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+	return transport
+}
+
+func (c *WebhookChannel) Name() string {
+	return ChannelWebhook
+}
+
+// Send posts the notification to every webhook of the user. A user without webhooks
+// is skipped silently.
+func (c *WebhookChannel) Send(ctx context.Context, n notification.Notification) error {
+	webhooks, err := c.storage.ListWebhooks(ctx, n.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	body, err := n.Marshal()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, webhook := range webhooks {
+		if err := c.deliver(ctx, webhook, n, body); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", webhook.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *WebhookChannel) deliver(ctx context.Context, webhook storage.Webhook, n notification.Notification,
+	body []byte,
+) error {
+	deliveryID := deliveryID(webhook.ID, n)
+	for attempt := 1; ; attempt++ {
+		started := time.Now()
+		statusCode, err := c.post(ctx, webhook, deliveryID, body)
+		c.record(ctx, storage.Delivery{
+			ID:          uuid.NewString(),
+			WebhookID:   webhook.ID,
+			EventID:     n.EventID,
+			StartAt:     n.Date,
+			Attempt:     attempt,
+			DeliveredAt: started,
+			Duration:    time.Since(started),
+			StatusCode:  statusCode,
+			Error:       errorText(err),
+		})
+		if err == nil {
+			return nil
+		}
+
+		if !retryable(statusCode) || errors.Is(err, netguard.ErrForbiddenAddress) ||
+			attempt > c.options.MaxRetries || ctx.Err() != nil {
+			return err
+		}
+		delay := c.backoff(attempt)
+		c.logger.WarnContext(ctx, "failed to deliver webhook, will retry",
+			"webhook_id", webhook.ID, "event_id", n.EventID, "attempt", attempt, "delay", delay, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// post sends the signed body and returns the status code of the response, zero if there is none.
+func (c *WebhookChannel) post(ctx context.Context, webhook storage.Webhook, deliveryID string,
+	body []byte,
+) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(TimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	request.Header.Set(SignatureHeader, Sign(webhook.Secret, timestamp, body))
+	request.Header.Set(DeliveryHeader, deliveryID)
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	// the rest of the body is read, so that the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, maxResponseSize))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("unexpected response status %s", response.Status)
+	}
+	return response.StatusCode, nil
+}
+
+func (c *WebhookChannel) record(ctx context.Context, delivery storage.Delivery) {
+	if err := c.storage.AddDelivery(ctx, delivery); err != nil {
+		c.logger.ErrorContext(ctx, "failed to record webhook delivery",
+			"webhook_id", delivery.WebhookID, "event_id", delivery.EventID, "error", err)
+	}
+}
+
+// backoff returns the delay before the retry following the attempt.
+func (c *WebhookChannel) backoff(attempt int) time.Duration {
+	delay := c.options.MaxBackoff
+	if attempt < 32 {
+		delay = min(c.options.MinBackoff<<(attempt-1), c.options.MaxBackoff)
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay/2+1) //nolint:gosec
+}
+
+// Sign returns the value of SignatureHeader for the body signed at the timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliveryID identifies the notification sent to the webhook.
+func deliveryID(webhookID string, n notification.Notification) string {
+	sum := sha256.Sum256([]byte(webhookID + "\n" + n.EventID + "\n" + n.Date.UTC().Format(time.RFC3339Nano)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// retryable reports whether a failed attempt with the status code may succeed later.
+// Zero status code means there was no response at all.
+func retryable(statusCode int) bool {
+	return statusCode == 0 || statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,155 @@
+package sender
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/netguard"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+// receiver is a webhook endpoint responding with the given statuses in turn, the last one repeats.
+type receiver struct {
+	t        *testing.T
+	secret   string
+	statuses []int
+
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   []string
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	require.NoError(rc.t, err)
+
+	timestamp, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+	require.NoError(rc.t, err)
+	require.Equal(rc.t, Sign(rc.secret, time.Unix(timestamp, 0), body), r.Header.Get(SignatureHeader))
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, string(body))
+	w.WriteHeader(rc.statuses[min(len(rc.requests), len(rc.statuses))-1])
+}
+
+func newWebhook(t *testing.T, s *memorystorage.Storage, id string, statuses ...int) *receiver {
+	t.Helper()
+
+	rc := &receiver{t: t, secret: "secret " + id, statuses: statuses}
+	server := httptest.NewServer(rc)
+	t.Cleanup(server.Close)
+
+	webhook := storage.Webhook{ID: id, UserID: "user", URL: server.URL, Secret: rc.secret, CreatedAt: time.Now()}
+	require.NoError(t, s.CreateWebhook(context.Background(), webhook))
+	return rc
+}
+
+func TestWebhookChannel(t *testing.T) {
+	ctx := context.Background()
+	options := WebhookOptions{
+		Timeout:    time.Second,
+		MaxRetries: 2,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 5 * time.Millisecond,
+		// the receivers listen on the loopback
+		AllowPrivateAddresses: true,
+	}
+
+	t.Run("retries", func(t *testing.T) {
+		s := memorystorage.New()
+		flaky := newWebhook(t, s, "flaky", http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK)
+		healthy := newWebhook(t, s, "healthy", http.StatusNoContent)
+
+		require.NoError(t, NewWebhookChannel(nopLogger{}, s, options).Send(ctx, n))
+
+		require.Len(t, healthy.requests, 1)
+		require.JSONEq(t, `{"eventId":"1","title":"meeting","date":"2024-03-11T10:00:00Z","userId":"user",
+			"publishedAt":"2024-03-11T09:00:00Z"}`, healthy.bodies[0])
+		require.Equal(t, "application/json", healthy.requests[0].Header.Get("Content-Type"))
+
+		require.Len(t, flaky.requests, 3)
+		for _, r := range flaky.requests {
+			require.Equal(t, flaky.requests[0].Header.Get(DeliveryHeader), r.Header.Get(DeliveryHeader))
+		}
+		require.NotEqual(t, healthy.requests[0].Header.Get(DeliveryHeader), flaky.requests[0].Header.Get(DeliveryHeader))
+
+		deliveries, err := s.ListDeliveries(ctx, "flaky", 10)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 3)
+		for i, status := range []int{http.StatusOK, http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+			require.Equal(t, 3-i, deliveries[i].Attempt)
+			require.Equal(t, status, deliveries[i].StatusCode)
+			require.Equal(t, "1", deliveries[i].EventID)
+			require.True(t, n.Date.Equal(deliveries[i].StartAt))
+		}
+		require.Empty(t, deliveries[0].Error)
+		require.NotEmpty(t, deliveries[1].Error)
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		s := memorystorage.New()
+		rejecting := newWebhook(t, s, "rejecting", http.StatusBadRequest)
+		failing := newWebhook(t, s, "failing", http.StatusInternalServerError)
+
+		err := NewWebhookChannel(nopLogger{}, s, options).Send(ctx, n)
+		require.ErrorContains(t, err, "webhook rejecting")
+		require.ErrorContains(t, err, "webhook failing")
+		require.Len(t, rejecting.requests, 1)
+		require.Len(t, failing.requests, options.MaxRetries+1)
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		s := memorystorage.New()
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+		webhook := storage.Webhook{ID: "down", UserID: "user", URL: server.URL, Secret: "secret", CreatedAt: time.Now()}
+		require.NoError(t, s.CreateWebhook(ctx, webhook))
+
+		require.Error(t, NewWebhookChannel(nopLogger{}, s, options).Send(ctx, n))
+		deliveries, err := s.ListDeliveries(ctx, "down", 10)
+		require.NoError(t, err)
+		require.Len(t, deliveries, options.MaxRetries+1)
+		require.Zero(t, deliveries[0].StatusCode)
+	})
+
+	t.Run("private address", func(t *testing.T) {
+		s := memorystorage.New()
+		internal := newWebhook(t, s, "internal", http.StatusOK)
+
+		options := options
+		options.AllowPrivateAddresses = false
+		err := NewWebhookChannel(nopLogger{}, s, options).Send(ctx, n)
+		require.ErrorIs(t, err, netguard.ErrForbiddenAddress)
+		require.Empty(t, internal.requests)
+
+		deliveries, err := s.ListDeliveries(ctx, "internal", 10)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+	})
+
+	t.Run("no webhooks", func(t *testing.T) {
+		require.NoError(t, NewWebhookChannel(nopLogger{}, memorystorage.New(), options).Send(ctx, n))
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	c := NewWebhookChannel(nopLogger{}, nil, WebhookOptions{MinBackoff: time.Second, MaxBackoff: 10 * time.Second})
+	limits := map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 100: 10 * time.Second}
+	for attempt, limit := range limits {
+		for range 100 {
+			delay := c.backoff(attempt)
+			require.GreaterOrEqual(t, delay, limit/2)
+			require.LessOrEqual(t, delay, limit)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package internalgrpc
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/logger"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const requestIDKey = "x-request-id"
+
+// loggingInterceptor logs every call by analogy with the HTTP access log. The request ID
+// is taken from x-request-id metadata or generated, returned in the response header
+// and added to all records logged within the call context.
+func loggingInterceptor(l Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+
+		requestID := logger.RequestID(firstMetadataValue(ctx, requestIDKey))
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, requestID))
+
+		fields := []any{"request_id", requestID}
+		if userID := userID(ctx); userID != "" {
+			fields = append(fields, "user_id", userID)
+		}
+		ctx = logger.ContextWith(ctx, fields...)
+
+		response, err := handler(ctx, req)
+
+		clientIP := ""
+		if p, ok := peer.FromContext(ctx); ok {
+			clientIP = p.Addr.String()
+		}
+		l.InfoContext(ctx, "grpc request",
+			"client_ip", clientIP,
+			"method", info.FullMethod,
+			"code", status.Code(err).String(),
+			"latency", time.Since(start),
+			"user_agent", firstMetadataValue(ctx, "user-agent"),
+		)
+
+		return response, err
+	}
+}
+
+// rateLimitInterceptor rejects calls of a client exceeding the rate limit. Clients are told apart
+// by the IP address, as x-user-id is chosen by the client and changing it would reset the limit.
+func rateLimitInterceptor(limiter *ratelimit.Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var key string
+		if p, ok := peer.FromContext(ctx); ok {
+			key = p.Addr.String()
+			if host, _, err := net.SplitHostPort(key); err == nil {
+				key = host
+			}
+		}
+		if !limiter.Allow(key) {
+			return nil, status.Error(codes.ResourceExhausted, "too many requests")
+		}
+		return handler(ctx, req)
+	}
+}
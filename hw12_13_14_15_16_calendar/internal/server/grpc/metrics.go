@@ -0,0 +1,35 @@
+package internalgrpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "calendar_grpc_requests_total",
+		Help: "Number of gRPC calls by method and status code.",
+	}, []string{"method", "code"})
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "calendar_grpc_request_duration_seconds",
+		Help:    "Latency of gRPC calls by method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+// metricsInterceptor collects metrics of every call by analogy with the HTTP routes.
+func metricsInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	start := time.Now()
+	response, err := handler(ctx, req)
+
+	requestsTotal.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+	requestDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+	return response, err
+}
@@ -0,0 +1,311 @@
+package internalgrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/app"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/ratelimit"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/pkg/eventpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const userIDKey = "x-user-id"
+
+type Server struct {
+	eventpb.UnimplementedEventServiceServer
+
+	logger Logger
+	app    Application
+	addr   string
+	server *grpc.Server
+}
+
+type Logger interface {
+	Info(msg string, args ...any)
+	InfoContext(ctx context.Context, msg string, args ...any)
+}
+
+type Application interface {
+	CreateEvent(ctx context.Context, userID string, event storage.Event) (storage.Event, error)
+	UpdateEvent(ctx context.Context, userID, id string, event storage.Event) (storage.Event, error)
+	DeleteEvent(ctx context.Context, userID, id string) error
+	ListEventsForDay(ctx context.Context, userID string, date time.Time) ([]storage.Event, error)
+	ListEventsForWeek(ctx context.Context, userID string, date time.Time) ([]storage.Event, error)
+	ListEventsForMonth(ctx context.Context, userID string, date time.Time) ([]storage.Event, error)
+	FreeBusy(ctx context.Context, userID string, userIDs []string, from, to time.Time) ([]app.UserBusy, error)
+	FindSlots(ctx context.Context, userID string, query app.SlotQuery) ([]app.Interval, error)
+}
+
+// NewServer creates the API server. Calls are limited by the limiter unless it's nil.
+func NewServer(logger Logger, app Application, addr string, limiter *ratelimit.Limiter) *Server {
+	s := &Server{
+		logger: logger,
+		app:    app,
+		addr:   addr,
+	}
+
+	s.server = grpc.NewServer(grpc.ChainUnaryInterceptor(
+		loggingInterceptor(logger),
+		metricsInterceptor,
+		rateLimitInterceptor(limiter),
+	))
+	eventpb.RegisterEventServiceServer(s.server, s)
+	return s
+}
+
+// Start serves requests until the server is stopped.
+func (s *Server) Start(_ context.Context) error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen %s: %w", s.addr, err)
+	}
+	return s.serve(listener)
+}
+
+func (s *Server) serve(listener net.Listener) error {
+	s.logger.Info("grpc server is listening", "addr", listener.Addr().String())
+
+	if err := s.server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// Stop waits for in-flight requests to complete until ctx is done,
+// then closes all connections.
+func (s *Server) Stop(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
+
+func (s *Server) Create(ctx context.Context, request *eventpb.CreateRequest) (*eventpb.CreateResponse, error) {
+	event, err := s.app.CreateEvent(ctx, userID(ctx), fromProto(request.GetEvent()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &eventpb.CreateResponse{Event: toProto(event)}, nil
+}
+
+func (s *Server) Update(ctx context.Context, request *eventpb.UpdateRequest) (*eventpb.UpdateResponse, error) {
+	event := fromProto(request.GetEvent())
+	event.Version = request.GetVersion()
+	event, err := s.app.UpdateEvent(ctx, userID(ctx), request.GetId(), event)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &eventpb.UpdateResponse{Event: toProto(event)}, nil
+}
+
+func (s *Server) Delete(ctx context.Context, request *eventpb.DeleteRequest) (*eventpb.DeleteResponse, error) {
+	if err := s.app.DeleteEvent(ctx, userID(ctx), request.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &eventpb.DeleteResponse{}, nil
+}
+
+func (s *Server) ListDay(ctx context.Context, request *eventpb.ListRequest) (*eventpb.ListResponse, error) {
+	return list(ctx, request, s.app.ListEventsForDay)
+}
+
+func (s *Server) ListWeek(ctx context.Context, request *eventpb.ListRequest) (*eventpb.ListResponse, error) {
+	return list(ctx, request, s.app.ListEventsForWeek)
+}
+
+func (s *Server) ListMonth(ctx context.Context, request *eventpb.ListRequest) (*eventpb.ListResponse, error) {
+	return list(ctx, request, s.app.ListEventsForMonth)
+}
+
+type listFunc func(ctx context.Context, userID string, date time.Time) ([]storage.Event, error)
+
+func list(ctx context.Context, request *eventpb.ListRequest, fn listFunc) (*eventpb.ListResponse, error) {
+	if !request.GetDate().IsValid() {
+		return nil, status.Error(codes.InvalidArgument, "date is required")
+	}
+	loc, err := storage.LoadLocation(request.GetTimeZone())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown time zone %q", request.GetTimeZone())
+	}
+
+	events, err := fn(ctx, userID(ctx), request.GetDate().AsTime().In(loc))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	response := &eventpb.ListResponse{Events: make([]*eventpb.Event, 0, len(events))}
+	for _, event := range events {
+		response.Events = append(response.Events, toProto(event))
+	}
+	return response, nil
+}
+
+func (s *Server) FreeBusy(ctx context.Context, request *eventpb.FreeBusyRequest) (*eventpb.FreeBusyResponse, error) {
+	users, err := s.app.FreeBusy(ctx, userID(ctx), request.GetUserIds(),
+		timeOf(request.GetFrom()), timeOf(request.GetTo()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	response := &eventpb.FreeBusyResponse{Users: make([]*eventpb.UserBusy, 0, len(users))}
+	for _, user := range users {
+		response.Users = append(response.Users, &eventpb.UserBusy{UserId: user.UserID, Busy: intervalsToProto(user.Busy)})
+	}
+	return response, nil
+}
+
+func (s *Server) FindSlots(ctx context.Context, request *eventpb.FindSlotsRequest) (*eventpb.FindSlotsResponse, error) {
+	loc, err := storage.LoadLocation(request.GetTimeZone())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown time zone %q", request.GetTimeZone())
+	}
+
+	query := app.SlotQuery{
+		UserIDs:   request.GetUserIds(),
+		Duration:  request.GetDuration().AsDuration(),
+		From:      timeOf(request.GetFrom()),
+		To:        timeOf(request.GetTo()),
+		WorkStart: request.GetWorkStart().AsDuration(),
+		WorkEnd:   request.GetWorkEnd().AsDuration(),
+		Location:  loc,
+		Limit:     int(request.GetLimit()),
+	}
+	for _, weekday := range request.GetWeekdays() {
+		if weekday < int32(time.Sunday) || weekday > int32(time.Saturday) {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown weekday %d", weekday)
+		}
+		query.Weekdays = append(query.Weekdays, time.Weekday(weekday))
+	}
+
+	slots, err := s.app.FindSlots(ctx, userID(ctx), query)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &eventpb.FindSlotsResponse{Slots: intervalsToProto(slots)}, nil
+}
+
+func userID(ctx context.Context) string {
+	return firstMetadataValue(ctx, userIDKey)
+}
+
+func firstMetadataValue(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func toStatus(err error) error {
+	var code codes.Code
+	switch {
+	case errors.Is(err, app.ErrEmptyUserID):
+		code = codes.Unauthenticated
+	case errors.Is(err, storage.ErrEventNotFound):
+		code = codes.NotFound
+	case errors.Is(err, storage.ErrDateBusy), errors.Is(err, storage.ErrEventAlreadyExists):
+		code = codes.AlreadyExists
+	case errors.Is(err, storage.ErrVersionConflict):
+		code = codes.FailedPrecondition
+	case errors.Is(err, app.ErrInvalidEvent), errors.Is(err, app.ErrInvalidQuery):
+		code = codes.InvalidArgument
+	default:
+		// details of internal errors are logged by the application
+		return status.Error(codes.Internal, "internal error")
+	}
+	return status.Error(code, err.Error())
+}
+
+func fromProto(event *eventpb.Event) storage.Event {
+	result := storage.Event{
+		Title:        event.GetTitle(),
+		Duration:     event.GetDuration().AsDuration(),
+		Description:  event.GetDescription(),
+		NotifyBefore: event.GetNotifyBefore().AsDuration(),
+		RRule:        event.GetRrule(),
+		SeriesID:     event.GetSeriesId(),
+		TimeZone:     event.GetTimeZone(),
+	}
+	if event.GetStartAt() != nil {
+		result.StartAt = event.GetStartAt().AsTime()
+	}
+	for _, exdate := range event.GetExdates() {
+		result.ExDates = append(result.ExDates, exdate.AsTime())
+	}
+	if event.GetRecurrenceId() != nil {
+		result.RecurrenceID = event.GetRecurrenceId().AsTime()
+	}
+	for _, reminder := range event.GetReminders() {
+		result.Reminders = append(result.Reminders,
+			storage.Reminder{Before: reminder.GetBefore().AsDuration(), Channel: reminder.GetChannel()})
+	}
+	return result
+}
+
+func toProto(event storage.Event) *eventpb.Event {
+	result := &eventpb.Event{
+		Id:           event.ID,
+		UserId:       event.UserID,
+		Title:        event.Title,
+		StartAt:      timestamppb.New(event.StartAt),
+		Duration:     durationpb.New(event.Duration),
+		Description:  event.Description,
+		NotifyBefore: durationpb.New(event.NotifyBefore),
+		Rrule:        event.RRule,
+		SeriesId:     event.SeriesID,
+		TimeZone:     event.TimeZone,
+		Version:      event.Version,
+	}
+	for _, exdate := range event.ExDates {
+		result.Exdates = append(result.Exdates, timestamppb.New(exdate))
+	}
+	if !event.RecurrenceID.IsZero() {
+		result.RecurrenceId = timestamppb.New(event.RecurrenceID)
+	}
+	for _, reminder := range event.Reminders {
+		result.Reminders = append(result.Reminders,
+			&eventpb.Reminder{Before: durationpb.New(reminder.Before), Channel: reminder.Channel})
+	}
+	for _, attendee := range event.Attendees {
+		result.Attendees = append(result.Attendees,
+			&eventpb.Attendee{UserId: attendee.UserID, Status: string(attendee.Status)})
+	}
+	return result
+}
+
+// timeOf returns the zero time for an unset timestamp, so that the application reports it.
+func timeOf(t *timestamppb.Timestamp) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.AsTime()
+}
+
+func intervalsToProto(intervals []app.Interval) []*eventpb.Interval {
+	result := make([]*eventpb.Interval, 0, len(intervals))
+	for _, interval := range intervals {
+		result = append(result, &eventpb.Interval{
+			Start: timestamppb.New(interval.Start),
+			End:   timestamppb.New(interval.End),
+		})
+	}
+	return result
+}
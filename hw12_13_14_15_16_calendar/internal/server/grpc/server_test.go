@@ -0,0 +1,341 @@
+package internalgrpc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/app"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/logger"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/pkg/eventpb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Info(string, ...any)                          {}
+func (nopLogger) DebugContext(context.Context, string, ...any) {}
+func (nopLogger) InfoContext(context.Context, string, ...any)  {}
+func (nopLogger) WarnContext(context.Context, string, ...any)  {}
+func (nopLogger) ErrorContext(context.Context, string, ...any) {}
+
+func newClient(t *testing.T) eventpb.EventServiceClient {
+	t.Helper()
+	return newAppClient(t, app.New(nopLogger{}, memorystorage.New()))
+}
+
+// newAppClient serves the application, so a test can change events beyond the service as well.
+func newAppClient(t *testing.T, a *app.App) eventpb.EventServiceClient {
+	t.Helper()
+
+	s := NewServer(nopLogger{}, a, "", nil)
+	listener := bufconn.Listen(1 << 20)
+	go func() {
+		require.NoError(t, s.serve(listener))
+	}()
+	t.Cleanup(func() {
+		require.NoError(t, s.Stop(context.Background()))
+	})
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return eventpb.NewEventServiceClient(conn)
+}
+
+func asUser(userID string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), userIDKey, userID)
+}
+
+var baseTime = time.Date(2024, time.March, 11, 10, 0, 0, 0, time.UTC)
+
+func newEvent(title string, startAt time.Time) *eventpb.Event {
+	return &eventpb.Event{
+		Title:        title,
+		StartAt:      timestamppb.New(startAt),
+		Duration:     durationpb.New(time.Hour),
+		Description:  "description",
+		NotifyBefore: durationpb.New(15 * time.Minute),
+	}
+}
+
+func TestEventService(t *testing.T) {
+	client := newClient(t)
+	ctx := asUser("user")
+
+	var header metadata.MD
+	created, err := client.Create(ctx, &eventpb.CreateRequest{Event: newEvent("meeting", baseTime)}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.NotEmpty(t, created.GetEvent().GetId())
+	require.Equal(t, "user", created.GetEvent().GetUserId())
+	require.Equal(t, 15*time.Minute, created.GetEvent().GetNotifyBefore().AsDuration())
+	require.NotEmpty(t, header.Get(requestIDKey))
+
+	id := created.GetEvent().GetId()
+	updated, err := client.Update(ctx, &eventpb.UpdateRequest{Id: id, Event: newEvent("renamed", baseTime)})
+	require.NoError(t, err)
+	require.Equal(t, "renamed", updated.GetEvent().GetTitle())
+	require.Equal(t, id, updated.GetEvent().GetId())
+	require.Equal(t, int64(2), updated.GetEvent().GetVersion())
+
+	list, err := client.ListDay(ctx, &eventpb.ListRequest{Date: timestamppb.New(baseTime)})
+	require.NoError(t, err)
+	require.Len(t, list.GetEvents(), 1)
+	require.True(t, proto.Equal(updated.GetEvent(), list.GetEvents()[0]))
+
+	list, err = client.ListWeek(ctx, &eventpb.ListRequest{Date: timestamppb.New(baseTime.AddDate(0, 0, 1))})
+	require.NoError(t, err)
+	require.Empty(t, list.GetEvents())
+
+	list, err = client.ListMonth(ctx, &eventpb.ListRequest{Date: timestamppb.New(baseTime.AddDate(0, 0, -10))})
+	require.NoError(t, err)
+	require.Len(t, list.GetEvents(), 1)
+
+	_, err = client.Delete(ctx, &eventpb.DeleteRequest{Id: id})
+	require.NoError(t, err)
+
+	list, err = client.ListDay(ctx, &eventpb.ListRequest{Date: timestamppb.New(baseTime)})
+	require.NoError(t, err)
+	require.Empty(t, list.GetEvents())
+}
+
+func TestRequestID(t *testing.T) {
+	client := newClient(t)
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(asUser("user"), requestIDKey, "request-42")
+	_, err := client.ListDay(ctx, &eventpb.ListRequest{Date: timestamppb.New(baseTime)}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.Equal(t, []string{"request-42"}, header.Get(requestIDKey))
+
+	// malformed ones are replaced, as they are logged and sent back
+	for _, requestID := range []string{"with spaces", strings.Repeat("x", logger.MaxRequestIDLength+1)} {
+		ctx := metadata.AppendToOutgoingContext(asUser("user"), requestIDKey, requestID)
+		_, err := client.ListDay(ctx, &eventpb.ListRequest{Date: timestamppb.New(baseTime)}, grpc.Header(&header))
+		require.NoError(t, err)
+		require.Len(t, header.Get(requestIDKey), 1)
+		require.NotEqual(t, requestID, header.Get(requestIDKey)[0])
+		require.NotEmpty(t, header.Get(requestIDKey)[0])
+	}
+}
+
+func TestReminders(t *testing.T) {
+	client := newClient(t)
+	ctx := asUser("user")
+
+	created, err := client.Create(ctx, &eventpb.CreateRequest{Event: newEvent("meeting", baseTime)})
+	require.NoError(t, err)
+	require.Empty(t, created.GetEvent().GetReminders())
+
+	event := newEvent("meeting", baseTime)
+	event.NotifyBefore = nil
+	event.Reminders = []*eventpb.Reminder{
+		{Before: durationpb.New(24 * time.Hour), Channel: "webhook"},
+		{Before: durationpb.New(10 * time.Minute)},
+	}
+	updated, err := client.Update(ctx, &eventpb.UpdateRequest{Id: created.GetEvent().GetId(), Event: event})
+	require.NoError(t, err)
+	reminders := updated.GetEvent().GetReminders()
+	require.Len(t, reminders, 2)
+	require.Equal(t, "webhook", reminders[0].GetChannel())
+	require.Equal(t, 24*time.Hour, reminders[0].GetBefore().AsDuration())
+	require.Empty(t, reminders[1].GetChannel())
+	require.Equal(t, 10*time.Minute, reminders[1].GetBefore().AsDuration())
+
+	event.Reminders = []*eventpb.Reminder{{Before: durationpb.New(time.Hour), Channel: "pigeon"}}
+	_, err = client.Update(ctx, &eventpb.UpdateRequest{Id: created.GetEvent().GetId(), Event: event})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestAttendees(t *testing.T) {
+	a := app.New(nopLogger{}, memorystorage.New())
+	client := newAppClient(t, a)
+
+	created, err := client.Create(asUser("owner"), &eventpb.CreateRequest{Event: newEvent("meeting", baseTime)})
+	require.NoError(t, err)
+	require.Empty(t, created.GetEvent().GetAttendees())
+	id := created.GetEvent().GetId()
+	_, err = a.InviteAttendee(context.Background(), "owner", id, "guest")
+	require.NoError(t, err)
+	_, err = a.RespondToInvitation(context.Background(), "guest", id, storage.StatusAccepted)
+	require.NoError(t, err)
+
+	// the attendees see the event along with the owner
+	for _, userID := range []string{"owner", "guest"} {
+		list, err := client.ListDay(asUser(userID), &eventpb.ListRequest{Date: timestamppb.New(baseTime)})
+		require.NoError(t, err)
+		require.Len(t, list.GetEvents(), 1)
+		attendees := list.GetEvents()[0].GetAttendees()
+		require.Len(t, attendees, 1)
+		require.Equal(t, "guest", attendees[0].GetUserId())
+		require.Equal(t, "accepted", attendees[0].GetStatus())
+	}
+
+	// and an update made through the service keeps them
+	event := newEvent("renamed", baseTime)
+	event.Attendees = []*eventpb.Attendee{{UserId: "intruder", Status: "accepted"}}
+	updated, err := client.Update(asUser("owner"), &eventpb.UpdateRequest{Id: id, Event: event})
+	require.NoError(t, err)
+	require.Len(t, updated.GetEvent().GetAttendees(), 1)
+	require.Equal(t, "guest", updated.GetEvent().GetAttendees()[0].GetUserId())
+}
+
+func TestErrors(t *testing.T) {
+	client := newClient(t)
+	ctx := asUser("user")
+
+	created, err := client.Create(ctx, &eventpb.CreateRequest{Event: newEvent("meeting", baseTime)})
+	require.NoError(t, err)
+	id := created.GetEvent().GetId()
+
+	_, err = client.Create(context.Background(), &eventpb.CreateRequest{Event: newEvent("meeting", baseTime)})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.Create(ctx, &eventpb.CreateRequest{Event: newEvent("overlap", baseTime)})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = client.Create(ctx, &eventpb.CreateRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.Update(asUser("other"), &eventpb.UpdateRequest{Id: id, Event: newEvent("stolen", baseTime)})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.Update(ctx, &eventpb.UpdateRequest{Id: id, Event: newEvent("renamed", baseTime), Version: 1})
+	require.NoError(t, err)
+	_, err = client.Update(ctx, &eventpb.UpdateRequest{Id: id, Event: newEvent("stale", baseTime), Version: 1})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = client.Delete(ctx, &eventpb.DeleteRequest{Id: "unknown"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.ListDay(ctx, &eventpb.ListRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestRecurringEvents(t *testing.T) {
+	client := newClient(t)
+	ctx := asUser("user")
+
+	event := newEvent("standup", baseTime)
+	event.Rrule = "FREQ=WEEKLY;BYDAY=MO,TU"
+	event.Exdates = []*timestamppb.Timestamp{timestamppb.New(baseTime.AddDate(0, 0, 7))}
+	created, err := client.Create(ctx, &eventpb.CreateRequest{Event: event})
+	require.NoError(t, err)
+	series := created.GetEvent()
+	require.Equal(t, event.GetRrule(), series.GetRrule())
+	require.Len(t, series.GetExdates(), 1)
+	require.Nil(t, series.GetRecurrenceId())
+
+	override := newEvent("long standup", baseTime.AddDate(0, 0, 1))
+	override.Duration = durationpb.New(2 * time.Hour)
+	override.SeriesId = series.GetId()
+	override.RecurrenceId = timestamppb.New(baseTime.AddDate(0, 0, 1))
+	_, err = client.Create(ctx, &eventpb.CreateRequest{Event: override})
+	require.NoError(t, err)
+
+	list, err := client.ListMonth(ctx, &eventpb.ListRequest{Date: timestamppb.New(baseTime.AddDate(0, 0, -10))})
+	require.NoError(t, err)
+	// Mondays and Tuesdays from March 11 to 31 but March 18
+	require.Len(t, list.GetEvents(), 5)
+	require.Equal(t, series.GetId(), list.GetEvents()[0].GetId())
+	require.True(t, baseTime.Equal(list.GetEvents()[0].GetRecurrenceId().AsTime()))
+	require.Equal(t, "long standup", list.GetEvents()[1].GetTitle())
+	require.Equal(t, series.GetId(), list.GetEvents()[1].GetSeriesId())
+
+	override.RecurrenceId = nil
+	_, err = client.Create(ctx, &eventpb.CreateRequest{Event: override})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestTimeZones(t *testing.T) {
+	client := newClient(t)
+	ctx := asUser("user")
+
+	// late evening in New York is the next day in Moscow
+	_, err := client.Create(ctx, &eventpb.CreateRequest{
+		Event: newEvent("late", time.Date(2024, time.March, 11, 23, 30, 0, 0, time.UTC)),
+	})
+	require.NoError(t, err)
+
+	standup := newEvent("standup", time.Date(2024, time.March, 4, 15, 0, 0, 0, time.UTC))
+	standup.Rrule = "FREQ=WEEKLY"
+	standup.TimeZone = "America/New_York"
+	created, err := client.Create(ctx, &eventpb.CreateRequest{Event: standup})
+	require.NoError(t, err)
+	require.Equal(t, "America/New_York", created.GetEvent().GetTimeZone())
+
+	date := timestamppb.New(time.Date(2024, time.March, 11, 12, 0, 0, 0, time.UTC))
+	list, err := client.ListDay(ctx, &eventpb.ListRequest{Date: date, TimeZone: "America/New_York"})
+	require.NoError(t, err)
+	require.Len(t, list.GetEvents(), 2)
+	// the series keeps its local time after DST starts on March 10
+	require.Equal(t, time.Date(2024, time.March, 11, 14, 0, 0, 0, time.UTC), list.GetEvents()[0].GetStartAt().AsTime())
+
+	list, err = client.ListDay(ctx, &eventpb.ListRequest{Date: date, TimeZone: "Europe/Moscow"})
+	require.NoError(t, err)
+	require.Len(t, list.GetEvents(), 1)
+	require.Equal(t, "standup", list.GetEvents()[0].GetTitle())
+
+	_, err = client.ListDay(ctx, &eventpb.ListRequest{Date: date, TimeZone: "Mars/Olympus"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestFreeBusy(t *testing.T) {
+	client := newClient(t)
+
+	_, err := client.Create(asUser("alice"), &eventpb.CreateRequest{Event: newEvent("meeting", baseTime)})
+	require.NoError(t, err)
+
+	from := timestamppb.New(baseTime.Add(-2 * time.Hour))
+	to := timestamppb.New(baseTime.Add(2 * time.Hour))
+	busy, err := client.FreeBusy(asUser("bob"), &eventpb.FreeBusyRequest{UserIds: []string{"alice"}, From: from, To: to})
+	require.NoError(t, err)
+	require.Len(t, busy.GetUsers(), 1)
+	require.Equal(t, "alice", busy.GetUsers()[0].GetUserId())
+	require.Len(t, busy.GetUsers()[0].GetBusy(), 1)
+	require.Equal(t, baseTime, busy.GetUsers()[0].GetBusy()[0].GetStart().AsTime())
+
+	// 11:00 in Moscow is 08:00 UTC
+	slots, err := client.FindSlots(asUser("bob"), &eventpb.FindSlotsRequest{
+		UserIds:   []string{"alice", "bob"},
+		Duration:  durationpb.New(time.Hour),
+		From:      from,
+		To:        to,
+		WorkStart: durationpb.New(11 * time.Hour),
+		TimeZone:  "Europe/Moscow",
+		Weekdays:  []int32{int32(time.Monday)},
+	})
+	require.NoError(t, err)
+	require.Len(t, slots.GetSlots(), 3)
+	require.Equal(t, baseTime.Add(-2*time.Hour), slots.GetSlots()[0].GetStart().AsTime())
+	require.Equal(t, baseTime.Add(time.Hour), slots.GetSlots()[2].GetStart().AsTime())
+
+	_, err = client.FreeBusy(asUser("bob"), &eventpb.FreeBusyRequest{UserIds: []string{"alice"}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.FindSlots(asUser("bob"), &eventpb.FindSlotsRequest{
+		UserIds: []string{"alice"}, Duration: durationpb.New(time.Hour), From: from, To: to, Weekdays: []int32{7},
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.FreeBusy(context.Background(),
+		&eventpb.FreeBusyRequest{UserIds: []string{"alice"}, From: from, To: to})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,63 @@
+package internalhttp
+
+import (
+	"net/http"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+)
+
+type inviteRequest struct {
+	UserID string `json:"userId"`
+}
+
+type invitationResponseRequest struct {
+	// Status is accepted, declined or tentative.
+	Status storage.AttendeeStatus `json:"status"`
+}
+
+// inviteAttendee handles POST /events/{id}/attendees, the owner invites the user to the event.
+func (s *Server) inviteAttendee(w http.ResponseWriter, r *http.Request) {
+	var request inviteRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	event, err := s.app.InviteAttendee(r.Context(), r.Header.Get(userIDHeader), r.PathValue("id"), request.UserID)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", eventETag(event))
+	s.writeJSON(w, r, http.StatusOK, newEventResponse(event))
+}
+
+// removeAttendee handles DELETE /events/{id}/attendees/{userId}, the owner withdraws the invitation.
+func (s *Server) removeAttendee(w http.ResponseWriter, r *http.Request) {
+	_, err := s.app.RemoveAttendee(r.Context(), r.Header.Get(userIDHeader), r.PathValue("id"), r.PathValue("userId"))
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondToInvitation handles PUT /events/{id}/response, the attendee accepts or declines the invitation.
+func (s *Server) respondToInvitation(w http.ResponseWriter, r *http.Request) {
+	var request invitationResponseRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	event, err := s.app.RespondToInvitation(r.Context(), r.Header.Get(userIDHeader), r.PathValue("id"), request.Status)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", eventETag(event))
+	s.writeJSON(w, r, http.StatusOK, newEventResponse(event))
+}
@@ -0,0 +1,197 @@
+// Package caldav implements the subset of CalDAV (RFC 4791) calendar clients need to sync
+// with the service: discovery with PROPFIND, calendar-query and calendar-multiget reports,
+// and GET, PUT and DELETE of calendar object resources.
+//
+// Every user has a single calendar. Its resources are named after event IDs and contain
+// an event or a series along with the overrides of its occurrences:
+//
+//	{prefix}/principals/{user}/                     the principal of the user
+//	{prefix}/calendars/{user}/                      the calendar home
+//	{prefix}/calendars/{user}/events/               the calendar
+//	{prefix}/calendars/{user}/events/{event id}.ics the calendar object resource
+package caldav
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/app"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	calendarName  = "events"
+	objectSuffix  = ".ics"
+	maxObjectSize = 1 << 20
+
+	allowedMethods = "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT"
+)
+
+type Logger interface {
+	ErrorContext(ctx context.Context, msg string, args ...any)
+}
+
+type Application interface {
+	DeleteEvent(ctx context.Context, userID, id string) error
+	ImportEvents(ctx context.Context, userID string, events []storage.Event) (app.ImportResult, error)
+	ExportEvents(ctx context.Context, userID string) ([]storage.Event, error)
+}
+
+type Handler struct {
+	logger Logger
+	app    Application
+	prefix string
+	userID func(r *http.Request) string
+}
+
+// New creates the handler serving the paths under prefix. userID returns the user
+// the request is made by or an empty string, if the request is anonymous.
+func New(logger Logger, app Application, prefix string, userID func(r *http.Request) string) *Handler {
+	return &Handler{
+		logger: logger,
+		app:    app,
+		prefix: strings.TrimSuffix(prefix, "/"),
+		userID: userID,
+	}
+}
+
+type kind int
+
+const (
+	kindRoot kind = iota
+	kindPrincipal
+	kindHome
+	kindCalendar
+	kindObject
+)
+
+// target is the resource a request is made to.
+type target struct {
+	kind   kind
+	userID string
+	// id is the event ID of a calendar object resource.
+	id string
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("DAV", "1, 3, calendar-access")
+
+	userID := h.userID(r)
+	if userID == "" {
+		w.Header().Set("WWW-Authenticate", `Basic realm="calendar"`)
+		http.Error(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	t, ok := h.parsePath(r.URL.Path)
+	if !ok || t.kind != kindRoot && t.userID != userID {
+		// resources of other users don't exist for the user
+		http.NotFound(w, r)
+		return
+	}
+	t.userID = userID
+
+	switch r.Method {
+	case http.MethodOptions:
+		w.Header().Set("Allow", allowedMethods)
+	case "PROPFIND":
+		h.propfind(w, r, t)
+	case "REPORT":
+		h.report(w, r, t)
+	case http.MethodGet, http.MethodHead:
+		h.get(w, r, t)
+	case http.MethodPut:
+		h.put(w, r, t)
+	case http.MethodDelete:
+		h.delete(w, r, t)
+	default:
+		w.Header().Set("Allow", allowedMethods)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) parsePath(path string) (target, bool) {
+	rest, ok := strings.CutPrefix(path, h.prefix+"/")
+	if !ok {
+		return target{}, false
+	}
+	segments := strings.Split(strings.TrimSuffix(rest, "/"), "/")
+
+	switch {
+	case rest == "":
+		return target{kind: kindRoot}, true
+	case len(segments) == 2 && segments[0] == "principals":
+		return target{kind: kindPrincipal, userID: segments[1]}, true
+	case len(segments) == 2 && segments[0] == "calendars":
+		return target{kind: kindHome, userID: segments[1]}, true
+	case len(segments) == 3 && segments[0] == "calendars" && segments[2] == calendarName:
+		return target{kind: kindCalendar, userID: segments[1]}, true
+	case len(segments) == 4 && segments[0] == "calendars" && segments[2] == calendarName:
+		id, ok := strings.CutSuffix(segments[3], objectSuffix)
+		if !ok || id == "" || strings.HasSuffix(rest, "/") {
+			return target{}, false
+		}
+		return target{kind: kindObject, userID: segments[1], id: id}, true
+	default:
+		return target{}, false
+	}
+}
+
+func (h *Handler) principalHref(userID string) string {
+	return h.prefix + "/principals/" + url.PathEscape(userID) + "/"
+}
+
+func (h *Handler) homeHref(userID string) string {
+	return h.prefix + "/calendars/" + url.PathEscape(userID) + "/"
+}
+
+func (h *Handler) calendarHref(userID string) string {
+	return h.homeHref(userID) + calendarName + "/"
+}
+
+func (h *Handler) objectHref(userID, id string) string {
+	return h.calendarHref(userID) + url.PathEscape(id) + objectSuffix
+}
+
+func (h *Handler) href(t target) string {
+	switch t.kind {
+	case kindPrincipal:
+		return h.principalHref(t.userID)
+	case kindHome:
+		return h.homeHref(t.userID)
+	case kindCalendar:
+		return h.calendarHref(t.userID)
+	case kindObject:
+		return h.objectHref(t.userID, t.id)
+	default:
+		return h.prefix + "/"
+	}
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrEventNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrDateBusy), errors.Is(err, storage.ErrEventAlreadyExists):
+		return http.StatusConflict
+	case errors.Is(err, storage.ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, app.ErrInvalidEvent):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := errorStatus(err)
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		// details of internal errors are logged by the application
+		message = http.StatusText(status)
+	}
+	http.Error(w, message, status)
+}
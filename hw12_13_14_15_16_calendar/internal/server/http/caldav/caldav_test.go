@@ -0,0 +1,282 @@
+package caldav
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/app"
+	memorystorage "github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) DebugContext(context.Context, string, ...any) {}
+func (nopLogger) InfoContext(context.Context, string, ...any)  {}
+func (nopLogger) WarnContext(context.Context, string, ...any)  {}
+func (nopLogger) ErrorContext(context.Context, string, ...any) {}
+
+type client struct {
+	t      *testing.T
+	server *httptest.Server
+}
+
+func newClient(t *testing.T) *client {
+	t.Helper()
+
+	userID := func(r *http.Request) string {
+		user, _, _ := r.BasicAuth()
+		return user
+	}
+	h := New(nopLogger{}, app.New(nopLogger{}, memorystorage.New()), "/dav", userID)
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+
+	return &client{t: t, server: server}
+}
+
+type result struct {
+	status int
+	header http.Header
+	body   string
+}
+
+func (c *client) do(method, path, user, body string, header ...string) result {
+	c.t.Helper()
+
+	request, err := http.NewRequest(method, c.server.URL+path, strings.NewReader(body)) //nolint:noctx
+	require.NoError(c.t, err)
+	if user != "" {
+		request.SetBasicAuth(user, "secret")
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		request.Header.Set(header[i], header[i+1])
+	}
+
+	response, err := c.server.Client().Do(request)
+	require.NoError(c.t, err)
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(response.Body)
+	require.NoError(c.t, err)
+	return result{status: response.StatusCode, header: response.Header, body: string(data)}
+}
+
+func calendarObject(uid string, lines ...string) string {
+	return strings.Join(append(append([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:" + uid,
+		"DTSTART:20240311T100000Z",
+		"DTEND:20240311T103000Z",
+		"SUMMARY:Standup",
+	}, lines...), "END:VCALENDAR"), "\r\n")
+}
+
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:cs="http://calendarserver.org/ns/">
+  <d:prop>
+    <d:current-user-principal/>
+    <c:calendar-home-set/>
+    <d:resourcetype/>
+    <d:getetag/>
+    <cs:getctag/>
+    <x:unknown xmlns:x="urn:example"/>
+  </d:prop>
+</d:propfind>`
+
+func TestDiscovery(t *testing.T) {
+	c := newClient(t)
+
+	r := c.do("PROPFIND", "/dav/", "", propfindBody)
+	require.Equal(t, http.StatusUnauthorized, r.status)
+	require.Contains(t, r.header.Get("WWW-Authenticate"), "Basic")
+
+	r = c.do(http.MethodOptions, "/dav/", "user", "")
+	require.Equal(t, http.StatusOK, r.status)
+	require.Contains(t, r.header.Get("DAV"), "calendar-access")
+	require.Contains(t, r.header.Get("Allow"), "REPORT")
+
+	r = c.do("PROPFIND", "/dav/", "user", propfindBody, "Depth", "0")
+	require.Equal(t, http.StatusMultiStatus, r.status)
+	require.Contains(t, r.body,
+		"<d:current-user-principal><d:href>/dav/principals/user/</d:href></d:current-user-principal>")
+	require.Contains(t, r.body, `<x:unknown xmlns:x="urn:example"/>`)
+	require.Contains(t, r.body, "HTTP/1.1 404 Not Found")
+
+	r = c.do("PROPFIND", "/dav/principals/user/", "user", propfindBody, "Depth", "0")
+	require.Equal(t, http.StatusMultiStatus, r.status)
+	require.Contains(t, r.body, "<c:calendar-home-set><d:href>/dav/calendars/user/</d:href></c:calendar-home-set>")
+
+	r = c.do("PROPFIND", "/dav/calendars/user/", "user", propfindBody, "Depth", "1")
+	require.Equal(t, http.StatusMultiStatus, r.status)
+	require.Contains(t, r.body, "<d:href>/dav/calendars/user/events/</d:href>")
+	require.Contains(t, r.body, "<d:resourcetype><d:collection/><c:calendar/></d:resourcetype>")
+
+	// an empty body asks for all the properties
+	r = c.do("PROPFIND", "/dav/calendars/user/events/", "user", "", "Depth", "0")
+	require.Equal(t, http.StatusMultiStatus, r.status)
+	require.Contains(t, r.body, `<c:supported-calendar-component-set><c:comp name="VEVENT"/>`)
+	require.Contains(t, r.body, "<c:calendar-multiget/>")
+
+	require.Equal(t, http.StatusNotFound, c.do("PROPFIND", "/dav/calendars/other/", "user", propfindBody).status)
+	require.Equal(t, http.StatusNotFound, c.do("PROPFIND", "/dav/unknown/", "user", propfindBody).status)
+	require.Equal(t, http.StatusNotFound,
+		c.do("PROPFIND", "/dav/calendars/user/events/missing.ics", "user", propfindBody).status)
+	require.Equal(t, http.StatusMethodNotAllowed, c.do(http.MethodPost, "/dav/", "user", "").status)
+}
+
+func TestObjects(t *testing.T) {
+	c := newClient(t)
+	path := "/dav/calendars/user/events/standup.ics"
+
+	series := calendarObject("standup",
+		"RRULE:FREQ=DAILY;COUNT=5",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:standup",
+		"RECURRENCE-ID:20240312T100000Z",
+		"DTSTART:20240312T120000Z",
+		"DTEND:20240312T123000Z",
+		"SUMMARY:Late standup",
+		"END:VEVENT",
+	)
+	require.Equal(t, http.StatusCreated, c.do(http.MethodPut, path, "user", series, "If-None-Match", "*").status)
+	require.Equal(t, http.StatusPreconditionFailed,
+		c.do(http.MethodPut, path, "user", series, "If-None-Match", "*").status)
+
+	r := c.do(http.MethodGet, path, "user", "")
+	require.Equal(t, http.StatusOK, r.status)
+	etag := r.header.Get("ETag")
+	require.NotEmpty(t, etag)
+	require.Contains(t, r.body, "RRULE:FREQ=DAILY;COUNT=5\r\n")
+	require.Contains(t, r.body, "RECURRENCE-ID:20240312T100000Z\r\n")
+	require.Equal(t, etag, c.do(http.MethodGet, path, "user", "").header.Get("ETag"), "ETag must be stable")
+
+	require.Equal(t, http.StatusNotFound, c.do(http.MethodGet, path, "other", "").status)
+	require.Equal(t, http.StatusConflict,
+		c.do(http.MethodPut, "/dav/calendars/other/events/standup.ics", "other", series).status)
+
+	// the override is dropped from the new representation
+	updated := calendarObject("standup", "RRULE:FREQ=DAILY;COUNT=3", "END:VEVENT")
+	require.Equal(t, http.StatusPreconditionFailed,
+		c.do(http.MethodPut, path, "user", updated, "If-Match", `"stale"`).status)
+	require.Equal(t, http.StatusNoContent, c.do(http.MethodPut, path, "user", updated, "If-Match", etag).status)
+
+	r = c.do(http.MethodGet, path, "user", "")
+	require.NotEqual(t, etag, r.header.Get("ETag"))
+	require.NotContains(t, r.body, "RECURRENCE-ID")
+
+	single := strings.ReplaceAll(calendarObject("review", "END:VEVENT"), "20240311", "20240320")
+	require.Equal(t, http.StatusCreated,
+		c.do(http.MethodPut, "/dav/calendars/user/events/review.ics", "user", single).status)
+	require.Equal(t, http.StatusConflict, c.do(http.MethodPut, "/dav/calendars/user/events/retro.ics", "user",
+		strings.ReplaceAll(single, "UID:review", "UID:retro")).status, "the time is busy")
+
+	require.Equal(t, http.StatusBadRequest,
+		c.do(http.MethodPut, "/dav/calendars/user/events/other.ics", "user", single).status, "UID mismatch")
+	require.Equal(t, http.StatusBadRequest, c.do(http.MethodPut, path, "user", "BEGIN:VCALENDAR").status)
+	require.Equal(t, http.StatusForbidden,
+		c.do(http.MethodPut, "/dav/calendars/user/events/bad.ics", "user",
+			calendarObject("bad", "RRULE:FREQ=YEARLY", "END:VEVENT")).status)
+
+	calendar := c.do(http.MethodGet, "/dav/calendars/user/events/", "user", "")
+	require.Equal(t, http.StatusOK, calendar.status)
+	require.Equal(t, 2, strings.Count(calendar.body, "BEGIN:VEVENT"))
+
+	require.Equal(t, http.StatusPreconditionFailed,
+		c.do(http.MethodDelete, path, "user", "", "If-Match", etag).status)
+	require.Equal(t, http.StatusNoContent, c.do(http.MethodDelete, path, "user", "").status)
+	require.Equal(t, http.StatusNotFound, c.do(http.MethodDelete, path, "user", "").status)
+	require.Equal(t, http.StatusForbidden, c.do(http.MethodDelete, "/dav/calendars/user/events/", "user", "").status)
+}
+
+func TestReminders(t *testing.T) {
+	c := newClient(t)
+	path := "/dav/calendars/user/events/planning.ics"
+	alarm := func(trigger string, lines ...string) []string {
+		return append(append([]string{"BEGIN:VALARM", "ACTION:DISPLAY", "TRIGGER:" + trigger}, lines...), "END:VALARM")
+	}
+
+	lines := append(alarm("-P1D", "X-CALENDAR-CHANNEL:webhook"), alarm("-PT10M")...)
+	object := calendarObject("planning", append(lines, "END:VEVENT")...)
+	require.Equal(t, http.StatusCreated, c.do(http.MethodPut, path, "user", object).status)
+
+	r := c.do(http.MethodGet, path, "user", "")
+	require.Equal(t, 2, strings.Count(r.body, "BEGIN:VALARM\r\n"))
+	require.Contains(t, r.body, "TRIGGER:-P1D\r\nX-CALENDAR-CHANNEL:webhook\r\n")
+	require.Contains(t, r.body, "TRIGGER:-PT10M\r\nEND:VALARM\r\n")
+
+	// a client dropping the channels doesn't reset them
+	lines = append(alarm("-P1D"), alarm("-PT10M")...)
+	object = strings.ReplaceAll(calendarObject("planning", append(lines, "END:VEVENT")...), "Standup", "Planning")
+	require.Equal(t, http.StatusNoContent,
+		c.do(http.MethodPut, path, "user", object, "If-Match", r.header.Get("ETag")).status)
+
+	r = c.do(http.MethodGet, path, "user", "")
+	require.Contains(t, r.body, "SUMMARY:Planning\r\n")
+	require.Equal(t, 2, strings.Count(r.body, "BEGIN:VALARM\r\n"))
+	require.Contains(t, r.body, "TRIGGER:-P1D\r\nX-CALENDAR-CHANNEL:webhook\r\n")
+	require.Contains(t, r.body, "TRIGGER:-PT10M\r\nEND:VALARM\r\n")
+}
+
+func TestReports(t *testing.T) {
+	c := newClient(t)
+	calendar := "/dav/calendars/user/events/"
+
+	require.Equal(t, http.StatusCreated, c.do(http.MethodPut, calendar+"standup.ics", "user",
+		calendarObject("standup", "RRULE:FREQ=WEEKLY", "END:VEVENT")).status)
+	require.Equal(t, http.StatusCreated, c.do(http.MethodPut, calendar+"review%40example.com.ics", "user",
+		strings.ReplaceAll(calendarObject("review@example.com", "END:VEVENT"), "20240311", "20240313")).status)
+
+	query := func(start, end string) string {
+		return `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/><c:calendar-data/></d:prop>
+  <c:filter>
+    <c:comp-filter name="VCALENDAR">
+      <c:comp-filter name="VEVENT"><c:time-range start="` + start + `" end="` + end + `"/></c:comp-filter>
+    </c:comp-filter>
+  </c:filter>
+</c:calendar-query>`
+	}
+
+	// the fourth week holds an occurrence of the series only
+	r := c.do("REPORT", calendar, "user", query("20240401T000000Z", "20240408T000000Z"), "Depth", "1")
+	require.Equal(t, http.StatusMultiStatus, r.status)
+	require.Contains(t, r.body, "<d:href>/dav/calendars/user/events/standup.ics</d:href>")
+	require.NotContains(t, r.body, "review")
+	require.Contains(t, r.body, "<c:calendar-data>BEGIN:VCALENDAR&#xD;&#xA;")
+
+	r = c.do("REPORT", calendar, "user", query("20240313T000000Z", ""), "Depth", "1")
+	require.Contains(t, r.body, "standup.ics")
+	require.Contains(t, r.body, "review@example.com.ics")
+
+	r = c.do("REPORT", calendar, "user", query("20240301T000000Z", "20240311T000000Z"), "Depth", "1")
+	require.Equal(t, http.StatusMultiStatus, r.status)
+	require.NotContains(t, r.body, "<d:response>")
+
+	require.Equal(t, http.StatusBadRequest, c.do("REPORT", calendar, "user", query("yesterday", "")).status)
+
+	multiget := `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/></d:prop>
+  <d:href>/dav/calendars/user/events/review%40example.com.ics</d:href>
+  <d:href>/dav/calendars/user/events/missing.ics</d:href>
+</c:calendar-multiget>`
+	r = c.do("REPORT", calendar, "user", multiget)
+	require.Equal(t, http.StatusMultiStatus, r.status)
+	require.Contains(t, r.body, "<d:getetag>&#34;")
+	require.NotContains(t, r.body, "calendar-data")
+	require.Contains(t, r.body,
+		"<d:href>/dav/calendars/user/events/missing.ics</d:href><d:status>HTTP/1.1 404 Not Found</d:status>")
+
+	sync := `<d:sync-collection xmlns:d="DAV:"><d:sync-token/></d:sync-collection>`
+	require.Equal(t, http.StatusForbidden, c.do("REPORT", calendar, "user", sync).status)
+	require.Equal(t, http.StatusForbidden, c.do("REPORT", "/dav/", "user", multiget).status)
+}
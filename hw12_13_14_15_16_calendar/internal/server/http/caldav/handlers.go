@@ -0,0 +1,390 @@
+package caldav
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/ical"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+)
+
+var (
+	propCalendarData = xml.Name{Space: nsCalDAV, Local: "calendar-data"}
+	propGetETag      = xml.Name{Space: nsDAV, Local: "getetag"}
+)
+
+// propfind reports properties of the target and, if Depth is not 0, of its members.
+func (h *Handler) propfind(w http.ResponseWriter, r *http.Request, t target) {
+	var request propfindRequest
+	if _, err := decodeXML(r, w, &request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	names := request.Prop.names()
+	namesOnly := request.PropName != nil
+
+	responses, err := h.resources(r, t, r.Header.Get("Depth") != "0")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if responses == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	m := newMultistatus()
+	for _, response := range responses {
+		m.add(response, names, namesOnly)
+	}
+	m.write(w)
+}
+
+// resources returns the target along with its members, if they are requested,
+// or nil if the target doesn't exist.
+func (h *Handler) resources(r *http.Request, t target, members bool) ([]response, error) {
+	userID := t.userID
+	common := properties{
+		{Space: nsDAV, Local: "current-user-principal"}: href(h.principalHref(userID)),
+	}
+	with := func(props properties) properties {
+		for name, value := range common {
+			props[name] = value
+		}
+		return props
+	}
+
+	switch t.kind {
+	case kindRoot:
+		return []response{{href: h.href(t), props: with(properties{
+			{Space: nsDAV, Local: "resourcetype"}: "<d:collection/>",
+		})}}, nil
+
+	case kindPrincipal:
+		return []response{{href: h.href(t), props: with(properties{
+			{Space: nsDAV, Local: "resourcetype"}:         "<d:collection/><d:principal/>",
+			{Space: nsDAV, Local: "displayname"}:          escape(userID),
+			{Space: nsDAV, Local: "principal-URL"}:        href(h.principalHref(userID)),
+			{Space: nsCalDAV, Local: "calendar-home-set"}: href(h.homeHref(userID)),
+		})}}, nil
+
+	case kindHome:
+		responses := []response{{href: h.href(t), props: with(properties{
+			{Space: nsDAV, Local: "resourcetype"}: "<d:collection/>",
+		})}}
+		if !members {
+			return responses, nil
+		}
+		calendar, err := h.resources(r, target{kind: kindCalendar, userID: userID}, false)
+		return append(responses, calendar...), err
+
+	case kindCalendar:
+		objects, err := h.objects(r.Context(), userID)
+		if err != nil {
+			return nil, err
+		}
+		responses := []response{{href: h.href(t), props: with(properties{
+			{Space: nsDAV, Local: "resourcetype"}: "<d:collection/><c:calendar/>",
+			{Space: nsDAV, Local: "displayname"}:  "Calendar",
+			{Space: nsDAV, Local: "owner"}:        href(h.principalHref(userID)),
+			{Space: nsDAV, Local: "current-user-privilege-set"}: "<d:privilege><d:read/></d:privilege>" +
+				"<d:privilege><d:write/></d:privilege><d:privilege><d:write-content/></d:privilege>",
+			{Space: nsDAV, Local: "supported-report-set"}: "<d:supported-report><d:report><c:calendar-query/>" +
+				"</d:report></d:supported-report><d:supported-report><d:report><c:calendar-multiget/>" +
+				"</d:report></d:supported-report>",
+			{Space: nsCalDAV, Local: "supported-calendar-component-set"}: `<c:comp name="VEVENT"/>`,
+			{Space: nsCalendarServer, Local: "getctag"}:                  escape(ctag(objects)),
+		})}}
+		if !members {
+			return responses, nil
+		}
+		for _, o := range objects {
+			responses = append(responses, h.objectResponse(t.userID, o, false))
+		}
+		return responses, nil
+
+	default:
+		o, err := h.object(r.Context(), userID, t.id)
+		if err != nil || o == nil {
+			return nil, err
+		}
+		return []response{h.objectResponse(userID, o, false)}, nil
+	}
+}
+
+// objectResponse returns properties of the resource. Calendar data is included only
+// if it's asked for, as it's never reported among all the properties.
+func (h *Handler) objectResponse(userID string, o *object, data bool) response {
+	props := properties{
+		{Space: nsDAV, Local: "resourcetype"}:           "",
+		{Space: nsDAV, Local: "current-user-principal"}: href(h.principalHref(userID)),
+		{Space: nsDAV, Local: "getcontenttype"}:         "text/calendar; charset=utf-8; component=vevent",
+		propGetETag:                                     escape(o.etag),
+	}
+	if data {
+		props[propCalendarData] = escape(string(o.data))
+	}
+	return response{href: h.objectHref(userID, o.id), props: props}
+}
+
+// report handles calendar-query and calendar-multiget reports on the calendar.
+func (h *Handler) report(w http.ResponseWriter, r *http.Request, t target) {
+	if t.kind != kindCalendar {
+		http.Error(w, "reports are supported only by the calendar", http.StatusForbidden)
+		return
+	}
+
+	var request reportRequest
+	if ok, err := decodeXML(r, w, &request); err != nil || !ok {
+		http.Error(w, fmt.Sprintf("%s: report is required", errBadRequest), http.StatusBadRequest)
+		return
+	}
+	names := request.Prop.names()
+	if names == nil {
+		names = []xml.Name{propGetETag}
+	}
+
+	objects, err := h.objects(r.Context(), t.userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var responses []response
+	switch request.XMLName {
+	case xml.Name{Space: nsCalDAV, Local: "calendar-query"}:
+		responses, err = h.query(&request, t.userID, objects)
+	case xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}:
+		responses = h.multiget(&request, t.userID, objects)
+	default:
+		http.Error(w, fmt.Sprintf("report %s is not supported", request.XMLName.Local), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, errBadRequest) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to query calendar", "error", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	m := newMultistatus()
+	for _, response := range responses {
+		m.add(response, names, false)
+	}
+	m.write(w)
+}
+
+func (h *Handler) query(request *reportRequest, userID string, objects []*object) ([]response, error) {
+	filter, err := request.eventFilter()
+	if err != nil || filter.none {
+		return nil, err
+	}
+
+	var responses []response
+	for _, o := range objects {
+		ok, err := o.intersects(filter.from, filter.to)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			responses = append(responses, h.objectResponse(userID, o, true))
+		}
+	}
+	return responses, nil
+}
+
+func (h *Handler) multiget(request *reportRequest, userID string, objects []*object) []response {
+	byID := make(map[string]*object, len(objects))
+	for _, o := range objects {
+		byID[o.id] = o
+	}
+
+	responses := make([]response, 0, len(request.Hrefs))
+	for _, ref := range request.Hrefs {
+		var o *object
+		if u, err := url.Parse(strings.TrimSpace(ref)); err == nil {
+			if t, ok := h.parsePath(u.Path); ok && t.kind == kindObject && t.userID == userID {
+				o = byID[t.id]
+			}
+		}
+
+		if o == nil {
+			responses = append(responses, response{href: ref})
+			continue
+		}
+		responses = append(responses, h.objectResponse(userID, o, true))
+	}
+	return responses
+}
+
+// get returns the resource or, for the calendar, all the events as a single calendar.
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, t target) {
+	var data []byte
+	switch t.kind {
+	case kindCalendar:
+		events, err := h.app.ExportEvents(r.Context(), t.userID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		var b strings.Builder
+		if err := ical.Encode(&b, events, objectStamp); err != nil {
+			h.logger.ErrorContext(r.Context(), "failed to encode calendar", "error", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		data = []byte(b.String())
+
+	case kindObject:
+		o, err := h.object(r.Context(), t.userID, t.id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if o == nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", o.etag)
+		data = o.data
+
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	_, _ = w.Write(data)
+}
+
+// put creates or replaces the resource. Its UID must match the name of the resource.
+// Overrides missing in the new representation are deleted. As the stored representation
+// is never the same as the one sent, no ETag is returned and clients have to get it again.
+func (h *Handler) put(w http.ResponseWriter, r *http.Request, t target) {
+	if t.kind != kindObject {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	events, err := ical.Decode(http.MaxBytesReader(w, r.Body, maxObjectSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := checkObject(t.id, events); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	existing, err := h.object(r.Context(), t.userID, t.id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !checkPreconditions(w, r, existing) {
+		return
+	}
+
+	result, err := h.app.ImportEvents(r.Context(), t.userID, events)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(result.Failed) > 0 {
+		writeError(w, result.Failed[0].Err)
+		return
+	}
+
+	if existing == nil {
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	for _, old := range existing.events {
+		if old.SeriesID == "" || containsOverride(events, old.RecurrenceID) {
+			continue
+		}
+		if err := h.app.DeleteEvent(r.Context(), t.userID, old.ID); err != nil &&
+			!errors.Is(err, storage.ErrEventNotFound) {
+			writeError(w, err)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request, t target) {
+	if t.kind != kindObject {
+		http.Error(w, "only calendar object resources can be deleted", http.StatusForbidden)
+		return
+	}
+
+	if r.Header.Get("If-Match") != "" {
+		existing, err := h.object(r.Context(), t.userID, t.id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if !checkPreconditions(w, r, existing) {
+			return
+		}
+	}
+
+	if err := h.app.DeleteEvent(r.Context(), t.userID, t.id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkPreconditions checks If-Match and If-None-Match headers against the current state
+// of the resource, which is nil if it doesn't exist. It responds 412 if they don't hold.
+func checkPreconditions(w http.ResponseWriter, r *http.Request, existing *object) bool {
+	if match := r.Header.Get("If-Match"); match != "" && !matchesETag(match, existing) {
+		http.Error(w, "resource has been changed", http.StatusPreconditionFailed)
+		return false
+	}
+	if noneMatch := r.Header.Get("If-None-Match"); noneMatch != "" && matchesETag(noneMatch, existing) {
+		http.Error(w, "resource already exists", http.StatusPreconditionFailed)
+		return false
+	}
+	return true
+}
+
+// checkObject checks that the events make up a single resource with the given ID:
+// an event or a series possibly with overrides.
+func checkObject(id string, events []storage.Event) error {
+	var masters int
+	for _, event := range events {
+		uid := event.ID
+		if event.SeriesID != "" {
+			uid = event.SeriesID
+		} else {
+			masters++
+		}
+		if uid != id {
+			return fmt.Errorf("%w: UID %q doesn't match the resource name", errBadRequest, uid)
+		}
+	}
+	if masters != 1 {
+		return fmt.Errorf("%w: resource must contain exactly one event without RECURRENCE-ID", errBadRequest)
+	}
+	return nil
+}
+
+func containsOverride(events []storage.Event, recurrenceID time.Time) bool {
+	for _, event := range events {
+		if event.SeriesID != "" && event.RecurrenceID.Equal(recurrenceID) {
+			return true
+		}
+	}
+	return false
+}
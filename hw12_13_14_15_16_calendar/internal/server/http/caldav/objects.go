@@ -0,0 +1,173 @@
+package caldav
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/ical"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+)
+
+// objectStamp is DTSTAMP of every resource. For a stored object it means the time of
+// the last modification, which isn't tracked, so a constant keeps representations
+// of unchanged objects and their ETags the same.
+var objectStamp = time.Unix(0, 0).UTC()
+
+// object is a calendar object resource: an event or a series with its overrides.
+type object struct {
+	id     string
+	events []storage.Event
+	data   []byte
+	etag   string
+}
+
+func newObject(id string, events []storage.Event) (*object, error) {
+	var buf bytes.Buffer
+	if err := ical.Encode(&buf, events, objectStamp); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	return &object{
+		id:     id,
+		events: events,
+		data:   buf.Bytes(),
+		etag:   `"` + hex.EncodeToString(sum[:16]) + `"`,
+	}, nil
+}
+
+// objects returns all calendar object resources of the user ordered by start time.
+func (h *Handler) objects(ctx context.Context, userID string) ([]*object, error) {
+	events, err := h.app.ExportEvents(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	groups := make(map[string][]storage.Event)
+	for _, event := range events {
+		id := event.ID
+		if event.SeriesID != "" {
+			id = event.SeriesID
+		}
+		if _, ok := groups[id]; !ok {
+			ids = append(ids, id)
+		}
+		groups[id] = append(groups[id], event)
+	}
+
+	objects := make([]*object, 0, len(ids))
+	for _, id := range ids {
+		group := groups[id]
+		// the series goes first, overrides follow it
+		slices.SortStableFunc(group, func(a, b storage.Event) int {
+			return strings.Compare(a.SeriesID, b.SeriesID)
+		})
+		o, err := newObject(id, group)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, o)
+	}
+	return objects, nil
+}
+
+// object returns the resource with the given ID or nil if there is none.
+func (h *Handler) object(ctx context.Context, userID, id string) (*object, error) {
+	objects, err := h.objects(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range objects {
+		if o.id == id {
+			return o, nil
+		}
+	}
+	return nil, nil
+}
+
+// ctag changes whenever any resource of the calendar does.
+func ctag(objects []*object) string {
+	hash := sha256.New()
+	for _, o := range objects {
+		hash.Write([]byte(o.id))
+		hash.Write([]byte(o.etag))
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// intersects reports whether any event or occurrence of the object intersects [from, to).
+func (o *object) intersects(from, to time.Time) (bool, error) {
+	var series []storage.Event
+	overrides := make(storage.Overrides)
+	for _, event := range o.events {
+		switch {
+		case event.Recurring():
+			series = append(series, event)
+		case event.SeriesID != "":
+			overrides[event.SeriesID] = append(overrides[event.SeriesID], event.RecurrenceID)
+			fallthrough
+		default:
+			if event.In(from, to) {
+				return true, nil
+			}
+		}
+	}
+
+	for _, s := range series {
+		end, finite, err := storage.LastEnd(s)
+		if err != nil {
+			return false, err
+		}
+		until := to
+		if finite {
+			until = minTime(until, end)
+		}
+
+		// a year at a time, so that an open time range doesn't expand an infinite series as a whole
+		for start := maxTime(from, s.StartAt); start.Before(until); start = start.AddDate(1, 0, 0) {
+			occurrences, err := storage.Occurrences(s, overrides[s.ID], start, minTime(start.AddDate(1, 0, 0), until))
+			if err != nil {
+				return false, err
+			}
+			if len(occurrences) > 0 {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// matchesETag reports whether the If-Match or If-None-Match header value matches the ETag
+// of the object, which is nil if the resource doesn't exist.
+func matchesETag(header string, o *object) bool {
+	if o == nil {
+		return false
+	}
+	for _, etag := range strings.Split(header, ",") {
+		etag = strings.TrimSpace(etag)
+		if etag == "*" || etag == o.etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,251 @@
+package caldav
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	nsDAV            = "DAV:"
+	nsCalDAV         = "urn:ietf:params:xml:ns:caldav"
+	nsCalendarServer = "http://calendarserver.org/ns/"
+
+	timeRangeLayout = "20060102T150405Z"
+)
+
+var errBadRequest = errors.New("bad request")
+
+var prefixes = map[string]string{
+	nsDAV:            "d",
+	nsCalDAV:         "c",
+	nsCalendarServer: "cs",
+}
+
+// propNames is a list of property names, such as the content of DAV:prop element of a request.
+type propNames struct {
+	Names []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+func (p *propNames) names() []xml.Name {
+	if p == nil {
+		return nil
+	}
+	names := make([]xml.Name, 0, len(p.Names))
+	for _, n := range p.Names {
+		names = append(names, n.XMLName)
+	}
+	return names
+}
+
+type propfindRequest struct {
+	XMLName  xml.Name   `xml:"DAV: propfind"`
+	PropName *struct{}  `xml:"DAV: propname"`
+	Prop     *propNames `xml:"DAV: prop"`
+}
+
+type timeRange struct {
+	Start string `xml:"start,attr"`
+	End   string `xml:"end,attr"`
+}
+
+type compFilter struct {
+	Name        string       `xml:"name,attr"`
+	TimeRange   *timeRange   `xml:"urn:ietf:params:xml:ns:caldav time-range"`
+	CompFilters []compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+// reportRequest is either calendar-query or calendar-multiget report.
+type reportRequest struct {
+	XMLName xml.Name
+	Prop    *propNames `xml:"DAV: prop"`
+	Hrefs   []string   `xml:"DAV: href"`
+	Filter  *struct {
+		CompFilter compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+	} `xml:"urn:ietf:params:xml:ns:caldav filter"`
+}
+
+// eventFilter describes which events a calendar-query selects.
+type eventFilter struct {
+	none     bool
+	from, to time.Time
+}
+
+// eventFilter supports the filters clients use: VCALENDAR with an optional VEVENT filter
+// having an optional time range. Filters of other components select nothing.
+func (r *reportRequest) eventFilter() (eventFilter, error) {
+	filter := eventFilter{to: time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)}
+	if r.Filter == nil {
+		return filter, nil
+	}
+
+	calendar := r.Filter.CompFilter
+	if calendar.Name != "VCALENDAR" {
+		return eventFilter{none: true}, nil
+	}
+	if len(calendar.CompFilters) == 0 {
+		return filter, nil
+	}
+	i := slices.IndexFunc(calendar.CompFilters, func(f compFilter) bool { return f.Name == "VEVENT" })
+	if i < 0 {
+		return eventFilter{none: true}, nil
+	}
+
+	if tr := calendar.CompFilters[i].TimeRange; tr != nil {
+		var err error
+		if tr.Start != "" {
+			if filter.from, err = time.Parse(timeRangeLayout, tr.Start); err != nil {
+				return eventFilter{}, fmt.Errorf("%w: malformed time-range start %q", errBadRequest, tr.Start)
+			}
+		}
+		if tr.End != "" {
+			if filter.to, err = time.Parse(timeRangeLayout, tr.End); err != nil {
+				return eventFilter{}, fmt.Errorf("%w: malformed time-range end %q", errBadRequest, tr.End)
+			}
+		}
+	}
+	return filter, nil
+}
+
+// decodeXML decodes the request body into v. It reports false if the body is empty.
+func decodeXML(r *http.Request, w http.ResponseWriter, v any) (bool, error) {
+	err := xml.NewDecoder(http.MaxBytesReader(w, r.Body, maxObjectSize)).Decode(v)
+	if errors.Is(err, io.EOF) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("%w: invalid XML body: %w", errBadRequest, err)
+	}
+	return true, nil
+}
+
+// properties maps property names to their values, which are XML fragments.
+type properties map[xml.Name]string
+
+// response is a response element of a multistatus.
+type response struct {
+	href string
+	// props is nil for a missing resource.
+	props properties
+}
+
+// multistatus builds the body of a multistatus response.
+type multistatus struct {
+	b strings.Builder
+}
+
+func newMultistatus() *multistatus {
+	m := &multistatus{}
+	m.b.WriteString(xml.Header)
+	m.b.WriteString(`<d:multistatus`)
+	for _, ns := range []string{nsDAV, nsCalDAV, nsCalendarServer} {
+		fmt.Fprintf(&m.b, ` xmlns:%s="%s"`, prefixes[ns], ns)
+	}
+	m.b.WriteString(">")
+	return m
+}
+
+// add reports the requested properties of the resource along with their values or as missing.
+// If names are nil, all the properties are reported, and if namesOnly is set, values are omitted.
+func (m *multistatus) add(r response, names []xml.Name, namesOnly bool) {
+	m.b.WriteString("<d:response><d:href>")
+	m.text(r.href)
+	m.b.WriteString("</d:href>")
+
+	if r.props == nil {
+		m.status(http.StatusNotFound)
+		m.b.WriteString("</d:response>")
+		return
+	}
+
+	if names == nil {
+		for name := range r.props {
+			names = append(names, name)
+		}
+		slices.SortFunc(names, func(a, b xml.Name) int {
+			return strings.Compare(a.Space+" "+a.Local, b.Space+" "+b.Local)
+		})
+	}
+
+	var missing []xml.Name
+	m.b.WriteString("<d:propstat><d:prop>")
+	for _, name := range names {
+		value, ok := r.props[name]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		if namesOnly {
+			value = ""
+		}
+		m.element(name, value)
+	}
+	m.b.WriteString("</d:prop>")
+	m.status(http.StatusOK)
+	m.b.WriteString("</d:propstat>")
+
+	if len(missing) > 0 {
+		m.b.WriteString("<d:propstat><d:prop>")
+		for _, name := range missing {
+			m.element(name, "")
+		}
+		m.b.WriteString("</d:prop>")
+		m.status(http.StatusNotFound)
+		m.b.WriteString("</d:propstat>")
+	}
+
+	m.b.WriteString("</d:response>")
+}
+
+func (m *multistatus) status(code int) {
+	fmt.Fprintf(&m.b, "<d:status>HTTP/1.1 %d %s</d:status>", code, http.StatusText(code))
+}
+
+func (m *multistatus) element(name xml.Name, value string) {
+	prefix, ok := prefixes[name.Space]
+	tag := prefix + ":" + name.Local
+	if !ok {
+		tag = "x:" + name.Local
+	}
+
+	m.b.WriteString("<" + tag)
+	if !ok {
+		m.b.WriteString(` xmlns:x="`)
+		m.text(name.Space)
+		m.b.WriteString(`"`)
+	}
+	if value == "" {
+		m.b.WriteString("/>")
+		return
+	}
+	m.b.WriteString(">" + value + "</" + tag + ">")
+}
+
+func (m *multistatus) text(s string) {
+	m.b.WriteString(escape(s))
+}
+
+func (m *multistatus) write(w http.ResponseWriter) {
+	m.b.WriteString("</d:multistatus>")
+	w.Header().Set("Content-Type", `application/xml; charset="utf-8"`)
+	w.WriteHeader(http.StatusMultiStatus)
+	_, _ = io.WriteString(w, m.b.String())
+}
+
+// escape escapes the text to be used as a property value.
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func href(s string) string {
+	return "<d:href>" + escape(s) + "</d:href>"
+}
@@ -0,0 +1,132 @@
+package internalhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+)
+
+// duration is represented in JSON as a string like "1h30m".
+type duration time.Duration
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string: %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+type eventRequest struct {
+	Title        string      `json:"title"`
+	StartAt      time.Time   `json:"startAt"`
+	Duration     duration    `json:"duration"`
+	Description  string      `json:"description"`
+	NotifyBefore duration    `json:"notifyBefore"`
+	RRule        string      `json:"rrule"`
+	ExDates      []time.Time `json:"exdates"`
+	SeriesID     string      `json:"seriesId"`
+	RecurrenceID *time.Time  `json:"recurrenceId"`
+	TimeZone     string      `json:"timeZone"`
+	Reminders    []reminder  `json:"reminders"`
+}
+
+// reminder asks to notify about the event the given time before it, through the channel if it's set.
+type reminder struct {
+	Before  duration `json:"before"`
+	Channel string   `json:"channel,omitempty"`
+}
+
+func (r eventRequest) toEvent() storage.Event {
+	event := storage.Event{
+		Title:        r.Title,
+		StartAt:      r.StartAt,
+		Duration:     time.Duration(r.Duration),
+		Description:  r.Description,
+		NotifyBefore: time.Duration(r.NotifyBefore),
+		RRule:        r.RRule,
+		ExDates:      r.ExDates,
+		SeriesID:     r.SeriesID,
+		TimeZone:     r.TimeZone,
+	}
+	if r.RecurrenceID != nil {
+		event.RecurrenceID = *r.RecurrenceID
+	}
+	for _, reminder := range r.Reminders {
+		event.Reminders = append(event.Reminders,
+			storage.Reminder{Before: time.Duration(reminder.Before), Channel: reminder.Channel})
+	}
+	return event
+}
+
+type eventResponse struct {
+	ID           string      `json:"id"`
+	UserID       string      `json:"userId"`
+	Title        string      `json:"title"`
+	StartAt      time.Time   `json:"startAt"`
+	EndAt        time.Time   `json:"endAt"`
+	Duration     duration    `json:"duration"`
+	Description  string      `json:"description"`
+	NotifyBefore duration    `json:"notifyBefore"`
+	RRule        string      `json:"rrule,omitempty"`
+	ExDates      []time.Time `json:"exdates,omitempty"`
+	SeriesID     string      `json:"seriesId,omitempty"`
+	// RecurrenceID is the original start of an occurrence of a series.
+	RecurrenceID *time.Time `json:"recurrenceId,omitempty"`
+	TimeZone     string     `json:"timeZone,omitempty"`
+	// Version is incremented by every update, it's returned as ETag of a single event as well.
+	Version int64 `json:"version"`
+	// Attendees are managed by the attendee endpoints, as the users other than the owner change them.
+	Attendees []attendeeResponse `json:"attendees,omitempty"`
+	Reminders []reminder         `json:"reminders,omitempty"`
+}
+
+type attendeeResponse struct {
+	UserID string                 `json:"userId"`
+	Status storage.AttendeeStatus `json:"status"`
+}
+
+func newEventResponse(event storage.Event) eventResponse {
+	var recurrenceID *time.Time
+	if !event.RecurrenceID.IsZero() {
+		recurrenceID = &event.RecurrenceID
+	}
+	var attendees []attendeeResponse
+	for _, attendee := range event.Attendees {
+		attendees = append(attendees, attendeeResponse{UserID: attendee.UserID, Status: attendee.Status})
+	}
+	var reminders []reminder
+	for _, r := range event.Reminders {
+		reminders = append(reminders, reminder{Before: duration(r.Before), Channel: r.Channel})
+	}
+
+	return eventResponse{
+		ID:           event.ID,
+		UserID:       event.UserID,
+		Title:        event.Title,
+		StartAt:      event.StartAt,
+		EndAt:        event.EndAt(),
+		Duration:     duration(event.Duration),
+		Description:  event.Description,
+		NotifyBefore: duration(event.NotifyBefore),
+		RRule:        event.RRule,
+		ExDates:      event.ExDates,
+		SeriesID:     event.SeriesID,
+		RecurrenceID: recurrenceID,
+		TimeZone:     event.TimeZone,
+		Version:      event.Version,
+		Attendees:    attendees,
+		Reminders:    reminders,
+	}
+}
@@ -0,0 +1,168 @@
+package internalhttp
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/app"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+)
+
+const clockLayout = "15:04"
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+type intervalResponse struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type userBusyResponse struct {
+	UserID string             `json:"userId"`
+	Busy   []intervalResponse `json:"busy"`
+}
+
+// freeBusy handles GET /freebusy?users=alice,bob&from=2006-01-02&to=2006-01-03&tz=Europe/Moscow.
+// from and to are dates or moments of time in RFC 3339 format, dates are taken in the time zone
+// given by tz, UTC by default.
+func (s *Server) freeBusy(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	from, to, err := parsePeriod(query)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	users, err := s.app.FreeBusy(r.Context(), r.Header.Get(userIDHeader), splitList(query.Get("users")), from, to)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	response := make([]userBusyResponse, 0, len(users))
+	for _, user := range users {
+		response = append(response, userBusyResponse{UserID: user.UserID, Busy: newIntervalsResponse(user.Busy)})
+	}
+	s.writeJSON(w, r, http.StatusOK, response)
+}
+
+// findSlots handles GET /slots?users=alice,bob&duration=1h&from=2006-01-02&to=2006-01-09
+// &tz=Europe/Moscow&workStart=09:00&workEnd=18:00&days=mon,tue,wed,thu,fri&limit=10.
+// Working hours and days are taken in the time zone given by tz, all day and every day by default.
+func (s *Server) findSlots(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	slotQuery, err := parseSlotQuery(query)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	slots, err := s.app.FindSlots(r.Context(), r.Header.Get(userIDHeader), slotQuery)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, r, http.StatusOK, newIntervalsResponse(slots))
+}
+
+func parseSlotQuery(query url.Values) (app.SlotQuery, error) {
+	from, to, err := parsePeriod(query)
+	if err != nil {
+		return app.SlotQuery{}, err
+	}
+	result := app.SlotQuery{
+		UserIDs:  splitList(query.Get("users")),
+		From:     from,
+		To:       to,
+		Location: from.Location(),
+	}
+
+	if result.Duration, err = time.ParseDuration(query.Get("duration")); err != nil {
+		return app.SlotQuery{}, fmt.Errorf("%w: duration must be like 1h30m", errBadRequest)
+	}
+	if result.WorkStart, err = parseClock("workStart", query.Get("workStart")); err != nil {
+		return app.SlotQuery{}, err
+	}
+	if result.WorkEnd, err = parseClock("workEnd", query.Get("workEnd")); err != nil {
+		return app.SlotQuery{}, err
+	}
+	for _, day := range splitList(query.Get("days")) {
+		weekday, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return app.SlotQuery{}, fmt.Errorf("%w: days must be a list of mon, tue, wed, thu, fri, sat, sun",
+				errBadRequest)
+		}
+		result.Weekdays = append(result.Weekdays, weekday)
+	}
+	if limit := query.Get("limit"); limit != "" {
+		if result.Limit, err = strconv.Atoi(limit); err != nil {
+			return app.SlotQuery{}, fmt.Errorf("%w: limit must be a number", errBadRequest)
+		}
+	}
+	return result, nil
+}
+
+// parsePeriod parses from and to query parameters in the time zone given by tz.
+func parsePeriod(query url.Values) (from, to time.Time, err error) {
+	loc, err := storage.LoadLocation(query.Get("tz"))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: unknown time zone %q", errBadRequest, query.Get("tz"))
+	}
+	if from, err = parseDate("from", query.Get("from"), loc); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if to, err = parseDate("to", query.Get("to"), loc); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return from, to, nil
+}
+
+// parseClock parses the wall clock time like 09:30 as the time since midnight,
+// 24:00 means the end of the day.
+func parseClock(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if value == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	clock, err := time.Parse(clockLayout, value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s must be in %s format", errBadRequest, name, clockLayout)
+	}
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
+
+// splitList splits the comma separated list skipping empty values.
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func newIntervalsResponse(intervals []app.Interval) []intervalResponse {
+	response := make([]intervalResponse, 0, len(intervals))
+	for _, interval := range intervals {
+		response = append(response, intervalResponse{Start: interval.Start, End: interval.End})
+	}
+	return response
+}
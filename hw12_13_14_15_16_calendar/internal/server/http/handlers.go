@@ -0,0 +1,228 @@
+package internalhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/app"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	userIDHeader = "X-User-ID"
+	maxBodySize  = 1 << 20
+	dateLayout   = time.DateOnly
+)
+
+var (
+	errBadRequest         = errors.New("bad request")
+	errTooManyRequests    = errors.New("too many requests")
+	errPreconditionFailed = errors.New("precondition failed")
+)
+
+func (s *Server) createEvent(w http.ResponseWriter, r *http.Request) {
+	var request eventRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	event, err := s.app.CreateEvent(r.Context(), r.Header.Get(userIDHeader), request.toEvent())
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", eventETag(event))
+	s.writeJSON(w, r, http.StatusCreated, newEventResponse(event))
+}
+
+// updateEvent replaces the event. If-Match header with the ETag of the event makes the update
+// fail with 412 if the event has been changed since it was read.
+func (s *Server) updateEvent(w http.ResponseWriter, r *http.Request) {
+	var request eventRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	version, err := parseIfMatch(r.Header.Get("If-Match"))
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	event := request.toEvent()
+	event.Version = version
+	event, err = s.app.UpdateEvent(r.Context(), r.Header.Get(userIDHeader), r.PathValue("id"), event)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", eventETag(event))
+	s.writeJSON(w, r, http.StatusOK, newEventResponse(event))
+}
+
+func (s *Server) deleteEvent(w http.ResponseWriter, r *http.Request) {
+	if err := s.app.DeleteEvent(r.Context(), r.Header.Get(userIDHeader), r.PathValue("id")); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getEvent(w http.ResponseWriter, r *http.Request) {
+	event, err := s.app.GetEvent(r.Context(), r.Header.Get(userIDHeader), r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", eventETag(event))
+	s.writeJSON(w, r, http.StatusOK, newEventResponse(event))
+}
+
+// eventETag returns the entity tag of the event, which is its version.
+func eventETag(event storage.Event) string {
+	return `"` + strconv.FormatInt(event.Version, 10) + `"`
+}
+
+// parseIfMatch returns the version of the event If-Match header value requires, zero if any
+// version will do. Only a single strong entity tag or "*" is supported, anything else, e.g. a weak tag
+// or a list of them, is rejected as a bad request. A tag which isn't a version fails the precondition.
+func parseIfMatch(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+
+	unquoted, ok := strings.CutPrefix(value, `"`)
+	if ok {
+		unquoted, ok = strings.CutSuffix(unquoted, `"`)
+	}
+	if !ok || strings.ContainsFunc(unquoted, func(r rune) bool { return r == '"' || r <= ' ' || r == 0x7f }) {
+		return 0, fmt.Errorf("%w: If-Match must be a single strong ETag or *", errBadRequest)
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("%w: If-Match must be the ETag of the event", errPreconditionFailed)
+	}
+	return version, nil
+}
+
+// listEvents handles GET /events?period=day|week|month&date=2006-01-02&tz=Europe/Moscow.
+// Boundaries of the period are calculated in the time zone given by tz, UTC by default.
+func (s *Server) listEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	loc, err := storage.LoadLocation(query.Get("tz"))
+	if err != nil {
+		s.writeError(w, r, fmt.Errorf("%w: unknown time zone %q", errBadRequest, query.Get("tz")))
+		return
+	}
+	date, err := parseDate("date", query.Get("date"), loc)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	var events []storage.Event
+	userID := r.Header.Get(userIDHeader)
+	switch period := query.Get("period"); period {
+	case "day":
+		events, err = s.app.ListEventsForDay(r.Context(), userID, date)
+	case "week":
+		events, err = s.app.ListEventsForWeek(r.Context(), userID, date)
+	case "month":
+		events, err = s.app.ListEventsForMonth(r.Context(), userID, date)
+	default:
+		err = fmt.Errorf("%w: period must be one of day, week, month", errBadRequest)
+	}
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	response := make([]eventResponse, 0, len(events))
+	for _, event := range events {
+		response = append(response, newEventResponse(event))
+	}
+	s.writeJSON(w, r, http.StatusOK, response)
+}
+
+// parseDate parses the query parameter with the given name as a date in the location. A moment
+// of time given in RFC 3339 format means the date it falls on in the location.
+func parseDate(name, value string, loc *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("%w: %s is required", errBadRequest, name)
+	}
+	if date, err := time.ParseInLocation(dateLayout, value, loc); err == nil {
+		return date, nil
+	}
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date.In(loc), nil
+	}
+	return time.Time{}, fmt.Errorf("%w: %s must be in %s or RFC 3339 format", errBadRequest, name, dateLayout)
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: invalid JSON body: %w", errBadRequest, err)
+	}
+	return nil
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to write response", "error", err)
+	}
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status := errorStatus(err)
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		// details of internal errors are logged by the application
+		message = http.StatusText(status)
+	}
+	s.writeJSON(w, r, status, errorResponse{Error: message})
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, errBadRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, app.ErrEmptyUserID):
+		return http.StatusUnauthorized
+	case errors.Is(err, storage.ErrEventNotFound), errors.Is(err, storage.ErrWebhookNotFound),
+		errors.Is(err, storage.ErrRevisionNotFound), errors.Is(err, storage.ErrAttendeeNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrDateBusy), errors.Is(err, storage.ErrEventAlreadyExists):
+		return http.StatusConflict
+	case errors.Is(err, app.ErrInvalidQuery):
+		return http.StatusBadRequest
+	case errors.Is(err, errTooManyRequests):
+		return http.StatusTooManyRequests
+	case errors.Is(err, storage.ErrVersionConflict), errors.Is(err, errPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, app.ErrInvalidEvent), errors.Is(err, app.ErrInvalidWebhook),
+		errors.Is(err, app.ErrInvalidAttendee):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,73 @@
+package internalhttp
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+)
+
+type changeResponse struct {
+	Revision  int64                 `json:"revision"`
+	ActorID   string                `json:"actorId"`
+	Action    storage.Action        `json:"action"`
+	ChangedAt time.Time             `json:"changedAt"`
+	Fields    []fieldChangeResponse `json:"fields"`
+	// Event is the state of the event after the change, or before it for a deletion.
+	Event        eventResponse `json:"event"`
+	RestoredFrom int64         `json:"restoredFrom,omitempty"`
+}
+
+type fieldChangeResponse struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// eventHistory handles GET /events/{id}/history, the earliest change goes first.
+func (s *Server) eventHistory(w http.ResponseWriter, r *http.Request) {
+	changes, err := s.app.EventHistory(r.Context(), r.Header.Get(userIDHeader), r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	response := make([]changeResponse, 0, len(changes))
+	for _, change := range changes {
+		fields := make([]fieldChangeResponse, 0, len(change.Fields))
+		for _, f := range change.Fields {
+			fields = append(fields, fieldChangeResponse{Field: f.Field, Before: f.Before, After: f.After})
+		}
+		response = append(response, changeResponse{
+			Revision:     change.Revision,
+			ActorID:      change.ActorID,
+			Action:       change.Action,
+			ChangedAt:    change.ChangedAt,
+			Fields:       fields,
+			Event:        newEventResponse(change.Event),
+			RestoredFrom: change.RestoredFrom,
+		})
+	}
+	s.writeJSON(w, r, http.StatusOK, response)
+}
+
+// restoreEvent handles POST /events/{id}/history/{revision}/restore, bringing the event back
+// to its state after the revision. A deleted event is created again.
+func (s *Server) restoreEvent(w http.ResponseWriter, r *http.Request) {
+	revision, err := strconv.ParseInt(r.PathValue("revision"), 10, 64)
+	if err != nil {
+		s.writeError(w, r, fmt.Errorf("%w: revision must be a number", errBadRequest))
+		return
+	}
+
+	event, err := s.app.RestoreEvent(r.Context(), r.Header.Get(userIDHeader), r.PathValue("id"), revision)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", eventETag(event))
+	s.writeJSON(w, r, http.StatusOK, newEventResponse(event))
+}
@@ -0,0 +1,73 @@
+package internalhttp
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/ical"
+)
+
+const maxImportSize = 10 << 20
+
+type importResponse struct {
+	Created int             `json:"created"`
+	Updated int             `json:"updated"`
+	Failed  []importFailure `json:"failed"`
+}
+
+type importFailure struct {
+	ID           string     `json:"id"`
+	RecurrenceID *time.Time `json:"recurrenceId,omitempty"`
+	Error        string     `json:"error"`
+}
+
+// importEvents handles POST /events/import with an iCalendar body. Events are matched
+// by UID, so importing the same calendar again updates the events instead of duplicating them.
+func (s *Server) importEvents(w http.ResponseWriter, r *http.Request) {
+	events, err := ical.Decode(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		s.writeError(w, r, fmt.Errorf("%w: %w", errBadRequest, err))
+		return
+	}
+
+	result, err := s.app.ImportEvents(r.Context(), r.Header.Get(userIDHeader), events)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	response := importResponse{
+		Created: result.Created,
+		Updated: result.Updated,
+		Failed:  make([]importFailure, 0, len(result.Failed)),
+	}
+	for _, failure := range result.Failed {
+		f := importFailure{ID: failure.ID, Error: failure.Err.Error()}
+		if !failure.RecurrenceID.IsZero() {
+			f.RecurrenceID = &failure.RecurrenceID
+		}
+		response.Failed = append(response.Failed, f)
+	}
+	s.writeJSON(w, r, http.StatusOK, response)
+}
+
+// exportEvents handles GET /events.ics, the feed of all the events of the user,
+// which calendar clients subscribe to with basic authentication.
+func (s *Server) exportEvents(w http.ResponseWriter, r *http.Request) {
+	userID := clientUserID(r)
+	if userID == "" {
+		// clients send the credentials only when they're asked to
+		w.Header().Set("WWW-Authenticate", `Basic realm="calendar"`)
+	}
+	events, err := s.app.ExportEvents(r.Context(), userID)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	if err := ical.Encode(w, events, time.Now()); err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to write response", "error", err)
+	}
+}
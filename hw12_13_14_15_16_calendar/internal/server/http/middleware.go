@@ -1,11 +1,148 @@
 package internalhttp
 
 import (
+	"net"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/logger"
 )
 
-func loggingMiddleware(next http.Handler) http.Handler { //nolint:unused
+const (
+	requestIDHeader = "X-Request-ID"
+
+	corsAllowedMethods = "GET, POST, PUT, DELETE"
+	corsAllowedHeaders = "Content-Type, If-Match, X-User-ID, X-Request-ID"
+	corsExposedHeaders = "ETag, X-Request-ID"
+	corsMaxAge         = "600"
+)
+
+// loggingMiddleware writes an access log line for every request. The request ID is taken
+// from X-Request-ID header or generated, returned to the client and added to all records
+// logged within the request context.
+func loggingMiddleware(l Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// TODO
+		start := time.Now()
+
+		requestID := logger.RequestID(r.Header.Get(requestIDHeader))
+		w.Header().Set(requestIDHeader, requestID)
+
+		fields := []any{"request_id", requestID}
+		if userID := r.Header.Get(userIDHeader); userID != "" {
+			fields = append(fields, "user_id", userID)
+		}
+		ctx := logger.ContextWith(r.Context(), fields...)
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		l.InfoContext(ctx, "http request",
+			"client_ip", clientIP(r),
+			"method", r.Method,
+			"path", r.URL.RequestURI(),
+			"proto", r.Proto,
+			"status", recorder.status,
+			"size", recorder.size,
+			"latency", time.Since(start),
+			"user_agent", r.UserAgent(),
+		)
+	})
+}
+
+// rateLimitMiddleware rejects requests of a client exceeding the rate limit. Clients are told apart
+// by the IP address, as X-User-ID is chosen by the client and changing it would reset the limit.
+func (s *Server) rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.limiter.Allow(clientIP(r)) {
+			w.Header().Set("Retry-After", "1")
+			s.writeError(w, r, errTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// corsMiddleware allows browsers to call the API from the configured origins
+// and answers their preflight requests.
+func (s *Server) corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !s.allowedOrigin(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header := w.Header()
+		header.Set("Access-Control-Allow-Origin", origin)
+		header.Add("Vary", "Origin")
+		header.Set("Access-Control-Expose-Headers", corsExposedHeaders)
+
+		// CalDAV clients send OPTIONS too, a preflight is told apart by the requested method
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			header.Set("Access-Control-Allow-Methods", corsAllowedMethods)
+			header.Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+			header.Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
 	})
 }
+
+// SetOrigins replaces the origins browsers may call the API from, "*" allows any origin.
+// It's safe to call while requests are served.
+func (s *Server) SetOrigins(origins []string) {
+	s.origins.Store(&origins)
+}
+
+func (s *Server) allowedOrigin(origin string) bool {
+	for _, allowed := range *s.origins.Load() {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// responseRecorder remembers the status and the size of the response.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	size        int
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.size += n
+	return n, err
+}
+
+func (r *responseRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap allows http.ResponseController to reach the original writer.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,139 @@
+package internalhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/app"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/logger"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/ratelimit"
+	memorystorage "github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logg, err := logger.New("info", logger.FormatJSON, buf)
+	require.NoError(t, err)
+
+	s := NewServer(logg, app.New(logg, memorystorage.New()), "", nil, nil)
+	records := func() []map[string]any {
+		var result []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var record map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &record))
+			result = append(result, record)
+		}
+		buf.Reset()
+		return result
+	}
+
+	t.Run("request id is propagated", func(t *testing.T) {
+		body := `{"title": "meeting", "startAt": "2024-03-11T10:00:00Z", "duration": "1h"}`
+		request := httptest.NewRequest(http.MethodPost, "/events?source=test", strings.NewReader(body))
+		request.Header.Set(requestIDHeader, "request-42")
+		request.Header.Set(userIDHeader, "user")
+		request.Header.Set("User-Agent", "test-agent")
+		request.RemoteAddr = "192.0.2.1:54321"
+		response := httptest.NewRecorder()
+
+		s.server.Handler.ServeHTTP(response, request)
+		require.Equal(t, http.StatusCreated, response.Code)
+		require.Equal(t, "request-42", response.Header().Get(requestIDHeader))
+
+		logged := records()
+		require.Len(t, logged, 2)
+
+		require.Equal(t, "event created", logged[0]["msg"])
+		require.Equal(t, "request-42", logged[0]["request_id"])
+		require.Equal(t, "user", logged[0]["user_id"])
+
+		access := logged[1]
+		require.Equal(t, "http request", access["msg"])
+		require.Equal(t, "request-42", access["request_id"])
+		require.Equal(t, "192.0.2.1", access["client_ip"])
+		require.Equal(t, http.MethodPost, access["method"])
+		require.Equal(t, "/events?source=test", access["path"])
+		require.Equal(t, "HTTP/1.1", access["proto"])
+		require.EqualValues(t, http.StatusCreated, access["status"])
+		require.EqualValues(t, response.Body.Len(), access["size"])
+		require.Contains(t, access, "latency")
+		require.Equal(t, "test-agent", access["user_agent"])
+	})
+
+	t.Run("request id is generated", func(t *testing.T) {
+		for _, requestID := range []string{"", "with spaces", strings.Repeat("x", logger.MaxRequestIDLength+1)} {
+			request := httptest.NewRequest(http.MethodGet, "/events/unknown", nil)
+			request.Header.Set(requestIDHeader, requestID)
+			request.Header.Set(userIDHeader, "user")
+			response := httptest.NewRecorder()
+
+			s.server.Handler.ServeHTTP(response, request)
+			require.Equal(t, http.StatusNotFound, response.Code)
+
+			generated := response.Header().Get(requestIDHeader)
+			require.NotEmpty(t, generated)
+			require.NotEqual(t, requestID, generated)
+
+			logged := records()
+			require.Len(t, logged, 1)
+			require.Equal(t, generated, logged[0]["request_id"])
+			require.EqualValues(t, http.StatusNotFound, logged[0]["status"])
+		}
+	})
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	s := NewServer(nopLogger{}, app.New(nopLogger{}, memorystorage.New()), "", nil, nil)
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodOptions, "/events", nil)
+		request.Header.Set("Origin", origin)
+		request.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		response := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(response, request)
+		return response
+	}
+
+	response := preflight("https://calendar.example.com")
+	require.Empty(t, response.Header().Get("Access-Control-Allow-Origin"))
+
+	s.SetOrigins([]string{"https://calendar.example.com"})
+	response = preflight("https://calendar.example.com")
+	require.Equal(t, http.StatusNoContent, response.Code)
+	require.Equal(t, "https://calendar.example.com", response.Header().Get("Access-Control-Allow-Origin"))
+	require.Contains(t, response.Header().Get("Access-Control-Allow-Headers"), userIDHeader)
+
+	response = preflight("https://evil.example.com")
+	require.Empty(t, response.Header().Get("Access-Control-Allow-Origin"))
+
+	request := httptest.NewRequest(http.MethodGet, "/events?period=day&date=2024-03-11", nil)
+	request.Header.Set("Origin", "https://calendar.example.com")
+	request.Header.Set(userIDHeader, "user")
+	response = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(response, request)
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Equal(t, "https://calendar.example.com", response.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	s := NewServer(nopLogger{}, app.New(nopLogger{}, memorystorage.New()), "", nil, ratelimit.New(0.001, 2))
+	get := func(remoteAddr, userID string) int {
+		request := httptest.NewRequest(http.MethodGet, "/events?period=day&date=2024-03-11", nil)
+		request.RemoteAddr = remoteAddr
+		request.Header.Set(userIDHeader, userID)
+		response := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(response, request)
+		return response.Code
+	}
+
+	require.Equal(t, http.StatusOK, get("192.0.2.1:1234", "alice"))
+	require.Equal(t, http.StatusOK, get("192.0.2.1:1234", "alice"))
+	require.Equal(t, http.StatusTooManyRequests, get("192.0.2.1:1234", "alice"))
+	// another user ID doesn't reset the limit of the address
+	require.Equal(t, http.StatusTooManyRequests, get("192.0.2.1:5678", "bob"))
+	require.Equal(t, http.StatusOK, get("192.0.2.2:1234", "bob"))
+}
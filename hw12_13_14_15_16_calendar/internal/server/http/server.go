@@ -2,30 +2,149 @@ package internalhttp
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/app"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/monitoring"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/ratelimit"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/server/http/caldav"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	readHeaderTimeout = 5 * time.Second
+	readTimeout       = 30 * time.Second
+	writeTimeout      = 30 * time.Second
+	idleTimeout       = 2 * time.Minute
+
+	davPrefix = "/dav"
 )
 
-type Server struct { // TODO
+type Server struct {
+	logger  Logger
+	app     Application
+	server  *http.Server
+	limiter *ratelimit.Limiter
+	origins atomic.Pointer[[]string]
 }
 
-type Logger interface { // TODO
+type Logger interface {
+	Info(msg string, args ...any)
+	InfoContext(ctx context.Context, msg string, args ...any)
+	ErrorContext(ctx context.Context, msg string, args ...any)
 }
 
-type Application interface { // TODO
+type Application interface {
+	CreateEvent(ctx context.Context, userID string, event storage.Event) (storage.Event, error)
+	UpdateEvent(ctx context.Context, userID, id string, event storage.Event) (storage.Event, error)
+	DeleteEvent(ctx context.Context, userID, id string) error
+	GetEvent(ctx context.Context, userID, id string) (storage.Event, error)
+	ListEventsForDay(ctx context.Context, userID string, date time.Time) ([]storage.Event, error)
+	ListEventsForWeek(ctx context.Context, userID string, date time.Time) ([]storage.Event, error)
+	ListEventsForMonth(ctx context.Context, userID string, date time.Time) ([]storage.Event, error)
+	ImportEvents(ctx context.Context, userID string, events []storage.Event) (app.ImportResult, error)
+	ExportEvents(ctx context.Context, userID string) ([]storage.Event, error)
+	FreeBusy(ctx context.Context, userID string, userIDs []string, from, to time.Time) ([]app.UserBusy, error)
+	FindSlots(ctx context.Context, userID string, query app.SlotQuery) ([]app.Interval, error)
+	CreateWebhook(ctx context.Context, userID, url string) (storage.Webhook, error)
+	ListWebhooks(ctx context.Context, userID string) ([]storage.Webhook, error)
+	DeleteWebhook(ctx context.Context, userID, id string) error
+	ListDeliveries(ctx context.Context, userID, webhookID string, limit int) ([]storage.Delivery, error)
+	EventHistory(ctx context.Context, userID, id string) ([]storage.Change, error)
+	RestoreEvent(ctx context.Context, userID, id string, revision int64) (storage.Event, error)
+	InviteAttendee(ctx context.Context, userID, id, attendeeID string) (storage.Event, error)
+	RemoveAttendee(ctx context.Context, userID, id, attendeeID string) (storage.Event, error)
+	RespondToInvitation(ctx context.Context, userID, id string, status storage.AttendeeStatus) (storage.Event, error)
 }
 
-func NewServer(logger Logger, app Application) *Server {
-	return &Server{}
+// NewServer creates the API server, which also serves the monitoring endpoints
+// reporting readiness by the checks. Requests are limited by the limiter unless it's nil.
+// Cross-origin requests are denied until SetOrigins is called.
+func NewServer(logger Logger, app Application, addr string, checks map[string]monitoring.Check,
+	limiter *ratelimit.Limiter,
+) *Server {
+	s := &Server{
+		logger:  logger,
+		app:     app,
+		limiter: limiter,
+	}
+	s.SetOrigins(nil)
+
+	s.server = &http.Server{
+		Addr:              addr,
+		Handler:           s.routes(checks),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+	return s
 }
 
-func (s *Server) Start(ctx context.Context) error {
-	// TODO
-	<-ctx.Done()
-	return nil
+func (s *Server) routes(checks map[string]monitoring.Check) http.Handler {
+	mux := http.NewServeMux()
+	handle := func(pattern string, handler http.Handler) {
+		mux.Handle(pattern, instrument(pattern, handler))
+	}
+	handleFunc := func(pattern string, handler http.HandlerFunc) {
+		handle(pattern, handler)
+	}
+
+	handleFunc("POST /events", s.createEvent)
+	handleFunc("GET /events", s.listEvents)
+	handleFunc("GET /events/{id}", s.getEvent)
+	handleFunc("PUT /events/{id}", s.updateEvent)
+	handleFunc("DELETE /events/{id}", s.deleteEvent)
+	handleFunc("GET /events/{id}/history", s.eventHistory)
+	handleFunc("POST /events/{id}/history/{revision}/restore", s.restoreEvent)
+	handleFunc("POST /events/{id}/attendees", s.inviteAttendee)
+	handleFunc("DELETE /events/{id}/attendees/{userId}", s.removeAttendee)
+	handleFunc("PUT /events/{id}/response", s.respondToInvitation)
+	handleFunc("POST /events/import", s.importEvents)
+	handleFunc("GET /events.ics", s.exportEvents)
+	handleFunc("GET /freebusy", s.freeBusy)
+	handleFunc("GET /slots", s.findSlots)
+	handleFunc("POST /webhooks", s.createWebhook)
+	handleFunc("GET /webhooks", s.listWebhooks)
+	handleFunc("DELETE /webhooks/{id}", s.deleteWebhook)
+	handleFunc("GET /webhooks/{id}/deliveries", s.listDeliveries)
+
+	handle(davPrefix+"/", caldav.New(s.logger, s.app, davPrefix, clientUserID))
+	handle("/.well-known/caldav", http.RedirectHandler(davPrefix+"/", http.StatusMovedPermanently))
+
+	// probes and scrapes are frequent, so they bypass the access log and the metrics
+	root := http.NewServeMux()
+	monitoring.Register(root, checks)
+	root.Handle("/", loggingMiddleware(s.logger, s.corsMiddleware(s.rateLimitMiddleware(mux))))
+	return root
 }
 
-func (s *Server) Stop(ctx context.Context) error {
-	// TODO
+// clientUserID identifies the user of a request made by a calendar client, over CalDAV or to the feed.
+// Calendar clients can't send custom headers, so besides X-User-ID the user name of basic authentication
+// is accepted. Like the header, the credentials are supposed to be checked by the gateway in front of the service.
+func clientUserID(r *http.Request) string {
+	if userID := r.Header.Get(userIDHeader); userID != "" {
+		return userID
+	}
+	userID, _, _ := r.BasicAuth()
+	return userID
+}
+
+// Start serves requests until the server is stopped.
+func (s *Server) Start(_ context.Context) error {
+	s.logger.Info("http server is listening", "addr", s.server.Addr)
+
+	if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
 	return nil
 }
 
-// TODO
+// Stop stops accepting new connections and waits for in-flight requests
+// to complete until ctx is done.
+func (s *Server) Stop(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
@@ -0,0 +1,554 @@
+package internalhttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/app"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Info(string, ...any)                          {}
+func (nopLogger) DebugContext(context.Context, string, ...any) {}
+func (nopLogger) InfoContext(context.Context, string, ...any)  {}
+func (nopLogger) WarnContext(context.Context, string, ...any)  {}
+func (nopLogger) ErrorContext(context.Context, string, ...any) {}
+
+type client struct {
+	t      *testing.T
+	server *httptest.Server
+}
+
+func newClient(t *testing.T) *client {
+	t.Helper()
+
+	s := NewServer(nopLogger{}, app.New(nopLogger{}, memorystorage.New()), "", nil, nil)
+	server := httptest.NewServer(s.server.Handler)
+	t.Cleanup(server.Close)
+
+	return &client{t: t, server: server}
+}
+
+// do sends the request on behalf of the user and decodes JSON response into result if it's not nil.
+func (c *client) do(method, path, userID string, body any, result any) int {
+	c.t.Helper()
+
+	response, err := c.server.Client().Do(c.request(method, path, userID, body))
+	require.NoError(c.t, err)
+	defer response.Body.Close()
+
+	if result != nil {
+		require.Equal(c.t, "application/json", response.Header.Get("Content-Type"))
+		require.NoError(c.t, json.NewDecoder(response.Body).Decode(result))
+	}
+	return response.StatusCode
+}
+
+// request creates the request on behalf of the user. A string body is sent as is, anything else as JSON.
+func (c *client) request(method, path, userID string, body any) *http.Request {
+	c.t.Helper()
+
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = bytes.NewBufferString(b)
+	default:
+		data, err := json.Marshal(b)
+		require.NoError(c.t, err)
+		reader = bytes.NewBuffer(data)
+	}
+
+	request, err := http.NewRequest(method, c.server.URL+path, reader) //nolint:noctx
+	require.NoError(c.t, err)
+	if userID != "" {
+		request.Header.Set(userIDHeader, userID)
+	}
+	return request
+}
+
+var baseTime = time.Date(2024, time.March, 11, 10, 0, 0, 0, time.UTC)
+
+func eventBody(title string, startAt time.Time) map[string]any {
+	return map[string]any{
+		"title":        title,
+		"startAt":      startAt,
+		"duration":     "1h",
+		"description":  "description",
+		"notifyBefore": "15m",
+	}
+}
+
+func TestEvents(t *testing.T) {
+	c := newClient(t)
+
+	var created eventResponse
+	require.Equal(t, http.StatusCreated, c.do(http.MethodPost, "/events", "user", eventBody("meeting", baseTime), &created))
+	require.NotEmpty(t, created.ID)
+	require.Equal(t, "user", created.UserID)
+	require.Equal(t, "meeting", created.Title)
+	require.Equal(t, duration(time.Hour), created.Duration)
+	require.Equal(t, duration(15*time.Minute), created.NotifyBefore)
+	require.True(t, baseTime.Add(time.Hour).Equal(created.EndAt))
+
+	var got eventResponse
+	require.Equal(t, http.StatusOK, c.do(http.MethodGet, "/events/"+created.ID, "user", nil, &got))
+	require.Equal(t, created, got)
+
+	var updated eventResponse
+	require.Equal(t, http.StatusOK,
+		c.do(http.MethodPut, "/events/"+created.ID, "user", eventBody("renamed", baseTime.Add(time.Hour)), &updated))
+	require.Equal(t, created.ID, updated.ID)
+	require.Equal(t, "renamed", updated.Title)
+
+	var listed []eventResponse
+	require.Equal(t, http.StatusOK, c.do(http.MethodGet, "/events?period=day&date=2024-03-11", "user", nil, &listed))
+	require.Equal(t, []eventResponse{updated}, listed)
+	require.Equal(t, http.StatusOK, c.do(http.MethodGet, "/events?period=week&date=2024-03-12", "user", nil, &listed))
+	require.Empty(t, listed)
+	require.Equal(t, http.StatusOK,
+		c.do(http.MethodGet, "/events?period=month&date=2024-03-01T00:00:00Z", "user", nil, &listed))
+	require.Len(t, listed, 1)
+
+	require.Equal(t, http.StatusNoContent, c.do(http.MethodDelete, "/events/"+created.ID, "user", nil, nil))
+	require.Equal(t, http.StatusNotFound, c.do(http.MethodGet, "/events/"+created.ID, "user", nil, nil))
+}
+
+func TestErrors(t *testing.T) {
+	c := newClient(t)
+
+	var created eventResponse
+	require.Equal(t, http.StatusCreated, c.do(http.MethodPost, "/events", "user", eventBody("meeting", baseTime), &created))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		userID string
+		body   any
+		status int
+	}{
+		{"no user", http.MethodGet, "/events/" + created.ID, "", nil, http.StatusUnauthorized},
+		{"another user", http.MethodGet, "/events/" + created.ID, "other", nil, http.StatusNotFound},
+		{"unknown event", http.MethodDelete, "/events/unknown", "user", nil, http.StatusNotFound},
+		{"busy", http.MethodPost, "/events", "user", eventBody("overlap", baseTime), http.StatusConflict},
+		{"invalid event", http.MethodPost, "/events", "user", eventBody("", baseTime.AddDate(0, 0, 1)), http.StatusUnprocessableEntity},
+		{"malformed json", http.MethodPost, "/events", "user", "{", http.StatusBadRequest},
+		{"unknown field", http.MethodPost, "/events", "user", `{"color": "red"}`, http.StatusBadRequest},
+		{"bad duration", http.MethodPost, "/events", "user", `{"duration": "long"}`, http.StatusBadRequest},
+		{"no period", http.MethodGet, "/events?date=2024-03-11", "user", nil, http.StatusBadRequest},
+		{"no date", http.MethodGet, "/events?period=day", "user", nil, http.StatusBadRequest},
+		{"bad date", http.MethodGet, "/events?period=day&date=11.03.2024", "user", nil, http.StatusBadRequest},
+		{"wrong method", http.MethodPatch, "/events/" + created.ID, "user", nil, http.StatusMethodNotAllowed},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c.t = t
+			require.Equal(t, tc.status, c.do(tc.method, tc.path, tc.userID, tc.body, nil))
+		})
+	}
+}
+
+func TestVersions(t *testing.T) {
+	c := newClient(t)
+
+	var created eventResponse
+	require.Equal(t, http.StatusCreated,
+		c.do(http.MethodPost, "/events", "user", eventBody("meeting", baseTime), &created))
+	require.Equal(t, int64(1), created.Version)
+
+	// update sends PUT with the If-Match header and returns the status and ETag of the response
+	update := func(ifMatch string) (int, string) {
+		t.Helper()
+		request := c.request(http.MethodPut, "/events/"+created.ID, "user", eventBody("renamed", baseTime))
+		if ifMatch != "" {
+			request.Header.Set("If-Match", ifMatch)
+		}
+		response, err := c.server.Client().Do(request)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		return response.StatusCode, response.Header.Get("ETag")
+	}
+
+	response, err := c.server.Client().Do(c.request(http.MethodGet, "/events/"+created.ID, "user", nil))
+	require.NoError(t, err)
+	response.Body.Close()
+	require.Equal(t, `"1"`, response.Header.Get("ETag"))
+
+	code, etag := update(`"1"`)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, `"2"`, etag)
+
+	code, _ = update(`"1"`)
+	require.Equal(t, http.StatusPreconditionFailed, code)
+	code, _ = update(`"unknown"`)
+	require.Equal(t, http.StatusPreconditionFailed, code)
+	// only a single strong ETag is supported, other forms aren't taken for a conflict
+	for _, ifMatch := range []string{`W/"2"`, "2", `"1", "2"`, `"2`} {
+		code, _ = update(ifMatch)
+		require.Equal(t, http.StatusBadRequest, code, ifMatch)
+	}
+
+	code, etag = update("*")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, `"3"`, etag)
+	code, etag = update("")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, `"4"`, etag)
+}
+
+func TestHistory(t *testing.T) {
+	c := newClient(t)
+
+	var created eventResponse
+	require.Equal(t, http.StatusCreated,
+		c.do(http.MethodPost, "/events", "user", eventBody("meeting", baseTime), &created))
+	require.Equal(t, http.StatusOK,
+		c.do(http.MethodPut, "/events/"+created.ID, "user", eventBody("renamed", baseTime), nil))
+	require.Equal(t, http.StatusNoContent, c.do(http.MethodDelete, "/events/"+created.ID, "user", nil, nil))
+
+	var history []changeResponse
+	require.Equal(t, http.StatusOK, c.do(http.MethodGet, "/events/"+created.ID+"/history", "user", nil, &history))
+	require.Len(t, history, 3)
+	require.Equal(t, storage.ActionUpdate, history[1].Action)
+	require.Equal(t, "user", history[1].ActorID)
+	require.Equal(t, []fieldChangeResponse{{Field: "title", Before: "meeting", After: "renamed"}}, history[1].Fields)
+	require.Equal(t, "renamed", history[2].Event.Title)
+	require.Equal(t, http.StatusNotFound, c.do(http.MethodGet, "/events/"+created.ID+"/history", "other", nil, nil))
+
+	restore := "/events/" + created.ID + "/history/1/restore"
+	var restored eventResponse
+	require.Equal(t, http.StatusOK, c.do(http.MethodPost, restore, "user", nil, &restored))
+	require.Equal(t, created.ID, restored.ID)
+	require.Equal(t, "meeting", restored.Title)
+	require.Equal(t, http.StatusOK, c.do(http.MethodGet, "/events/"+created.ID, "user", nil, nil))
+
+	require.Equal(t, http.StatusNotFound, c.do(http.MethodPost, restore, "other", nil, nil))
+	require.Equal(t, http.StatusNotFound,
+		c.do(http.MethodPost, "/events/"+created.ID+"/history/10/restore", "user", nil, nil))
+	require.Equal(t, http.StatusBadRequest,
+		c.do(http.MethodPost, "/events/"+created.ID+"/history/3/restore", "user", nil, nil))
+	require.Equal(t, http.StatusBadRequest,
+		c.do(http.MethodPost, "/events/"+created.ID+"/history/last/restore", "user", nil, nil))
+}
+
+func TestAttendees(t *testing.T) {
+	c := newClient(t)
+
+	var created eventResponse
+	require.Equal(t, http.StatusCreated,
+		c.do(http.MethodPost, "/events", "user", eventBody("meeting", baseTime), &created))
+	attendees := "/events/" + created.ID + "/attendees"
+
+	var invited eventResponse
+	require.Equal(t, http.StatusOK,
+		c.do(http.MethodPost, attendees, "user", map[string]string{"userId": "alice"}, &invited))
+	require.Equal(t, []attendeeResponse{{UserID: "alice", Status: storage.StatusNeedsAction}}, invited.Attendees)
+	require.Equal(t, http.StatusUnprocessableEntity,
+		c.do(http.MethodPost, attendees, "user", map[string]string{"userId": "user"}, nil))
+	require.Equal(t, http.StatusNotFound,
+		c.do(http.MethodPost, attendees, "alice", map[string]string{"userId": "bob"}, nil))
+
+	var listed []eventResponse
+	require.Equal(t, http.StatusOK, c.do(http.MethodGet, "/events?period=day&date=2024-03-11", "alice", nil, &listed))
+	require.Equal(t, []eventResponse{invited}, listed)
+
+	response := "/events/" + created.ID + "/response"
+	var answered eventResponse
+	require.Equal(t, http.StatusOK,
+		c.do(http.MethodPut, response, "alice", map[string]string{"status": "accepted"}, &answered))
+	require.Equal(t, []attendeeResponse{{UserID: "alice", Status: storage.StatusAccepted}}, answered.Attendees)
+	require.Equal(t, http.StatusUnprocessableEntity,
+		c.do(http.MethodPut, response, "alice", map[string]string{"status": "maybe"}, nil))
+	require.Equal(t, http.StatusNotFound,
+		c.do(http.MethodPut, response, "bob", map[string]string{"status": "accepted"}, nil))
+
+	require.Equal(t, http.StatusNoContent, c.do(http.MethodDelete, attendees+"/alice", "user", nil, nil))
+	require.Equal(t, http.StatusNotFound, c.do(http.MethodDelete, attendees+"/alice", "user", nil, nil))
+	require.Equal(t, http.StatusNotFound, c.do(http.MethodGet, "/events/"+created.ID, "alice", nil, nil))
+}
+
+func TestRecurringEvents(t *testing.T) {
+	c := newClient(t)
+
+	body := eventBody("standup", baseTime)
+	body["rrule"] = "FREQ=DAILY;COUNT=5"
+	body["exdates"] = []time.Time{baseTime.AddDate(0, 0, 1)}
+	var series eventResponse
+	require.Equal(t, http.StatusCreated, c.do(http.MethodPost, "/events", "user", body, &series))
+	require.Equal(t, "FREQ=DAILY;COUNT=5", series.RRule)
+	require.Nil(t, series.RecurrenceID)
+
+	override := eventBody("late standup", baseTime.AddDate(0, 0, 2).Add(time.Hour))
+	override["seriesId"] = series.ID
+	override["recurrenceId"] = baseTime.AddDate(0, 0, 2)
+	require.Equal(t, http.StatusCreated, c.do(http.MethodPost, "/events", "user", override, nil))
+
+	var listed []eventResponse
+	require.Equal(t, http.StatusOK, c.do(http.MethodGet, "/events?period=week&date=2024-03-11", "user", nil, &listed))
+	require.Len(t, listed, 4)
+	require.Equal(t, series.ID, listed[0].ID)
+	require.True(t, baseTime.Equal(*listed[0].RecurrenceID))
+	require.Equal(t, "late standup", listed[1].Title)
+	require.Equal(t, series.ID, listed[1].SeriesID)
+
+	body["rrule"] = "FREQ=SOMETIMES"
+	require.Equal(t, http.StatusUnprocessableEntity, c.do(http.MethodPost, "/events", "user", body, nil))
+}
+
+func TestReminders(t *testing.T) {
+	c := newClient(t)
+
+	body := eventBody("meeting", baseTime)
+	delete(body, "notifyBefore")
+	body["reminders"] = []map[string]any{
+		{"before": "24h", "channel": "webhook"},
+		{"before": "10m"},
+	}
+	var created eventResponse
+	require.Equal(t, http.StatusCreated, c.do(http.MethodPost, "/events", "user", body, &created))
+	require.Equal(t,
+		[]reminder{{Before: duration(24 * time.Hour), Channel: "webhook"}, {Before: duration(10 * time.Minute)}},
+		created.Reminders)
+
+	var got eventResponse
+	require.Equal(t, http.StatusOK, c.do(http.MethodGet, "/events/"+created.ID, "user", nil, &got))
+	require.Equal(t, created, got)
+
+	body["notifyBefore"] = "15m"
+	require.Equal(t, http.StatusUnprocessableEntity, c.do(http.MethodPut, "/events/"+created.ID, "user", body, nil))
+	delete(body, "notifyBefore")
+	body["reminders"] = []map[string]any{{"before": "1h", "channel": "pigeon"}}
+	require.Equal(t, http.StatusUnprocessableEntity, c.do(http.MethodPut, "/events/"+created.ID, "user", body, nil))
+}
+
+func TestICalendar(t *testing.T) {
+	c := newClient(t)
+
+	data := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:standup@example.com",
+		"DTSTART:20240311T100000Z",
+		"DURATION:PT15M",
+		"SUMMARY:Standup",
+		"RRULE:FREQ=DAILY;COUNT=5",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:standup@example.com",
+		"RECURRENCE-ID:20240312T100000Z",
+		"DTSTART:20240312T110000Z",
+		"DURATION:PT15M",
+		"SUMMARY:Late standup",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:broken@example.com",
+		"DTSTART:20240312T110000Z",
+		"SUMMARY:No duration",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	var imported importResponse
+	require.Equal(t, http.StatusOK, c.do(http.MethodPost, "/events/import", "user", data, &imported))
+	require.Equal(t, 2, imported.Created)
+	require.Len(t, imported.Failed, 1)
+	require.Equal(t, "broken@example.com", imported.Failed[0].ID)
+
+	var listed []eventResponse
+	require.Equal(t, http.StatusOK, c.do(http.MethodGet, "/events?period=week&date=2024-03-11", "user", nil, &listed))
+	require.Len(t, listed, 5)
+	require.Equal(t, "standup@example.com", listed[0].ID)
+	require.Equal(t, "Late standup", listed[1].Title)
+
+	response, err := c.server.Client().Do(c.request(http.MethodGet, "/events.ics", "user", nil))
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, "text/calendar; charset=utf-8", response.Header.Get("Content-Type"))
+	feed, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(feed), "UID:standup@example.com\r\n")
+	require.Contains(t, string(feed), "RECURRENCE-ID:20240312T100000Z\r\n")
+
+	// the feed imported back changes nothing
+	require.Equal(t, http.StatusOK, c.do(http.MethodPost, "/events/import", "user", string(feed), &imported))
+	require.Equal(t, importResponse{Updated: 2, Failed: []importFailure{}}, imported)
+
+	require.Equal(t, http.StatusBadRequest, c.do(http.MethodPost, "/events/import", "user", "BEGIN:VCALENDAR", nil))
+	require.Equal(t, http.StatusUnauthorized, c.do(http.MethodPost, "/events/import", "", data, nil))
+
+	// calendar clients subscribe to the feed with basic authentication, as they can't send the header
+	request := c.request(http.MethodGet, "/events.ics", "", nil)
+	response, err = c.server.Client().Do(request)
+	require.NoError(t, err)
+	response.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	require.Equal(t, `Basic realm="calendar"`, response.Header.Get("WWW-Authenticate"))
+
+	request.SetBasicAuth("user", "secret")
+	response, err = c.server.Client().Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	feed, err = io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(feed), "UID:standup@example.com\r\n")
+}
+
+func TestTimeZones(t *testing.T) {
+	c := newClient(t)
+
+	// late evening in New York is the next day in Moscow
+	late := eventBody("late", time.Date(2024, time.March, 11, 23, 30, 0, 0, time.UTC))
+	require.Equal(t, http.StatusCreated, c.do(http.MethodPost, "/events", "user", late, nil))
+
+	standup := eventBody("standup", time.Date(2024, time.March, 4, 15, 0, 0, 0, time.UTC))
+	standup["rrule"] = "FREQ=WEEKLY"
+	standup["timeZone"] = "America/New_York"
+	var series eventResponse
+	require.Equal(t, http.StatusCreated, c.do(http.MethodPost, "/events", "user", standup, &series))
+	require.Equal(t, "America/New_York", series.TimeZone)
+
+	tests := []struct {
+		query  string
+		titles []string
+	}{
+		{"date=2024-03-11&tz=America/New_York", []string{"standup", "late"}},
+		{"date=2024-03-11&tz=Europe/Moscow", []string{"standup"}},
+		{"date=2024-03-12&tz=Europe/Moscow", []string{"late"}},
+		{"date=2024-03-12T01:00:00Z&tz=America/New_York", []string{"standup", "late"}},
+		{"date=2024-03-12", []string{"late"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			var listed []eventResponse
+			require.Equal(t, http.StatusOK, c.do(http.MethodGet, "/events?period=day&"+tc.query, "user", nil, &listed))
+			titles := make([]string, 0, len(listed))
+			for _, event := range listed {
+				titles = append(titles, event.Title)
+			}
+			require.Equal(t, tc.titles, titles)
+		})
+	}
+
+	// the series keeps its local time after DST starts on March 10
+	var listed []eventResponse
+	require.Equal(t, http.StatusOK,
+		c.do(http.MethodGet, "/events?period=day&date=2024-03-11&tz=America/New_York", "user", nil, &listed))
+	require.Equal(t, time.Date(2024, time.March, 11, 14, 0, 0, 0, time.UTC), listed[0].StartAt)
+
+	require.Equal(t, http.StatusBadRequest,
+		c.do(http.MethodGet, "/events?period=day&date=2024-03-11&tz=Mars/Olympus", "user", nil, nil))
+	standup["timeZone"] = "Mars/Olympus"
+	require.Equal(t, http.StatusUnprocessableEntity, c.do(http.MethodPost, "/events", "user", standup, nil))
+}
+
+func TestFreeBusy(t *testing.T) {
+	c := newClient(t)
+
+	require.Equal(t, http.StatusCreated, c.do(http.MethodPost, "/events", "alice", eventBody("meeting", baseTime), nil))
+	require.Equal(t, http.StatusCreated,
+		c.do(http.MethodPost, "/events", "bob", eventBody("call", baseTime.Add(90*time.Minute)), nil))
+
+	var busy []userBusyResponse
+	require.Equal(t, http.StatusOK,
+		c.do(http.MethodGet, "/freebusy?users=alice,bob&from=2024-03-11&to=2024-03-12", "carol", nil, &busy))
+	require.Equal(t, []userBusyResponse{
+		{UserID: "alice", Busy: []intervalResponse{{baseTime, baseTime.Add(time.Hour)}}},
+		{UserID: "bob", Busy: []intervalResponse{{baseTime.Add(90 * time.Minute), baseTime.Add(150 * time.Minute)}}},
+	}, busy)
+
+	// working hours are taken in Moscow, where it's 13:00 at 10:00 UTC
+	var slots []intervalResponse
+	require.Equal(t, http.StatusOK, c.do(http.MethodGet, "/slots?users=alice,bob&duration=30m&from=2024-03-11"+
+		"&to=2024-03-12&tz=Europe/Moscow&workStart=12:00&workEnd=15:00&days=mon&limit=3", "carol", nil, &slots))
+	require.Equal(t, []intervalResponse{
+		{baseTime.Add(-time.Hour), baseTime.Add(-30 * time.Minute)},
+		{baseTime.Add(-30 * time.Minute), baseTime},
+		{baseTime.Add(time.Hour), baseTime.Add(90 * time.Minute)},
+	}, slots)
+
+	tests := []struct {
+		path   string
+		userID string
+		status int
+	}{
+		{"/freebusy?users=alice&from=2024-03-11&to=2024-03-12", "", http.StatusUnauthorized},
+		{"/freebusy?from=2024-03-11&to=2024-03-12", "carol", http.StatusBadRequest},
+		{"/freebusy?users=alice&from=2024-03-12&to=2024-03-11", "carol", http.StatusBadRequest},
+		{"/freebusy?users=alice&from=2024-03-11", "carol", http.StatusBadRequest},
+		{"/slots?users=alice&from=2024-03-11&to=2024-03-12", "carol", http.StatusBadRequest},
+		{"/slots?users=alice&duration=1h&from=2024-03-11&to=2024-03-12&workStart=9am", "carol", http.StatusBadRequest},
+		{"/slots?users=alice&duration=1h&from=2024-03-11&to=2024-03-12&days=monday", "carol", http.StatusBadRequest},
+		{"/slots?users=alice&duration=1h&from=2024-03-11&to=2024-03-12&limit=many", "carol", http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			require.Equal(t, tc.status, c.do(http.MethodGet, tc.path, tc.userID, nil, nil))
+		})
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	c := newClient(t)
+
+	var created webhookResponse
+	require.Equal(t, http.StatusCreated,
+		c.do(http.MethodPost, "/webhooks", "user", map[string]any{"url": "https://example.com/hook"}, &created))
+	require.NotEmpty(t, created.ID)
+	require.NotEmpty(t, created.Secret)
+
+	var listed []webhookResponse
+	require.Equal(t, http.StatusOK, c.do(http.MethodGet, "/webhooks", "user", nil, &listed))
+	require.Len(t, listed, 1)
+	require.Equal(t, created.ID, listed[0].ID)
+	require.Empty(t, listed[0].Secret)
+
+	var deliveries []deliveryResponse
+	path := "/webhooks/" + created.ID + "/deliveries"
+	require.Equal(t, http.StatusOK, c.do(http.MethodGet, path+"?limit=10", "user", nil, &deliveries))
+	require.Empty(t, deliveries)
+	require.Equal(t, http.StatusNotFound, c.do(http.MethodGet, path, "other", nil, nil))
+	require.Equal(t, http.StatusBadRequest, c.do(http.MethodGet, path+"?limit=-1", "user", nil, nil))
+
+	require.Equal(t, http.StatusUnprocessableEntity,
+		c.do(http.MethodPost, "/webhooks", "user", map[string]any{"url": "example.com/hook"}, nil))
+	require.Equal(t, http.StatusUnauthorized,
+		c.do(http.MethodPost, "/webhooks", "", map[string]any{"url": "https://example.com/hook"}, nil))
+
+	require.Equal(t, http.StatusNotFound, c.do(http.MethodDelete, "/webhooks/"+created.ID, "other", nil, nil))
+	require.Equal(t, http.StatusNoContent, c.do(http.MethodDelete, "/webhooks/"+created.ID, "user", nil, nil))
+	require.Equal(t, http.StatusNotFound, c.do(http.MethodDelete, "/webhooks/"+created.ID, "user", nil, nil))
+}
+
+func TestMonitoring(t *testing.T) {
+	c := newClient(t)
+
+	require.Equal(t, http.StatusOK, c.do(http.MethodGet, "/healthz", "", nil, nil))
+	require.Equal(t, http.StatusOK, c.do(http.MethodGet, "/readyz", "", nil, nil))
+
+	require.Equal(t, http.StatusNotFound, c.do(http.MethodGet, "/events/unknown", "user", nil, nil))
+
+	response, err := c.server.Client().Do(c.request(http.MethodGet, "/metrics", "", nil))
+	require.NoError(t, err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body),
+		`calendar_http_requests_total{code="404",method="get",route="GET /events/{id}"}`)
+	require.Contains(t, string(body),
+		`calendar_http_request_duration_seconds_count{method="get",route="GET /events/{id}"}`)
+}
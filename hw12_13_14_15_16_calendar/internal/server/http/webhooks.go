@@ -0,0 +1,112 @@
+package internalhttp
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+)
+
+type webhookRequest struct {
+	URL string `json:"url"`
+}
+
+type webhookResponse struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	// Secret is returned only once, when the webhook is created.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func newWebhookResponse(webhook storage.Webhook) webhookResponse {
+	return webhookResponse{ID: webhook.ID, URL: webhook.URL, CreatedAt: webhook.CreatedAt}
+}
+
+type deliveryResponse struct {
+	ID          string    `json:"id"`
+	EventID     string    `json:"eventId"`
+	StartAt     time.Time `json:"startAt"`
+	Attempt     int       `json:"attempt"`
+	DeliveredAt time.Time `json:"deliveredAt"`
+	Duration    duration  `json:"duration"`
+	StatusCode  int       `json:"statusCode,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// createWebhook handles POST /webhooks. The response contains the secret the payloads are signed with.
+func (s *Server) createWebhook(w http.ResponseWriter, r *http.Request) {
+	var request webhookRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	webhook, err := s.app.CreateWebhook(r.Context(), r.Header.Get(userIDHeader), request.URL)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	response := newWebhookResponse(webhook)
+	response.Secret = webhook.Secret
+	s.writeJSON(w, r, http.StatusCreated, response)
+}
+
+func (s *Server) listWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := s.app.ListWebhooks(r.Context(), r.Header.Get(userIDHeader))
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	response := make([]webhookResponse, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		response = append(response, newWebhookResponse(webhook))
+	}
+	s.writeJSON(w, r, http.StatusOK, response)
+}
+
+func (s *Server) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := s.app.DeleteWebhook(r.Context(), r.Header.Get(userIDHeader), r.PathValue("id")); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listDeliveries handles GET /webhooks/{id}/deliveries?limit=50, the latest attempts go first.
+func (s *Server) listDeliveries(w http.ResponseWriter, r *http.Request) {
+	var limit int
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil {
+			s.writeError(w, r, fmt.Errorf("%w: limit must be a number", errBadRequest))
+			return
+		}
+	}
+
+	deliveries, err := s.app.ListDeliveries(r.Context(), r.Header.Get(userIDHeader), r.PathValue("id"), limit)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	response := make([]deliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		response = append(response, deliveryResponse{
+			ID:          d.ID,
+			EventID:     d.EventID,
+			StartAt:     d.StartAt,
+			Attempt:     d.Attempt,
+			DeliveredAt: d.DeliveredAt,
+			Duration:    duration(d.Duration),
+			StatusCode:  d.StatusCode,
+			Error:       d.Error,
+		})
+	}
+	s.writeJSON(w, r, http.StatusOK, response)
+}
@@ -0,0 +1,47 @@
+package storage
+
+// AttendeeStatus is the participation status of an attendee, named after RFC 5545 PARTSTAT.
+type AttendeeStatus string
+
+const (
+	StatusNeedsAction AttendeeStatus = "needs-action"
+	StatusAccepted    AttendeeStatus = "accepted"
+	StatusDeclined    AttendeeStatus = "declined"
+	StatusTentative   AttendeeStatus = "tentative"
+)
+
+// Valid reports whether the status is one of the known ones.
+func (s AttendeeStatus) Valid() bool {
+	switch s {
+	case StatusNeedsAction, StatusAccepted, StatusDeclined, StatusTentative:
+		return true
+	}
+	return false
+}
+
+// Attendee is a user invited to the event by its owner.
+type Attendee struct {
+	UserID string
+	Status AttendeeStatus
+}
+
+// Attendee returns the attendee of the event with the given user ID.
+func (e Event) Attendee(userID string) (Attendee, bool) {
+	for _, attendee := range e.Attendees {
+		if attendee.UserID == userID {
+			return attendee, true
+		}
+	}
+	return Attendee{}, false
+}
+
+// Recipients returns the users to notify about the event: the owner and the attendees who have accepted.
+func (e Event) Recipients() []string {
+	recipients := []string{e.UserID}
+	for _, attendee := range e.Attendees {
+		if attendee.Status == StatusAccepted {
+			recipients = append(recipients, attendee.UserID)
+		}
+	}
+	return recipients
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Action is what a change has done to the event.
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Change is an entry of the audit log of the event. Entries are only ever added.
+type Change struct {
+	EventID string
+	// Revision numbers the changes of the event starting with 1, it's assigned by the storage.
+	Revision int64
+	// UserID is the owner of the event, ActorID is the user who has made the change.
+	UserID    string
+	ActorID   string
+	Action    Action
+	ChangedAt time.Time
+	// Fields are the fields of the event changed, all the fields set for a creation or a deletion.
+	Fields []FieldChange
+	// Event is the state of the event after the change, or before it for a deletion.
+	Event Event
+	// RestoredFrom is the revision the event has been restored to by the change, zero if none.
+	RestoredFrom int64
+}
+
+// Audit tells who changes events and when. The storage records a change of every event it creates,
+// updates or deletes along with the event itself, so the audit log never misses one.
+type Audit struct {
+	// ActorID is the user making the change, empty for the calendar itself.
+	ActorID   string
+	ChangedAt time.Time
+	// RestoredFrom is the revision the event is restored to by the change, zero if none.
+	RestoredFrom int64
+}
+
+// Change returns the change of the event from the state before to the state after.
+// A zero state stands for the event which doesn't exist.
+func (a Audit) Change(before, after Event) Change {
+	change := Change{
+		ActorID:      a.ActorID,
+		ChangedAt:    a.ChangedAt,
+		Fields:       Diff(before, after),
+		Event:        after,
+		RestoredFrom: a.RestoredFrom,
+	}
+	switch {
+	case before.ID == "":
+		change.Action = ActionCreate
+	case after.ID == "":
+		change.Action = ActionDelete
+		change.Event = before
+	default:
+		change.Action = ActionUpdate
+	}
+	change.EventID = change.Event.ID
+	change.UserID = change.Event.UserID
+	return change
+}
+
+// FieldChange holds the values of an event field before and after the change
+// in the text form of the API. An unset value is empty.
+type FieldChange struct {
+	Field  string
+	Before string
+	After  string
+}
+
+// Diff returns the fields which differ between two states of the event. A zero state
+// stands for the event which doesn't exist. The ID, owner and version aren't compared.
+func Diff(before, after Event) []FieldChange {
+	var changes []FieldChange
+	for _, f := range eventFields {
+		b, a := f.value(before), f.value(after)
+		if b != a {
+			changes = append(changes, FieldChange{Field: f.name, Before: b, After: a})
+		}
+	}
+	return changes
+}
+
+var eventFields = []struct {
+	name  string
+	value func(e Event) string
+}{
+	{"title", func(e Event) string { return e.Title }},
+	{"startAt", func(e Event) string { return formatTime(e.StartAt) }},
+	{"duration", func(e Event) string { return formatDuration(e.Duration) }},
+	{"description", func(e Event) string { return e.Description }},
+	{"notifyBefore", func(e Event) string { return formatDuration(e.NotifyBefore) }},
+	{"rrule", func(e Event) string { return e.RRule }},
+	{"exdates", func(e Event) string {
+		dates := make([]string, 0, len(e.ExDates))
+		for _, date := range e.ExDates {
+			dates = append(dates, formatTime(date))
+		}
+		slices.Sort(dates)
+		return strings.Join(dates, ",")
+	}},
+	{"seriesId", func(e Event) string { return e.SeriesID }},
+	{"recurrenceId", func(e Event) string { return formatTime(e.RecurrenceID) }},
+	{"timeZone", func(e Event) string { return e.TimeZone }},
+	{"attendees", func(e Event) string {
+		attendees := make([]string, 0, len(e.Attendees))
+		for _, attendee := range e.Attendees {
+			attendees = append(attendees, attendee.UserID+":"+string(attendee.Status))
+		}
+		slices.Sort(attendees)
+		return strings.Join(attendees, ",")
+	}},
+	{"reminders", func(e Event) string {
+		reminders := make([]string, 0, len(e.Reminders))
+		for _, reminder := range e.Reminders {
+			value := formatDuration(reminder.Before)
+			if reminder.Channel != "" {
+				value += ":" + reminder.Channel
+			}
+			reminders = append(reminders, value)
+		}
+		slices.Sort(reminders)
+		return strings.Join(reminders, ",")
+	}},
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
@@ -0,0 +1,14 @@
+package storage
+
+import "errors"
+
+var (
+	ErrEventNotFound      = errors.New("event not found")
+	ErrEventAlreadyExists = errors.New("event already exists")
+	ErrDateBusy           = errors.New("date is busy by another event")
+	ErrVersionConflict    = errors.New("event has been changed by someone else")
+	ErrWebhookNotFound    = errors.New("webhook not found")
+	ErrRevisionNotFound   = errors.New("revision not found")
+	ErrAttendeeNotFound   = errors.New("attendee not found")
+	ErrReminderNotFound   = errors.New("reminder not found")
+)
@@ -1,7 +1,86 @@
 package storage
 
+import "time"
+
 type Event struct {
-	ID    string
-	Title string
-	// TODO
+	ID           string
+	Title        string
+	StartAt      time.Time
+	Duration     time.Duration
+	Description  string
+	UserID       string
+	NotifyBefore time.Duration
+
+	// RRule is the RFC 5545 recurrence rule of a series, empty for a single event.
+	// StartAt and Duration of a series describe its first occurrence.
+	RRule string
+	// ExDates are starts of the occurrences excluded from the series.
+	ExDates []time.Time
+	// SeriesID is set for an override of a single occurrence of the series,
+	// which originally starts at RecurrenceID. Occurrences expanded from a series
+	// have the series ID and RecurrenceID equal to their start.
+	SeriesID     string
+	RecurrenceID time.Time
+	// TimeZone is the IANA name of the time zone the series recurs in, so that its occurrences
+	// keep their local time across DST transitions. Empty means UTC.
+	TimeZone string
+	// Version starts from 1 and is incremented by every update of the event. The version
+	// passed to an update is the one the change is based on, zero means any.
+	Version int64
+	// Attendees are the users invited to the event besides its owner, who see the event
+	// among their own ones. Overrides of a series have the attendees of the series.
+	Attendees []Attendee
+	// Reminders are notifications about the event, each due at its own time before the event
+	// and delivered through its own channel. Without them, NotifyBefore stands for a single
+	// reminder delivered through all the channels.
+	Reminders []Reminder
+}
+
+// Recurring reports whether the event is a series rather than a single event.
+func (e Event) Recurring() bool {
+	return e.RRule != ""
+}
+
+// Location returns the time zone of the event, UTC if it's unknown.
+func (e Event) Location() *time.Location {
+	loc, err := LoadLocation(e.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// UTC returns the event with all its times in UTC, the way storages return them.
+func (e Event) UTC() Event {
+	e.StartAt = e.StartAt.UTC()
+	if !e.RecurrenceID.IsZero() {
+		e.RecurrenceID = e.RecurrenceID.UTC()
+	}
+	if len(e.ExDates) > 0 {
+		exDates := make([]time.Time, 0, len(e.ExDates))
+		for _, t := range e.ExDates {
+			exDates = append(exDates, t.UTC())
+		}
+		e.ExDates = exDates
+	}
+	return e
+}
+
+func (e Event) EndAt() time.Time {
+	return e.StartAt.Add(e.Duration)
+}
+
+// Overlaps reports whether two events share any moment of time.
+// Events are treated as half-open intervals [StartAt, EndAt).
+func (e Event) Overlaps(other Event) bool {
+	return e.StartAt.Before(other.EndAt()) && other.StartAt.Before(e.EndAt())
+}
+
+// In reports whether the event intersects the interval [from, to).
+// An event without duration is treated as a point of time.
+func (e Event) In(from, to time.Time) bool {
+	if !e.StartAt.Before(to) {
+		return false
+	}
+	return e.EndAt().After(from) || !e.StartAt.Before(from)
 }
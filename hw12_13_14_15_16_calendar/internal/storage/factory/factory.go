@@ -0,0 +1,49 @@
+// Package storagefactory creates the storage described by the configuration,
+// so every process picks it the same way.
+package storagefactory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/app"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/config"
+	memorystorage "github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage/memory"
+	sqlstorage "github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage/sql"
+)
+
+var ErrUnknownStorage = errors.New("unknown storage type")
+
+// New creates the storage configured by conf. The returned function must be called
+// to release the storage resources.
+func New(ctx context.Context, conf config.StorageConf) (app.Storage, func(context.Context) error, error) {
+	switch conf.Type {
+	case config.StorageMemory:
+		return memorystorage.New(), func(context.Context) error { return nil }, nil
+	case config.StorageSQL:
+		s := sqlstorage.New(conf.Driver, conf.DSN)
+		if err := s.Connect(ctx); err != nil {
+			return nil, nil, err
+		}
+		return s, s.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: %q", ErrUnknownStorage, conf.Type)
+	}
+}
+
+// Migrate runs a migration command against the configured SQL storage.
+func Migrate(ctx context.Context, conf config.StorageConf, command string, out io.Writer) error {
+	if conf.Type != config.StorageSQL {
+		return fmt.Errorf("migrations are applicable only to %q storage", config.StorageSQL)
+	}
+
+	s := sqlstorage.New(conf.Driver, conf.DSN)
+	if err := s.Connect(ctx); err != nil {
+		return err
+	}
+	defer s.Close(ctx)
+
+	return s.Migrate(ctx, command, out)
+}
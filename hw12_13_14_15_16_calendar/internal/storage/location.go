@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"sync"
+	"time"
+)
+
+// locations caches loaded time zones, as time.LoadLocation reads the database every time.
+var locations sync.Map
+
+// LoadLocation returns the time zone with the given IANA name, UTC for an empty name.
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
@@ -0,0 +1,57 @@
+package memorystorage
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+)
+
+// AddChange appends the change to the audit log of the event, assigning the next revision to it.
+func (s *Storage) AddChange(_ context.Context, change storage.Change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.addChange(change)
+	return nil
+}
+
+func (s *Storage) addChange(change storage.Change) {
+	change.Revision = int64(len(s.changes[change.EventID]) + 1)
+	change.ChangedAt = change.ChangedAt.UTC()
+	change.Fields = slices.Clone(change.Fields)
+	change.Event = change.Event.UTC()
+	s.changes[change.EventID] = append(s.changes[change.EventID], change)
+}
+
+// purgeChanges removes at most limit audit logs of the events deleted before the given moment,
+// the earliest deleted first.
+func (s *Storage) purgeChanges(before time.Time, limit int) {
+	var deletions []storage.Change
+	for _, changes := range s.changes {
+		last := changes[len(changes)-1]
+		if last.Action == storage.ActionDelete && last.ChangedAt.Before(before) {
+			deletions = append(deletions, last)
+		}
+	}
+	slices.SortFunc(deletions, func(a, b storage.Change) int {
+		if c := a.ChangedAt.Compare(b.ChangedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.EventID, b.EventID)
+	})
+
+	for _, change := range deletions[:min(limit, len(deletions))] {
+		delete(s.changes, change.EventID)
+	}
+}
+
+// ListChanges returns the audit log of the event, the earliest change first.
+func (s *Storage) ListChanges(_ context.Context, eventID string) ([]storage.Change, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return slices.Clone(s.changes[eventID]), nil
+}
@@ -0,0 +1,84 @@
+package memorystorage
+
+import (
+	"sort"
+	"time"
+)
+
+type indexEntry struct {
+	start time.Time
+	end   time.Time
+	id    string
+}
+
+func (e indexEntry) less(other indexEntry) bool {
+	if e.start.Equal(other.start) {
+		return e.id < other.id
+	}
+	return e.start.Before(other.start)
+}
+
+// intervalIndex keeps intervals sorted by start time. Along with every entry it stores
+// the latest end among all preceding entries (including the entry itself). The sequence
+// of those maximums is non-decreasing, so both bounds of an overlap query are found
+// with binary search and only entries in between are checked.
+type intervalIndex struct {
+	entries []indexEntry
+	maxEnd  []time.Time
+}
+
+func (x *intervalIndex) insert(entry indexEntry) {
+	i := sort.Search(len(x.entries), func(i int) bool {
+		return entry.less(x.entries[i])
+	})
+
+	x.entries = append(x.entries, indexEntry{})
+	copy(x.entries[i+1:], x.entries[i:])
+	x.entries[i] = entry
+
+	x.maxEnd = append(x.maxEnd, time.Time{})
+	x.fixMaxEnd(i)
+}
+
+func (x *intervalIndex) remove(entry indexEntry) {
+	i := sort.Search(len(x.entries), func(i int) bool {
+		return !x.entries[i].less(entry)
+	})
+	if i == len(x.entries) || x.entries[i].id != entry.id {
+		return
+	}
+
+	x.entries = append(x.entries[:i], x.entries[i+1:]...)
+	x.maxEnd = x.maxEnd[:len(x.entries)]
+	x.fixMaxEnd(i)
+}
+
+// fixMaxEnd recalculates maximums starting from the i-th entry.
+func (x *intervalIndex) fixMaxEnd(i int) {
+	for ; i < len(x.entries); i++ {
+		end := x.entries[i].end
+		if i > 0 && x.maxEnd[i-1].After(end) {
+			end = x.maxEnd[i-1]
+		}
+		x.maxEnd[i] = end
+	}
+}
+
+// overlapping returns IDs of entries intersecting [from, to) in order of their start time.
+// Empty entries are considered as points and returned if they belong to the interval.
+func (x *intervalIndex) overlapping(from, to time.Time) []string {
+	hi := sort.Search(len(x.entries), func(i int) bool {
+		return !x.entries[i].start.Before(to)
+	})
+	lo := sort.Search(hi, func(i int) bool {
+		return !x.maxEnd[i].Before(from)
+	})
+
+	var ids []string
+	for _, entry := range x.entries[lo:hi] {
+		if entry.end.After(from) || !entry.start.Before(from) {
+			ids = append(ids, entry.id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,56 @@
+package memorystorage
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntervalIndex(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1)) //nolint:gosec
+	randomTime := func() time.Time {
+		return baseTime.Add(time.Duration(rnd.Intn(1000)) * time.Minute)
+	}
+
+	var x intervalIndex
+	events := make(map[string]storage.Event)
+	for i := 0; i < 500; i++ {
+		id := fmt.Sprint(i)
+		events[id] = newEvent(id, "user", randomTime(), time.Duration(rnd.Intn(120))*time.Minute)
+		x.insert(indexEntryOf(events[id]))
+	}
+	for i := 0; i < 100; i++ {
+		id := fmt.Sprint(rnd.Intn(500))
+		if event, ok := events[id]; ok {
+			x.remove(indexEntryOf(event))
+			delete(events, id)
+		}
+	}
+	require.Len(t, x.entries, len(events))
+
+	for i := 0; i < 1000; i++ {
+		from := randomTime()
+		to := from.Add(time.Duration(rnd.Intn(180)) * time.Minute)
+
+		var expected []storage.Event
+		for _, event := range events {
+			if event.In(from, to) {
+				expected = append(expected, event)
+			}
+		}
+		sort.Slice(expected, func(i, j int) bool {
+			return indexEntryOf(expected[i]).less(indexEntryOf(expected[j]))
+		})
+		expectedIDs := make([]string, 0, len(expected))
+		for _, event := range expected {
+			expectedIDs = append(expectedIDs, event.ID)
+		}
+
+		require.Equal(t, expectedIDs, append([]string{}, x.overlapping(from, to)...), "[%v, %v)", from, to)
+	}
+}
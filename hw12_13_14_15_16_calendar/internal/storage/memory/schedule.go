@@ -0,0 +1,69 @@
+package memorystorage
+
+import (
+	"container/heap"
+	"time"
+)
+
+type scheduleEntry struct {
+	at      time.Time
+	eventID string
+	index   int
+}
+
+// reminderSchedule is a min-heap of the moments the reminders are due next, so only
+// the due ones are looked at. Entries aren't removed when the reminders change, instead
+// the ones not matching the current moments of the reminders are skipped when they come.
+type reminderSchedule []scheduleEntry
+
+func (h reminderSchedule) Len() int           { return len(h) }
+func (h reminderSchedule) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h reminderSchedule) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *reminderSchedule) Push(x any) {
+	*h = append(*h, x.(scheduleEntry))
+}
+
+func (h *reminderSchedule) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return entry
+}
+
+// schedule sets the moments the reminders of the event are due next, zero means never.
+// Every moment which isn't zero has an entry in the heap.
+func (s *Storage) schedule(eventID string, next []time.Time) {
+	old := s.notifyAt[eventID]
+	for i, at := range next {
+		if !at.IsZero() && (i >= len(old) || !old[i].Equal(at)) {
+			heap.Push(&s.scheduled, scheduleEntry{at: at, eventID: eventID, index: i})
+		}
+	}
+	s.notifyAt[eventID] = next
+}
+
+// popDue removes the entries due at now from the heap and returns the ones current for
+// the reminders, the reminders are left unscheduled.
+func (s *Storage) popDue(now time.Time) []scheduleEntry {
+	var due []scheduleEntry
+	for len(s.scheduled) > 0 && !s.scheduled[0].at.After(now) {
+		entry := heap.Pop(&s.scheduled).(scheduleEntry)
+		next := s.notifyAt[entry.eventID]
+		if entry.index >= len(next) || !next[entry.index].Equal(entry.at) {
+			continue
+		}
+		next[entry.index] = time.Time{}
+		due = append(due, entry)
+	}
+	return due
+}
+
+// reschedule sets the moment the reminder left unscheduled by popDue is due next, zero means never.
+func (s *Storage) reschedule(entry scheduleEntry) {
+	if entry.at.IsZero() {
+		return
+	}
+	s.notifyAt[entry.eventID][entry.index] = entry.at
+	heap.Push(&s.scheduled, entry)
+}
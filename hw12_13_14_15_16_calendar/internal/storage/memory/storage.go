@@ -1,14 +1,483 @@
 package memorystorage
 
-import "sync"
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+)
 
 type Storage struct {
-	// TODO
-	mu sync.RWMutex //nolint:unused
+	mu sync.RWMutex
+
+	events map[string]storage.Event
+	// userEvents indexes single events of every owner by time, so neither range queries
+	// nor overlap checks have to scan all events. Series are kept apart in userSeries,
+	// as their occurrences are expanded on the fly.
+	userEvents map[string]*intervalIndex
+	userSeries map[string]map[string]struct{}
+	// overrides contains IDs of the overridden occurrences of every series.
+	overrides map[string]map[string]struct{}
+	// invitations contains IDs of the events every user attends without owning them.
+	invitations map[string]map[string]struct{}
+	// fired contains the start of the latest occurrence every reminder of an event
+	// has fired for, in the order of the active reminders of the event.
+	fired map[string][]time.Time
+	// notifyAt contains the moment every reminder of an event is due next, see storage.NextReminders,
+	// and scheduled orders them, so ListDueReminders doesn't look at the reminders which aren't due.
+	notifyAt  map[string][]time.Time
+	scheduled reminderSchedule
+
+	webhooks map[string]storage.Webhook
+	// deliveries contains deliveries of every webhook in the order they were added.
+	deliveries map[string][]storage.Delivery
+
+	// changes contains the audit log of every event ordered by revision.
+	changes map[string][]storage.Change
 }
 
 func New() *Storage {
-	return &Storage{}
+	return &Storage{
+		events:      make(map[string]storage.Event),
+		userEvents:  make(map[string]*intervalIndex),
+		userSeries:  make(map[string]map[string]struct{}),
+		overrides:   make(map[string]map[string]struct{}),
+		invitations: make(map[string]map[string]struct{}),
+		fired:       make(map[string][]time.Time),
+		notifyAt:    make(map[string][]time.Time),
+		webhooks:    make(map[string]storage.Webhook),
+		deliveries:  make(map[string][]storage.Delivery),
+		changes:     make(map[string][]storage.Change),
+	}
+}
+
+// Ping always succeeds, the storage lives within the process.
+func (s *Storage) Ping(_ context.Context) error {
+	return nil
+}
+
+func (s *Storage) CreateEvent(_ context.Context, event storage.Event, audit storage.Audit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.events[event.ID]; ok {
+		return storage.ErrEventAlreadyExists
+	}
+	if s.isBusy(event) {
+		return storage.ErrDateBusy
+	}
+	next, err := storage.NextReminders(event, time.Time{}, nil)
+	if err != nil {
+		return err
+	}
+
+	event.Version = 1
+	s.insert(event)
+	s.schedule(event.ID, next)
+	s.addChange(audit.Change(storage.Event{}, s.events[event.ID]))
+	return nil
+}
+
+// UpdateEvent replaces the event unless its version differs from the one of the passed event,
+// which is ErrVersionConflict. Zero version of the passed event matches any.
+func (s *Storage) UpdateEvent(_ context.Context, id string, event storage.Event, audit storage.Audit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.events[id]
+	if !ok {
+		return storage.ErrEventNotFound
+	}
+	if event.Version != 0 && event.Version != old.Version {
+		return storage.ErrVersionConflict
+	}
+
+	event.ID = id
+	event.Version = old.Version + 1
+	fired := storage.RescheduleReminders(old, event, s.fired[id])
+	next, err := storage.NextReminders(event, time.Time{}, fired)
+	if err != nil {
+		return err
+	}
+
+	s.remove(old)
+	if s.isBusy(event) {
+		s.insert(old)
+		return storage.ErrDateBusy
+	}
+
+	s.insert(event)
+	s.fired[id] = fired
+	s.schedule(id, next)
+	s.addChange(audit.Change(old, s.events[id]))
+	return nil
 }
 
-// TODO
+// DeleteEvent deletes the event. Deletion of a series deletes its overrides as well.
+func (s *Storage) DeleteEvent(_ context.Context, id string, audit storage.Audit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, ok := s.events[id]
+	if !ok {
+		return storage.ErrEventNotFound
+	}
+
+	for _, deleted := range s.delete(event) {
+		s.addChange(audit.Change(deleted, storage.Event{}))
+	}
+	return nil
+}
+
+func (s *Storage) GetEvent(_ context.Context, id string) (storage.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	event, ok := s.events[id]
+	if !ok {
+		return storage.Event{}, storage.ErrEventNotFound
+	}
+	return event, nil
+}
+
+// ListEvents returns events of the user intersecting [from, to) ordered by start time,
+// both the ones the user owns and the ones the user attends. Events crossing any of the boundaries
+// are included as well. Series are expanded into occurrences.
+func (s *Storage) ListEvents(_ context.Context, userID string, from, to time.Time) ([]storage.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var singles []storage.Event
+	if index, ok := s.userEvents[userID]; ok {
+		for _, id := range index.overlapping(from, to) {
+			singles = append(singles, s.events[id])
+		}
+	}
+
+	var series []storage.Event
+	for id := range s.userSeries[userID] {
+		if event := s.events[id]; event.StartAt.Before(to) {
+			series = append(series, event)
+		}
+	}
+
+	// invitations are few compared to own events, so they aren't indexed by time
+	for id := range s.invitations[userID] {
+		event := s.events[id]
+		switch {
+		case event.Recurring():
+			if event.StartAt.Before(to) {
+				series = append(series, event)
+			}
+		case event.In(from, to):
+			singles = append(singles, event)
+		}
+	}
+
+	return storage.ExpandSeries(singles, series, s.overridden(series), from, to)
+}
+
+// ListUserEvents returns all the events of the user as they are stored, series aren't expanded.
+// Events are ordered by start time.
+func (s *Storage) ListUserEvents(_ context.Context, userID string) ([]storage.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []storage.Event
+	for _, event := range s.events {
+		if event.UserID == userID {
+			events = append(events, event)
+		}
+	}
+
+	storage.SortEvents(events)
+	return events, nil
+}
+
+func (s *Storage) ListEventsForDay(ctx context.Context, userID string, date time.Time) ([]storage.Event, error) {
+	from, to := storage.DayPeriod(date)
+	return s.ListEvents(ctx, userID, from, to)
+}
+
+func (s *Storage) ListEventsForWeek(ctx context.Context, userID string, date time.Time) ([]storage.Event, error) {
+	from, to := storage.WeekPeriod(date)
+	return s.ListEvents(ctx, userID, from, to)
+}
+
+func (s *Storage) ListEventsForMonth(ctx context.Context, userID string, date time.Time) ([]storage.Event, error) {
+	from, to := storage.MonthPeriod(date)
+	return s.ListEvents(ctx, userID, from, to)
+}
+
+// ListDueReminders returns reminders about events and occurrences which are due at now
+// and haven't fired yet, ordered by the start of the events. Only the reminders whose moment
+// has come are looked at, those of them found not due are scheduled for their next occurrence,
+// so now isn't expected to go back between calls.
+func (s *Storage) ListDueReminders(_ context.Context, now time.Time) ([]storage.DueReminder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := s.popDue(now)
+	indexes := make(map[string][]int)
+	for _, entry := range entries {
+		indexes[entry.eventID] = append(indexes[entry.eventID], entry.index)
+	}
+
+	var reminders []storage.DueReminder
+	next := make(map[string][]time.Time, len(indexes))
+	for id, popped := range indexes {
+		event := s.events[id]
+		due, err := storage.DueReminders(event, s.overridden([]storage.Event{event})[id], now, s.fired[id])
+		if err == nil {
+			next[id], err = storage.NextReminders(event, now, s.fired[id])
+		}
+		if err != nil {
+			for _, entry := range entries {
+				s.reschedule(entry)
+			}
+			return nil, err
+		}
+
+		for _, d := range due {
+			if slices.Contains(popped, d.Index) {
+				reminders = append(reminders, d)
+			}
+		}
+	}
+	for _, entry := range entries {
+		entry.at = next[entry.eventID][entry.index]
+		s.reschedule(entry)
+	}
+
+	storage.SortDueReminders(reminders)
+	return reminders, nil
+}
+
+// MarkReminderFired excludes the due reminder of the event, or of the occurrences of a series starting
+// up to the one due, from ListDueReminders results until the event is moved or the reminder is changed.
+// It's ErrReminderNotFound if the event doesn't have the reminder at its index anymore.
+func (s *Storage) MarkReminderFired(_ context.Context, due storage.DueReminder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := due.Event.ID
+	event, ok := s.events[id]
+	if !ok {
+		return storage.ErrEventNotFound
+	}
+	if !event.HasReminder(due) {
+		return storage.ErrReminderNotFound
+	}
+
+	reminders := event.ActiveReminders()
+	fired := slices.Clone(s.fired[id])
+	if len(fired) < len(reminders) {
+		fired = append(fired, make([]time.Time, len(reminders)-len(fired))...)
+	}
+	if fired[due.Index].Before(due.Event.StartAt) {
+		fired[due.Index] = due.Event.StartAt
+	}
+	next, err := storage.NextReminders(event, time.Time{}, fired)
+	if err != nil {
+		return err
+	}
+
+	s.fired[id] = fired
+	// the other reminders keep their moments
+	scheduled := slices.Clone(s.notifyAt[id])
+	scheduled[due.Index] = next[due.Index]
+	s.schedule(id, scheduled)
+	return nil
+}
+
+// DeleteEventsBefore deletes at most limit events which ended before the given moment,
+// the oldest first, and returns the number of deleted events. A series ends with its last
+// occurrence, so infinite ones are never deleted. The audit logs of the events are deleted
+// with them, and so are at most limit logs of the events deleted before the moment.
+func (s *Storage) DeleteEventsBefore(_ context.Context, before time.Time, limit int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events, err := s.endedBefore(before)
+	if err != nil {
+		return 0, err
+	}
+	if len(events) > limit {
+		events = events[:limit]
+	}
+
+	var deleted int
+	for _, event := range events {
+		// the event could be an override deleted along with its series
+		if _, ok := s.events[event.ID]; !ok {
+			continue
+		}
+		for _, event := range s.delete(event) {
+			delete(s.changes, event.ID)
+			deleted++
+		}
+	}
+	s.purgeChanges(before, limit)
+	return deleted, nil
+}
+
+// CountEventsBefore returns the number of events which ended before the given moment.
+func (s *Storage) CountEventsBefore(_ context.Context, before time.Time) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events, err := s.endedBefore(before)
+	return len(events), err
+}
+
+type endedEvent struct {
+	storage.Event
+	end time.Time
+}
+
+func (s *Storage) endedBefore(before time.Time) ([]storage.Event, error) {
+	var ended []endedEvent
+	for _, event := range s.events {
+		end, ok, err := storage.LastEnd(event)
+		if err != nil {
+			return nil, err
+		}
+		if ok && end.Before(before) {
+			ended = append(ended, endedEvent{Event: event, end: end})
+		}
+	}
+
+	slices.SortFunc(ended, func(a, b endedEvent) int {
+		if c := a.end.Compare(b.end); c != 0 {
+			return c
+		}
+		if a.ID < b.ID {
+			return -1
+		}
+		if a.ID > b.ID {
+			return 1
+		}
+		return 0
+	})
+
+	events := make([]storage.Event, 0, len(ended))
+	for _, e := range ended {
+		events = append(events, e.Event)
+	}
+	return events, nil
+}
+
+// isBusy reports whether the event overlaps another event of the same owner.
+// Series and their overrides aren't checked, as a series has no end in general.
+func (s *Storage) isBusy(event storage.Event) bool {
+	if event.Recurring() || event.SeriesID != "" {
+		return false
+	}
+
+	index, ok := s.userEvents[event.UserID]
+	if !ok {
+		return false
+	}
+
+	for _, id := range index.overlapping(event.StartAt, event.EndAt()) {
+		if other := s.events[id]; other.SeriesID == "" && other.Overlaps(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// overridden returns the original starts of the overridden occurrences of the series.
+func (s *Storage) overridden(series []storage.Event) storage.Overrides {
+	overrides := make(storage.Overrides)
+	for _, event := range series {
+		for id := range s.overrides[event.ID] {
+			overrides[event.ID] = append(overrides[event.ID], s.events[id].RecurrenceID)
+		}
+	}
+	return overrides
+}
+
+func (s *Storage) insert(event storage.Event) {
+	// keep the same representation as the SQL storage does
+	event = event.UTC()
+	event.Attendees = slices.Clone(event.Attendees)
+	event.Reminders = slices.Clone(event.Reminders)
+	s.events[event.ID] = event
+
+	for _, attendee := range event.Attendees {
+		addToSet(s.invitations, attendee.UserID, event.ID)
+	}
+	if event.SeriesID != "" {
+		addToSet(s.overrides, event.SeriesID, event.ID)
+	}
+	if event.Recurring() {
+		addToSet(s.userSeries, event.UserID, event.ID)
+		return
+	}
+
+	index, ok := s.userEvents[event.UserID]
+	if !ok {
+		index = &intervalIndex{}
+		s.userEvents[event.UserID] = index
+	}
+	index.insert(indexEntryOf(event))
+}
+
+func (s *Storage) remove(event storage.Event) {
+	delete(s.events, event.ID)
+
+	for _, attendee := range event.Attendees {
+		removeFromSet(s.invitations, attendee.UserID, event.ID)
+	}
+	if event.SeriesID != "" {
+		removeFromSet(s.overrides, event.SeriesID, event.ID)
+	}
+	if event.Recurring() {
+		removeFromSet(s.userSeries, event.UserID, event.ID)
+		return
+	}
+
+	index := s.userEvents[event.UserID]
+	index.remove(indexEntryOf(event))
+	if len(index.entries) == 0 {
+		delete(s.userEvents, event.UserID)
+	}
+}
+
+// delete removes the event for good along with the overrides of a series
+// and returns the number of removed events.
+// delete removes the event along with its overrides and returns all of them.
+func (s *Storage) delete(event storage.Event) []storage.Event {
+	var deleted []storage.Event
+	for id := range s.overrides[event.ID] {
+		deleted = append(deleted, s.delete(s.events[id])...)
+	}
+
+	s.remove(event)
+	delete(s.fired, event.ID)
+	delete(s.notifyAt, event.ID)
+	return append(deleted, event)
+}
+
+func addToSet(sets map[string]map[string]struct{}, key, id string) {
+	set, ok := sets[key]
+	if !ok {
+		set = make(map[string]struct{})
+		sets[key] = set
+	}
+	set[id] = struct{}{}
+}
+
+func removeFromSet(sets map[string]map[string]struct{}, key, id string) {
+	delete(sets[key], id)
+	if len(sets[key]) == 0 {
+		delete(sets, key)
+	}
+}
+
+func indexEntryOf(event storage.Event) indexEntry {
+	return indexEntry{start: event.StartAt, end: event.EndAt(), id: event.ID}
+}
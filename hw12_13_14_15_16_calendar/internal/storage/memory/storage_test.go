@@ -1,7 +1,65 @@
 package memorystorage
 
-import "testing"
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	baseTime = storagetest.BaseTime
+	newEvent = storagetest.NewEvent
+)
 
 func TestStorage(t *testing.T) {
-	// TODO
+	storagetest.Run(t, func(*testing.T) storagetest.Storage {
+		return New()
+	})
+}
+
+func TestStorageConcurrency(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			id := fmt.Sprint(i)
+			event := newEvent(id, "user", baseTime.Add(time.Duration(i)*time.Hour), time.Hour)
+			require.NoError(t, s.CreateEvent(ctx, event, storage.Audit{}))
+			_, err := s.GetEvent(ctx, id)
+			require.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	events, err := s.ListEvents(ctx, "user", baseTime, baseTime.Add(100*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, events, 100)
+}
+
+func BenchmarkListEvents(b *testing.B) {
+	ctx := context.Background()
+	s := New()
+	for i := 0; i < 50_000; i++ {
+		event := newEvent(fmt.Sprint(i), "user", baseTime.Add(time.Duration(i)*time.Hour), time.Hour)
+		require.NoError(b, s.CreateEvent(ctx, event, storage.Audit{}))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		date := baseTime.Add(time.Duration(i%50_000) * time.Hour)
+		if _, err := s.ListEventsForWeek(ctx, "user", date); err != nil {
+			b.Fatal(err)
+		}
+	}
 }
@@ -0,0 +1,90 @@
+package memorystorage
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+)
+
+func (s *Storage) CreateWebhook(_ context.Context, webhook storage.Webhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhook.CreatedAt = webhook.CreatedAt.UTC()
+	s.webhooks[webhook.ID] = webhook
+	return nil
+}
+
+func (s *Storage) GetWebhook(_ context.Context, id string) (storage.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	webhook, ok := s.webhooks[id]
+	if !ok {
+		return storage.Webhook{}, storage.ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// ListWebhooks returns webhooks of the user in the order of creation.
+func (s *Storage) ListWebhooks(_ context.Context, userID string) ([]storage.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var webhooks []storage.Webhook
+	for _, webhook := range s.webhooks {
+		if webhook.UserID == userID {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	slices.SortFunc(webhooks, func(a, b storage.Webhook) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return webhooks, nil
+}
+
+// DeleteWebhook deletes the webhook along with its deliveries.
+func (s *Storage) DeleteWebhook(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.webhooks[id]; !ok {
+		return storage.ErrWebhookNotFound
+	}
+	delete(s.webhooks, id)
+	delete(s.deliveries, id)
+	return nil
+}
+
+func (s *Storage) AddDelivery(_ context.Context, delivery storage.Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery.StartAt = delivery.StartAt.UTC()
+	delivery.DeliveredAt = delivery.DeliveredAt.UTC()
+	s.deliveries[delivery.WebhookID] = append(s.deliveries[delivery.WebhookID], delivery)
+	return nil
+}
+
+// ListDeliveries returns at most limit latest deliveries to the webhook, the latest first.
+func (s *Storage) ListDeliveries(_ context.Context, webhookID string, limit int) ([]storage.Delivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deliveries := slices.Clone(s.deliveries[webhookID])
+	slices.SortFunc(deliveries, func(a, b storage.Delivery) int {
+		if c := b.DeliveredAt.Compare(a.DeliveredAt); c != 0 {
+			return c
+		}
+		if c := b.Attempt - a.Attempt; c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return deliveries[:min(limit, len(deliveries))], nil
+}
@@ -0,0 +1,27 @@
+package storage
+
+import "time"
+
+// DayPeriod returns the interval [from, to) of the day containing date.
+// Boundaries are calculated in the location of date.
+func DayPeriod(date time.Time) (from, to time.Time) {
+	from = startOfDay(date)
+	return from, from.AddDate(0, 0, 1)
+}
+
+// WeekPeriod returns the interval of seven days beginning with the day containing date.
+func WeekPeriod(date time.Time) (from, to time.Time) {
+	from = startOfDay(date)
+	return from, from.AddDate(0, 0, 7)
+}
+
+// MonthPeriod returns the interval of one month beginning with the day containing date.
+func MonthPeriod(date time.Time) (from, to time.Time) {
+	from = startOfDay(date)
+	return from, from.AddDate(0, 1, 0)
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/rrule"
+)
+
+// Overrides maps a series ID to the original starts of its overridden occurrences.
+type Overrides map[string][]time.Time
+
+// Occurrences returns occurrences of the series intersecting [from, to). Exception dates
+// and overridden occurrences are skipped, as the overrides are stored as separate events.
+func Occurrences(series Event, overridden []time.Time, from, to time.Time) ([]Event, error) {
+	rule, err := rrule.Parse(series.RRule)
+	if err != nil {
+		return nil, fmt.Errorf("event %s: %w", series.ID, err)
+	}
+
+	var events []Event
+	for _, start := range rule.Between(series.localStart(), from.Add(-series.Duration), to) {
+		occurrence := series.occurrence(start)
+		if !occurrence.In(from, to) || containsTime(series.ExDates, start) || containsTime(overridden, start) {
+			continue
+		}
+		events = append(events, occurrence)
+	}
+	return events, nil
+}
+
+// DueReminders returns the reminders of the event due at now. A series is expanded into
+// the occurrences whose reminders are due. firedUntil holds the start of the latest occurrence
+// every reminder has fired for, the occurrences starting at or before it are skipped.
+func DueReminders(event Event, overridden []time.Time, now time.Time, firedUntil []time.Time) ([]DueReminder, error) {
+	reminders := event.ActiveReminders()
+	if len(reminders) == 0 {
+		return nil, nil
+	}
+	fired := func(i int, start time.Time) bool {
+		return i < len(firedUntil) && !start.After(firedUntil[i])
+	}
+
+	var due []DueReminder
+	if !event.Recurring() {
+		for i, r := range reminders {
+			if event.ReminderDue(r, now) && !fired(i, event.StartAt) {
+				due = append(due, DueReminder{Event: event, Index: i})
+			}
+		}
+		return due, nil
+	}
+
+	rule, err := rrule.Parse(event.RRule)
+	if err != nil {
+		return nil, fmt.Errorf("event %s: %w", event.ID, err)
+	}
+	for i, r := range reminders {
+		// occurrences starting within (now, now+Before]
+		for _, start := range rule.Between(event.localStart(), now.Add(1), now.Add(r.Before+1)) {
+			if fired(i, start) || containsTime(event.ExDates, start) || containsTime(overridden, start) {
+				continue
+			}
+			due = append(due, DueReminder{Event: event.occurrence(start), Index: i})
+		}
+	}
+	return due, nil
+}
+
+// NextReminders returns the moment every active reminder of the event is due next, zero if never:
+// the one for the first occurrence starting after both now and the occurrence the reminder has fired for.
+// A moment at or before now means the reminder is due. Overridden occurrences aren't skipped here,
+// so a moment may come too early, but never too late, DueReminders tells what's actually due then.
+func NextReminders(event Event, now time.Time, firedUntil []time.Time) ([]time.Time, error) {
+	reminders := event.ActiveReminders()
+	next := make([]time.Time, len(reminders))
+
+	var rule rrule.Rule
+	if event.Recurring() {
+		var err error
+		if rule, err = rrule.Parse(event.RRule); err != nil {
+			return nil, fmt.Errorf("event %s: %w", event.ID, err)
+		}
+	}
+
+	for i, r := range reminders {
+		if r.Before <= 0 {
+			continue
+		}
+		after := now
+		if i < len(firedUntil) && firedUntil[i].After(after) {
+			after = firedUntil[i]
+		}
+
+		if !event.Recurring() {
+			if event.StartAt.After(after) {
+				next[i] = event.RemindAt(r).UTC()
+			}
+			continue
+		}
+		for {
+			start, ok := rule.After(event.localStart(), after)
+			if !ok {
+				break
+			}
+			if !containsTime(event.ExDates, start) {
+				next[i] = start.Add(-r.Before).UTC()
+				break
+			}
+			after = start
+		}
+	}
+	return next, nil
+}
+
+// ExpandSeries merges single events with the occurrences of the series intersecting [from, to).
+// The result is ordered by start time.
+func ExpandSeries(singles, series []Event, overrides Overrides, from, to time.Time) ([]Event, error) {
+	events := singles
+	for _, s := range series {
+		occurrences, err := Occurrences(s, overrides[s.ID], from, to)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, occurrences...)
+	}
+
+	SortEvents(events)
+	return events, nil
+}
+
+// LastEnd returns the end of the last occurrence of the event, ok is false for an infinite series.
+func LastEnd(event Event) (end time.Time, ok bool, err error) {
+	if !event.Recurring() {
+		return event.EndAt(), true, nil
+	}
+
+	rule, err := rrule.Parse(event.RRule)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("event %s: %w", event.ID, err)
+	}
+	last, ok := rule.Last(event.localStart())
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	return last.UTC().Add(event.Duration), true, nil
+}
+
+func SortEvents(events []Event) {
+	slices.SortFunc(events, func(a, b Event) int {
+		if c := a.StartAt.Compare(b.StartAt); c != 0 {
+			return c
+		}
+		if a.ID < b.ID {
+			return -1
+		}
+		if a.ID > b.ID {
+			return 1
+		}
+		return 0
+	})
+}
+
+// localStart returns the start of the series in its time zone, the rule is applied
+// to the local time, so that occurrences don't move across DST transitions.
+func (e Event) localStart() time.Time {
+	return e.StartAt.In(e.Location())
+}
+
+func (e Event) occurrence(start time.Time) Event {
+	start = start.UTC()
+	e.StartAt = start
+	e.RecurrenceID = start
+	e.ExDates = nil
+	return e
+}
+
+func containsTime(times []time.Time, t time.Time) bool {
+	return slices.ContainsFunc(times, t.Equal)
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"slices"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/rrule"
+)
+
+// Reminder asks to notify the recipients of the event some time before it starts.
+type Reminder struct {
+	Before time.Duration
+	// Channel is the name of the sender channel to deliver the reminder through, empty means all of them.
+	Channel string
+}
+
+// DueReminder is a reminder whose time has come for an event or an occurrence of a series.
+type DueReminder struct {
+	Event Event
+	// Index is the index of the reminder within the active reminders of the event.
+	Index int
+}
+
+// Reminder returns the reminder which is due.
+func (d DueReminder) Reminder() Reminder {
+	return d.Event.ActiveReminders()[d.Index]
+}
+
+// ActiveReminders returns the reminders of the event, or the one NotifyBefore stands for if there are none.
+func (e Event) ActiveReminders() []Reminder {
+	if len(e.Reminders) > 0 {
+		return e.Reminders
+	}
+	if e.NotifyBefore > 0 {
+		return []Reminder{{Before: e.NotifyBefore}}
+	}
+	return nil
+}
+
+// HasReminder reports whether the event still has the due reminder at its index.
+func (e Event) HasReminder(d DueReminder) bool {
+	due, current := d.Event.ActiveReminders(), e.ActiveReminders()
+	return d.Index >= 0 && d.Index < len(due) && d.Index < len(current) && due[d.Index] == current[d.Index]
+}
+
+// RemindAt returns the moment the reminder about the event is due.
+func (e Event) RemindAt(r Reminder) time.Time {
+	return e.StartAt.Add(-r.Before)
+}
+
+// ReminderDue reports whether the reminder about the event is due at now,
+// i.e. its time has come while the event itself hasn't started.
+func (e Event) ReminderDue(r Reminder, now time.Time) bool {
+	return r.Before > 0 && !e.RemindAt(r).After(now) && e.StartAt.After(now)
+}
+
+// RescheduleReminders returns the fired state of the reminders of the updated event given the one
+// of its previous version, see DueReminders. A reminder keeps its state if it's still there and
+// the occurrence it has fired for still starts at the same time, so it isn't sent again.
+// New and changed reminders and the ones of moved occurrences are pending again.
+func RescheduleReminders(old, updated Event, firedUntil []time.Time) []time.Time {
+	previous := old.ActiveReminders()
+	reminders := updated.ActiveReminders()
+	fired := make([]time.Time, len(reminders))
+	taken := make([]bool, len(previous))
+	for i, r := range reminders {
+		for j, p := range previous {
+			if p != r || taken[j] {
+				continue
+			}
+			taken[j] = true
+			if j < len(firedUntil) && !firedUntil[j].IsZero() && updated.occursAt(firedUntil[j]) {
+				fired[i] = firedUntil[j]
+			}
+			break
+		}
+	}
+	return fired
+}
+
+// occursAt reports whether the event or an occurrence of the series starts at t.
+func (e Event) occursAt(t time.Time) bool {
+	if !e.Recurring() {
+		return e.StartAt.Equal(t)
+	}
+	rule, err := rrule.Parse(e.RRule)
+	if err != nil {
+		return false
+	}
+	starts := rule.Between(e.localStart(), t, t.Add(1))
+	return len(starts) > 0 && starts[0].Equal(t) && !containsTime(e.ExDates, t)
+}
+
+// SortDueReminders orders reminders by the start of their events, then by the reminders of an event.
+func SortDueReminders(reminders []DueReminder) {
+	slices.SortFunc(reminders, func(a, b DueReminder) int {
+		if c := a.Event.StartAt.Compare(b.Event.StartAt); c != 0 {
+			return c
+		}
+		if a.Event.ID < b.Event.ID {
+			return -1
+		}
+		if a.Event.ID > b.Event.ID {
+			return 1
+		}
+		return a.Index - b.Index
+	})
+}
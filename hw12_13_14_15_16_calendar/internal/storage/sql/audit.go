@@ -0,0 +1,113 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+)
+
+const changeColumns = "event_id, revision, user_id, actor_id, action, changed_at, fields, event, restored_from"
+
+// AddChange appends the change to the audit log of the event, assigning the next revision to it.
+// Changes of the events of the same owner are serialized, so revisions don't collide.
+func (s *Storage) AddChange(ctx context.Context, change storage.Change) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.lockUser(ctx, tx, change.UserID); err != nil {
+			return err
+		}
+		return addChange(ctx, tx, change)
+	})
+}
+
+// addChange appends the change within the transaction, which must hold the lock of the event owner.
+func addChange(ctx context.Context, tx *sql.Tx, change storage.Change) error {
+	fields, err := json.Marshal(change.Fields)
+	if err != nil {
+		return fmt.Errorf("failed to marshal changed fields: %w", err)
+	}
+	event, err := json.Marshal(change.Event.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO event_changes ("+changeColumns+") "+
+			"SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4, $5, $6, $7, $8 "+
+			"FROM event_changes WHERE event_id = $1",
+		change.EventID, change.UserID, change.ActorID, string(change.Action), change.ChangedAt.UTC(),
+		string(fields), string(event), change.RestoredFrom,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert event change: %w", err)
+	}
+	return nil
+}
+
+// deletedLogs returns the last changes of at most limit audit logs which end with a deletion
+// made before the given moment, the earliest deleted first. The events of those are gone.
+func deletedLogs(ctx context.Context, tx *sql.Tx, before time.Time, limit int) ([]storage.Change, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT event_id, user_id FROM event_changes c WHERE action = $1 AND changed_at < $2 "+
+			"AND revision = (SELECT MAX(revision) FROM event_changes WHERE event_id = c.event_id) "+
+			"ORDER BY changed_at, event_id LIMIT $3",
+		string(storage.ActionDelete), before.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list logs of deleted events: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []storage.Change
+	for rows.Next() {
+		var change storage.Change
+		if err := rows.Scan(&change.EventID, &change.UserID); err != nil {
+			return nil, fmt.Errorf("failed to list logs of deleted events: %w", err)
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list logs of deleted events: %w", err)
+	}
+	return changes, nil
+}
+
+// ListChanges returns the audit log of the event, the earliest change first.
+func (s *Storage) ListChanges(ctx context.Context, eventID string) ([]storage.Change, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+changeColumns+" FROM event_changes WHERE event_id = $1 ORDER BY revision", eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list event changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []storage.Change
+	for rows.Next() {
+		var (
+			change        storage.Change
+			action        string
+			fields, event string
+		)
+		err := rows.Scan(&change.EventID, &change.Revision, &change.UserID, &change.ActorID, &action,
+			&change.ChangedAt, &fields, &event, &change.RestoredFrom)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list event changes: %w", err)
+		}
+		change.Action = storage.Action(action)
+		change.ChangedAt = change.ChangedAt.UTC()
+		if err := json.Unmarshal([]byte(fields), &change.Fields); err != nil {
+			return nil, fmt.Errorf("malformed fields of change %d of event %s: %w", change.Revision, eventID, err)
+		}
+		if err := json.Unmarshal([]byte(event), &change.Event); err != nil {
+			return nil, fmt.Errorf("malformed event of change %d of event %s: %w", change.Revision, eventID, err)
+		}
+		change.Event = change.Event.UTC()
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list event changes: %w", err)
+	}
+	return changes, nil
+}
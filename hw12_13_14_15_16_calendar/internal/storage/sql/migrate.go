@@ -0,0 +1,68 @@
+package sqlstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/migrations"
+	"github.com/pressly/goose/v3"
+)
+
+var ErrUnknownMigrateCommand = errors.New("unknown migrate command")
+
+var gooseDialects = map[string]goose.Dialect{
+	DriverPostgres: goose.DialectPostgres,
+	DriverSQLite:   goose.DialectSQLite3,
+}
+
+// Migrate runs a migration command and reports its results to out. Supported commands are:
+// up (apply all pending migrations), down (roll back the latest one), reset (roll back all of them)
+// and status.
+func (s *Storage) Migrate(ctx context.Context, command string, out io.Writer) error {
+	provider, err := goose.NewProvider(gooseDialects[s.driver], s.db, migrations.FS,
+		goose.WithGoMigrations(migrations.Go...))
+	if err != nil {
+		return fmt.Errorf("failed to initialize migrations: %w", err)
+	}
+
+	switch command {
+	case "up":
+		results, err := provider.Up(ctx)
+		for _, result := range results {
+			fmt.Fprintln(out, result)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to apply migrations: %w", err)
+		}
+	case "down":
+		result, err := provider.Down(ctx)
+		if result != nil {
+			fmt.Fprintln(out, result)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to roll back migration: %w", err)
+		}
+	case "reset":
+		results, err := provider.DownTo(ctx, 0)
+		for _, result := range results {
+			fmt.Fprintln(out, result)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to roll back migrations: %w", err)
+		}
+	case "status":
+		statuses, err := provider.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get migrations status: %w", err)
+		}
+		for _, status := range statuses {
+			fmt.Fprintf(out, "%-10s %05d %s\n", status.State, status.Source.Version, status.Source.Path)
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownMigrateCommand, command)
+	}
+
+	return nil
+}
@@ -1,20 +1,953 @@
 package sqlstorage
 
-import "context"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
-type Storage struct { // TODO
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+	_ "github.com/jackc/pgx/v5/stdlib" // PostgreSQL driver
+	_ "modernc.org/sqlite"             // SQLite driver
+)
+
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+var (
+	ErrUnknownDriver = errors.New("unknown database driver")
+	ErrNotConnected  = errors.New("storage is not connected")
+)
+
+type dialect struct {
+	sqlDriver string
+	// lockUser serializes modifications of the user's events within a transaction,
+	// so concurrent overlap checks can't miss each other.
+	lockUser string
 }
 
-func New() *Storage {
-	return &Storage{}
+var dialects = map[string]dialect{
+	DriverPostgres: {
+		sqlDriver: "pgx",
+		lockUser:  "SELECT pg_advisory_xact_lock(hashtext($1))",
+	},
+	DriverSQLite: {
+		// SQLite serializes writers on its own, and the pool is limited by the single connection.
+		sqlDriver: "sqlite",
+	},
+}
+
+type Storage struct {
+	driver string
+	dsn    string
+
+	dialect dialect
+	db      *sql.DB
+}
+
+func New(driver, dsn string) *Storage {
+	return &Storage{
+		driver: driver,
+		dsn:    dsn,
+	}
 }
 
 func (s *Storage) Connect(ctx context.Context) error {
-	// TODO
+	d, ok := dialects[s.driver]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownDriver, s.driver)
+	}
+
+	db, err := sql.Open(d.sqlDriver, s.dsn)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	if s.driver == DriverSQLite {
+		db.SetMaxOpenConns(1)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	s.dialect = d
+	s.db = db
 	return nil
 }
 
-func (s *Storage) Close(ctx context.Context) error {
-	// TODO
+// Ping checks that the database is still reachable.
+func (s *Storage) Ping(ctx context.Context) error {
+	if s.db == nil {
+		return ErrNotConnected
+	}
+	return s.db.PingContext(ctx)
+}
+
+func (s *Storage) Close(_ context.Context) error {
+	if s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// eventsBatch limits the number of events queried by their IDs at once,
+// so that the number of query parameters stays within the limits of the databases.
+const eventsBatch = 500
+
+const eventColumns = "id, user_id, title, description, start_at, end_at, notify_before, " +
+	"rrule, exdates, series_id, recurrence_id, time_zone, version, reminders"
+
+// dependentTables contain rows which belong to events and are deleted along with them.
+var dependentTables = []string{"attendees", "reminder_schedule"}
+
+func (s *Storage) CreateEvent(ctx context.Context, event storage.Event, audit storage.Audit) error {
+	event.Version = 1
+	row, err := eventRow(event)
+	if err != nil {
+		return err
+	}
+
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.lockUser(ctx, tx, event.UserID); err != nil {
+			return err
+		}
+
+		var exists bool
+		err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM events WHERE id = $1)", event.ID).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to check event existence: %w", err)
+		}
+		if exists {
+			return storage.ErrEventAlreadyExists
+		}
+
+		if err := isBusy(ctx, tx, event); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO events ("+eventColumns+", last_end_at) "+
+				"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)",
+			row...,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert event: %w", err)
+		}
+		if err := saveAttendees(ctx, tx, event); err != nil {
+			return err
+		}
+		if err := saveReminders(ctx, tx, event, nil); err != nil {
+			return err
+		}
+		return addChange(ctx, tx, audit.Change(storage.Event{}, event))
+	})
+}
+
+// UpdateEvent replaces the event unless its version differs from the one of the passed event,
+// which is ErrVersionConflict. Zero version of the passed event matches any.
+func (s *Storage) UpdateEvent(ctx context.Context, id string, event storage.Event, audit storage.Audit) error {
+	event.ID = id
+	row, err := eventRow(event)
+	if err != nil {
+		return err
+	}
+
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.lockUser(ctx, tx, event.UserID); err != nil {
+			return err
+		}
+		old, err := eventInTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if event.Version != 0 && event.Version != old.Version {
+			return storage.ErrVersionConflict
+		}
+		if err := isBusy(ctx, tx, event); err != nil {
+			return err
+		}
+		fired, err := firedReminders(ctx, tx, "event_id = $1", id)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx,
+			`UPDATE events
+			SET user_id = $2, title = $3, description = $4, start_at = $5, end_at = $6, notify_before = $7,
+				rrule = $8, exdates = $9, series_id = $10, recurrence_id = $11, time_zone = $12,
+				version = version + 1, reminders = $14, last_end_at = $15
+			WHERE id = $1 AND ($13 = 0 OR version = $13)`,
+			row...,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update event: %w", err)
+		}
+		if err := checkAffected(result); err != nil {
+			return err
+		}
+
+		event.Version = old.Version + 1
+		if err := saveAttendees(ctx, tx, event); err != nil {
+			return err
+		}
+		if err := saveReminders(ctx, tx, event, storage.RescheduleReminders(old, event, fired[id])); err != nil {
+			return err
+		}
+		return addChange(ctx, tx, audit.Change(old, event))
+	})
+}
+
+// DeleteEvent deletes the event. Deletion of a series deletes its overrides as well,
+// every deleted event gets the deletion into its audit log.
+func (s *Storage) DeleteEvent(ctx context.Context, id string, audit storage.Audit) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		var userID string
+		err := tx.QueryRowContext(ctx, "SELECT user_id FROM events WHERE id = $1", id).Scan(&userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrEventNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get event: %w", err)
+		}
+		if err := s.lockUser(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		events, err := scanEvents(ctx, tx, "SELECT "+eventColumns+" FROM events WHERE id = $1 OR series_id = $1", id)
+		if err != nil {
+			return fmt.Errorf("failed to get event: %w", err)
+		}
+		if len(events) == 0 {
+			return storage.ErrEventNotFound
+		}
+		if err := loadAttendees(ctx, tx, events); err != nil {
+			return err
+		}
+		if err := deleteEvents(ctx, tx, events, dependentTables); err != nil {
+			return err
+		}
+		for _, event := range events {
+			if err := addChange(ctx, tx, audit.Change(event, storage.Event{})); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Storage) GetEvent(ctx context.Context, id string) (storage.Event, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+eventColumns+" FROM events WHERE id = $1", id)
+
+	event, err := scanEvent(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Event{}, storage.ErrEventNotFound
+	}
+	if err != nil {
+		return storage.Event{}, fmt.Errorf("failed to get event: %w", err)
+	}
+
+	events := []storage.Event{event}
+	if err := loadAttendees(ctx, s.db, events); err != nil {
+		return storage.Event{}, err
+	}
+	return events[0], nil
+}
+
+// ListEvents returns events of the user intersecting [from, to) ordered by start time,
+// both the ones the user owns and the ones the user attends. Events crossing any of the boundaries
+// are included as well. Series are expanded into occurrences.
+func (s *Storage) ListEvents(ctx context.Context, userID string, from, to time.Time) ([]storage.Event, error) {
+	const visible = "(user_id = $1 OR id IN (SELECT event_id FROM attendees WHERE user_id = $1))"
+
+	singles, err := s.queryEvents(ctx,
+		`SELECT `+eventColumns+` FROM events
+		WHERE `+visible+` AND rrule = '' AND start_at < $3 AND (end_at > $2 OR start_at >= $2)`,
+		userID, from.UTC(), to.UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+
+	series, err := s.queryEvents(ctx,
+		`SELECT `+eventColumns+` FROM events WHERE `+visible+` AND rrule <> '' AND start_at < $2`,
+		userID, to.UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list series: %w", err)
+	}
+
+	overrides, err := s.overrides(ctx,
+		"(user_id = $1 OR series_id IN (SELECT event_id FROM attendees WHERE user_id = $1))", userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.ExpandSeries(singles, series, overrides, from, to)
+}
+
+// ListUserEvents returns all the events of the user as they are stored, series aren't expanded.
+// Events are ordered by start time.
+func (s *Storage) ListUserEvents(ctx context.Context, userID string) ([]storage.Event, error) {
+	events, err := s.queryEvents(ctx,
+		"SELECT "+eventColumns+" FROM events WHERE user_id = $1 ORDER BY start_at, id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user events: %w", err)
+	}
+	return events, nil
+}
+
+// ListDueReminders returns reminders about events and occurrences which are due at now
+// and haven't fired yet, ordered by the start of the events. Only the reminders whose notify_at
+// has come are read, those of them found not due get it moved to their next occurrence,
+// so now isn't expected to go back between calls.
+func (s *Storage) ListDueReminders(ctx context.Context, now time.Time) ([]storage.DueReminder, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+eventColumns+`, position, notify_at, fired_until FROM events JOIN reminder_schedule ON event_id = id
+		WHERE notify_at <= $1 ORDER BY id, position`,
+		now.UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due reminders: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		events []storage.Event
+		// scheduled holds the notify_at of the read reminders of every event by position
+		scheduled = make(map[string]map[int]time.Time)
+		fired     = make(map[string][]time.Time)
+	)
+	for rows.Next() {
+		var (
+			position   int
+			notifyAt   time.Time
+			firedUntil sql.NullTime
+		)
+		event, err := scanEvent(rows, &position, &notifyAt, &firedUntil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list due reminders: %w", err)
+		}
+		if _, ok := scheduled[event.ID]; !ok {
+			events = append(events, event)
+			scheduled[event.ID] = make(map[int]time.Time)
+		}
+		scheduled[event.ID][position] = notifyAt.UTC()
+		if firedUntil.Valid {
+			fired[event.ID] = setFired(fired[event.ID], position, firedUntil.Time)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list due reminders: %w", err)
+	}
+	rows.Close()
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	if err := loadAttendees(ctx, s.db, events); err != nil {
+		return nil, err
+	}
+	overrides, err := s.overrides(ctx,
+		"series_id IN (SELECT event_id FROM reminder_schedule WHERE notify_at <= $1)", now.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	var reminders []storage.DueReminder
+	for _, event := range events {
+		due, err := storage.DueReminders(event, overrides[event.ID], now, fired[event.ID])
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range due {
+			// the reminders which weren't read aren't due yet
+			if _, ok := scheduled[event.ID][d.Index]; ok {
+				reminders = append(reminders, d)
+			}
+		}
+
+		next, err := storage.NextReminders(event, now, fired[event.ID])
+		if err != nil {
+			return nil, err
+		}
+		for position, notifyAt := range scheduled[event.ID] {
+			var at time.Time
+			if position < len(next) {
+				at = next[position]
+			}
+			if at.Equal(notifyAt) {
+				continue
+			}
+			if err := s.reschedule(ctx, event.ID, position, notifyAt, at); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	storage.SortDueReminders(reminders)
+	return reminders, nil
+}
+
+// reschedule moves notify_at of the reminder at the position to next, zero means never,
+// unless the reminder has been rescheduled since it was read.
+func (s *Storage) reschedule(ctx context.Context, eventID string, position int, notifyAt, next time.Time) error {
+	var value any
+	if !next.IsZero() {
+		value = next
+	}
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE reminder_schedule SET notify_at = $4 WHERE event_id = $1 AND position = $2 AND notify_at = $3",
+		eventID, position, notifyAt, value)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule reminder: %w", err)
+	}
 	return nil
 }
+
+// MarkReminderFired excludes the due reminder of the event, or of the occurrences of a series starting
+// up to the one due, from ListDueReminders results until the event is moved or the reminder is changed.
+// It's ErrReminderNotFound if the event doesn't have the reminder at its index anymore.
+func (s *Storage) MarkReminderFired(ctx context.Context, due storage.DueReminder) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		// the reminders of the event don't change until the mark is saved
+		if err := s.lockUser(ctx, tx, due.Event.UserID); err != nil {
+			return err
+		}
+		event, err := scanEvent(tx.QueryRowContext(ctx, "SELECT "+eventColumns+" FROM events WHERE id = $1", due.Event.ID))
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrEventNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get event: %w", err)
+		}
+		if !event.HasReminder(due) {
+			return storage.ErrReminderNotFound
+		}
+
+		fired, err := firedReminders(ctx, tx, "event_id = $1", event.ID)
+		if err != nil {
+			return err
+		}
+		firedUntil := fired[event.ID]
+		if due.Index >= len(firedUntil) || firedUntil[due.Index].Before(due.Event.StartAt) {
+			firedUntil = setFired(firedUntil, due.Index, due.Event.StartAt)
+		}
+		next, err := storage.NextReminders(event, time.Time{}, firedUntil)
+		if err != nil {
+			return err
+		}
+		var notifyAt any
+		if !next[due.Index].IsZero() {
+			notifyAt = next[due.Index]
+		}
+
+		result, err := tx.ExecContext(ctx,
+			"UPDATE reminder_schedule SET fired_until = $3, notify_at = $4 WHERE event_id = $1 AND position = $2",
+			due.Event.ID, due.Index, firedUntil[due.Index], notifyAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to mark reminder fired: %w", err)
+		}
+		if err := checkAffected(result); err != nil {
+			return storage.ErrReminderNotFound
+		}
+		return nil
+	})
+}
+
+// DeleteEventsBefore deletes at most limit events which ended before the given moment,
+// the oldest first, and returns the number of deleted events. A series ends with its last
+// occurrence, so infinite ones are never deleted. Overrides are deleted along with their series.
+// The audit logs of the events are deleted with them, and so are at most limit logs
+// of the events deleted before the moment.
+func (s *Storage) DeleteEventsBefore(ctx context.Context, before time.Time, limit int) (int, error) {
+	const (
+		oldest  = "SELECT id FROM events WHERE last_end_at < $1 ORDER BY last_end_at, id LIMIT $2"
+		deleted = "FROM events WHERE id IN (" + oldest + ") OR series_id IN (" + oldest + ")"
+	)
+
+	var count int
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, "SELECT DISTINCT user_id "+deleted, before.UTC(), limit)
+		if err != nil {
+			return fmt.Errorf("failed to list owners of old events: %w", err)
+		}
+		var users []string
+		for rows.Next() {
+			var userID string
+			if err := rows.Scan(&userID); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to list owners of old events: %w", err)
+			}
+			users = append(users, userID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to list owners of old events: %w", err)
+		}
+		logs, err := deletedLogs(ctx, tx, before, limit)
+		if err != nil {
+			return err
+		}
+		for _, log := range logs {
+			users = append(users, log.UserID)
+		}
+
+		// the owners are locked in the same order by every purge, so purges don't deadlock
+		slices.Sort(users)
+		owners := make(map[string]bool)
+		for _, userID := range slices.Compact(users) {
+			if err := s.lockUser(ctx, tx, userID); err != nil {
+				return err
+			}
+			owners[userID] = true
+		}
+
+		events, err := scanEvents(ctx, tx, "SELECT "+eventColumns+" "+deleted, before.UTC(), limit)
+		if err != nil {
+			return fmt.Errorf("failed to list old events: %w", err)
+		}
+		// events of the owners which aren't locked have got old in between, they'll go next time
+		events = slices.DeleteFunc(events, func(event storage.Event) bool { return !owners[event.UserID] })
+		count = len(events)
+		// the audit log would keep what the retention is meant to forget otherwise
+		if err := deleteEvents(ctx, tx, events, append(slices.Clone(dependentTables), "event_changes")); err != nil {
+			return err
+		}
+
+		// the logs are checked again under the locks, an event could have been created anew
+		if logs, err = deletedLogs(ctx, tx, before, limit); err != nil {
+			return err
+		}
+		var ids []string
+		for _, log := range logs {
+			if owners[log.UserID] {
+				ids = append(ids, log.EventID)
+			}
+		}
+		return deleteRows(ctx, tx, "event_changes", "event_id", ids)
+	})
+	return count, err
+}
+
+// CountEventsBefore returns the number of events which ended before the given moment.
+func (s *Storage) CountEventsBefore(ctx context.Context, before time.Time) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM events WHERE last_end_at < $1", before.UTC()).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count old events: %w", err)
+	}
+	return count, nil
+}
+
+func (s *Storage) ListEventsForDay(ctx context.Context, userID string, date time.Time) ([]storage.Event, error) {
+	from, to := storage.DayPeriod(date)
+	return s.ListEvents(ctx, userID, from, to)
+}
+
+func (s *Storage) ListEventsForWeek(ctx context.Context, userID string, date time.Time) ([]storage.Event, error) {
+	from, to := storage.WeekPeriod(date)
+	return s.ListEvents(ctx, userID, from, to)
+}
+
+func (s *Storage) ListEventsForMonth(ctx context.Context, userID string, date time.Time) ([]storage.Event, error) {
+	from, to := storage.MonthPeriod(date)
+	return s.ListEvents(ctx, userID, from, to)
+}
+
+// queryEvents returns the events selected by the query along with their attendees.
+func (s *Storage) queryEvents(ctx context.Context, query string, args ...any) ([]storage.Event, error) {
+	events, err := scanEvents(ctx, s.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadAttendees(ctx, s.db, events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func scanEvents(ctx context.Context, q querier, query string, args ...any) ([]storage.Event, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []storage.Event
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// loadAttendees fills in the attendees of the events. The connection must be free by then,
+// as SQLite has only one.
+func loadAttendees(ctx context.Context, q querier, events []storage.Event) error {
+	// an event is listed once per due reminder
+	byID := make(map[string][]int, len(events))
+	for i, event := range events {
+		byID[event.ID] = append(byID[event.ID], i)
+	}
+
+	for start := 0; start < len(events); start += eventsBatch {
+		batch := events[start:min(start+eventsBatch, len(events))]
+		args := make([]any, 0, len(batch))
+		for _, event := range batch {
+			args = append(args, event.ID)
+		}
+
+		rows, err := q.QueryContext(ctx,
+			"SELECT event_id, user_id, status FROM attendees WHERE event_id IN ("+placeholders(len(args))+") "+
+				"ORDER BY event_id, position",
+			args...)
+		if err != nil {
+			return fmt.Errorf("failed to list attendees: %w", err)
+		}
+		for rows.Next() {
+			var (
+				eventID  string
+				attendee storage.Attendee
+			)
+			if err := rows.Scan(&eventID, &attendee.UserID, &attendee.Status); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to list attendees: %w", err)
+			}
+			for _, i := range byID[eventID] {
+				events[i].Attendees = append(events[i].Attendees, attendee)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to list attendees: %w", err)
+		}
+	}
+	return nil
+}
+
+// saveAttendees replaces the stored attendees of the event with its current ones.
+func saveAttendees(ctx context.Context, tx *sql.Tx, event storage.Event) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM attendees WHERE event_id = $1", event.ID); err != nil {
+		return fmt.Errorf("failed to delete attendees: %w", err)
+	}
+	for i, attendee := range event.Attendees {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO attendees (event_id, user_id, status, position) VALUES ($1, $2, $3, $4)",
+			event.ID, attendee.UserID, string(attendee.Status), i)
+		if err != nil {
+			return fmt.Errorf("failed to insert attendee: %w", err)
+		}
+	}
+	return nil
+}
+
+// eventInTx returns the event along with its attendees within the transaction.
+func eventInTx(ctx context.Context, tx *sql.Tx, id string) (storage.Event, error) {
+	events, err := scanEvents(ctx, tx, "SELECT "+eventColumns+" FROM events WHERE id = $1", id)
+	if err != nil {
+		return storage.Event{}, fmt.Errorf("failed to get event: %w", err)
+	}
+	if len(events) == 0 {
+		return storage.Event{}, storage.ErrEventNotFound
+	}
+	if err := loadAttendees(ctx, tx, events); err != nil {
+		return storage.Event{}, err
+	}
+	return events[0], nil
+}
+
+// deleteEvents deletes the events along with their rows in the dependent tables.
+// The transaction must hold the locks of their owners.
+func deleteEvents(ctx context.Context, tx *sql.Tx, events []storage.Event, dependent []string) error {
+	ids := make([]string, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	for _, table := range dependent {
+		if err := deleteRows(ctx, tx, table, "event_id", ids); err != nil {
+			return err
+		}
+	}
+	return deleteRows(ctx, tx, "events", "id", ids)
+}
+
+// deleteRows deletes the rows of the table whose column holds one of the values.
+func deleteRows(ctx context.Context, tx *sql.Tx, table, column string, values []string) error {
+	for start := 0; start < len(values); start += eventsBatch {
+		batch := values[start:min(start+eventsBatch, len(values))]
+		args := make([]any, 0, len(batch))
+		for _, value := range batch {
+			args = append(args, value)
+		}
+
+		query := "DELETE FROM " + table + " WHERE " + column + " IN (" + placeholders(len(args)) + ")"
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to delete from %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// saveReminders replaces the schedule of the active reminders of the event, firedUntil is their fired state.
+func saveReminders(ctx context.Context, tx *sql.Tx, event storage.Event, firedUntil []time.Time) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM reminder_schedule WHERE event_id = $1", event.ID); err != nil {
+		return fmt.Errorf("failed to delete reminder schedule: %w", err)
+	}
+	// the reminders of past occurrences are skipped by ListDueReminders then
+	next, err := storage.NextReminders(event, time.Time{}, firedUntil)
+	if err != nil {
+		return err
+	}
+	for i := range event.ActiveReminders() {
+		var notifyAt, fired any
+		if !next[i].IsZero() {
+			notifyAt = next[i]
+		}
+		if i < len(firedUntil) && !firedUntil[i].IsZero() {
+			fired = firedUntil[i].UTC()
+		}
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO reminder_schedule (event_id, position, notify_at, fired_until) VALUES ($1, $2, $3, $4)",
+			event.ID, i, notifyAt, fired)
+		if err != nil {
+			return fmt.Errorf("failed to insert reminder schedule: %w", err)
+		}
+	}
+	return nil
+}
+
+// firedReminders returns the fired state of the reminders of the events matching where.
+func firedReminders(ctx context.Context, q querier, where string, args ...any) (map[string][]time.Time, error) {
+	rows, err := q.QueryContext(ctx,
+		"SELECT event_id, position, fired_until FROM reminder_schedule WHERE fired_until IS NOT NULL AND "+where,
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fired reminders: %w", err)
+	}
+	defer rows.Close()
+
+	fired := make(map[string][]time.Time)
+	for rows.Next() {
+		var (
+			eventID    string
+			position   int
+			firedUntil time.Time
+		)
+		if err := rows.Scan(&eventID, &position, &firedUntil); err != nil {
+			return nil, fmt.Errorf("failed to list fired reminders: %w", err)
+		}
+		fired[eventID] = setFired(fired[eventID], position, firedUntil)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list fired reminders: %w", err)
+	}
+	return fired, nil
+}
+
+// setFired sets the fired state of the reminder at the position, growing the state as needed.
+func setFired(fired []time.Time, position int, firedUntil time.Time) []time.Time {
+	if position >= len(fired) {
+		fired = append(fired, make([]time.Time, position+1-len(fired))...)
+	}
+	fired[position] = firedUntil.UTC()
+	return fired
+}
+
+// overrides returns the original starts of the overridden occurrences of the events matching where.
+func (s *Storage) overrides(ctx context.Context, where string, args ...any) (storage.Overrides, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT series_id, recurrence_id FROM events WHERE series_id IS NOT NULL AND "+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list overrides: %w", err)
+	}
+	defer rows.Close()
+
+	overrides := make(storage.Overrides)
+	for rows.Next() {
+		var (
+			seriesID     string
+			recurrenceID time.Time
+		)
+		if err := rows.Scan(&seriesID, &recurrenceID); err != nil {
+			return nil, fmt.Errorf("failed to list overrides: %w", err)
+		}
+		overrides[seriesID] = append(overrides[seriesID], recurrenceID.UTC())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list overrides: %w", err)
+	}
+	return overrides, nil
+}
+
+func (s *Storage) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) lockUser(ctx context.Context, tx *sql.Tx, userID string) error {
+	if s.dialect.lockUser == "" {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, s.dialect.lockUser, userID); err != nil {
+		return fmt.Errorf("failed to lock user events: %w", err)
+	}
+	return nil
+}
+
+// isBusy reports whether the event overlaps another event of the same owner.
+// Series and their overrides aren't checked, as a series has no end in general.
+func isBusy(ctx context.Context, tx *sql.Tx, event storage.Event) error {
+	if event.Recurring() || event.SeriesID != "" {
+		return nil
+	}
+
+	var busy bool
+	err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS (
+			SELECT 1 FROM events
+			WHERE user_id = $1 AND id <> $2 AND start_at < $4 AND end_at > $3 AND rrule = '' AND series_id IS NULL
+		)`,
+		event.UserID, event.ID, event.StartAt.UTC(), event.EndAt().UTC(),
+	).Scan(&busy)
+	if err != nil {
+		return fmt.Errorf("failed to check overlapping events: %w", err)
+	}
+	if busy {
+		return storage.ErrDateBusy
+	}
+	return nil
+}
+
+func checkAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if affected == 0 {
+		return storage.ErrEventNotFound
+	}
+	return nil
+}
+
+// eventRow returns column values of the event in the order of eventColumns
+// followed by last_end_at.
+func eventRow(event storage.Event) ([]any, error) {
+	exDates := make([]string, 0, len(event.ExDates))
+	for _, t := range event.ExDates {
+		exDates = append(exDates, t.UTC().Format(time.RFC3339Nano))
+	}
+
+	reminders := make([]string, 0, len(event.Reminders))
+	for _, r := range event.Reminders {
+		reminders = append(reminders, fmt.Sprintf("%d:%s", int64(r.Before/time.Second), r.Channel))
+	}
+
+	var seriesID, recurrenceID, lastEndAt any
+	if event.SeriesID != "" {
+		seriesID = event.SeriesID
+		recurrenceID = event.RecurrenceID.UTC()
+	}
+	lastEnd, ok, err := storage.LastEnd(event)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		lastEndAt = lastEnd.UTC()
+	}
+
+	return []any{
+		event.ID, event.UserID, event.Title, event.Description,
+		event.StartAt.UTC(), event.EndAt().UTC(), int64(event.NotifyBefore / time.Second),
+		event.RRule, strings.Join(exDates, ","), seriesID, recurrenceID, event.TimeZone, event.Version,
+		strings.Join(reminders, ","), lastEndAt,
+	}, nil
+}
+
+// placeholders returns the placeholders of n query parameters starting with $1.
+func placeholders(n int) string {
+	list := make([]string, 0, n)
+	for i := 1; i <= n; i++ {
+		list = append(list, fmt.Sprintf("$%d", i))
+	}
+	return strings.Join(list, ", ")
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanEvent scans the columns listed in eventColumns followed by extra ones.
+func scanEvent(row scanner, extra ...any) (storage.Event, error) {
+	var (
+		event        storage.Event
+		endAt        time.Time
+		notifyBefore int64
+		exDates      string
+		reminders    string
+		seriesID     sql.NullString
+		recurrenceID sql.NullTime
+	)
+
+	dest := append([]any{
+		&event.ID, &event.UserID, &event.Title, &event.Description, &event.StartAt, &endAt, &notifyBefore,
+		&event.RRule, &exDates, &seriesID, &recurrenceID, &event.TimeZone, &event.Version, &reminders,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return storage.Event{}, err
+	}
+
+	event.StartAt = event.StartAt.UTC()
+	event.Duration = endAt.Sub(event.StartAt)
+	event.NotifyBefore = time.Duration(notifyBefore) * time.Second
+	event.SeriesID = seriesID.String
+	if recurrenceID.Valid {
+		event.RecurrenceID = recurrenceID.Time.UTC()
+	}
+	if exDates != "" {
+		for _, value := range strings.Split(exDates, ",") {
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return storage.Event{}, fmt.Errorf("malformed exdates of event %s: %w", event.ID, err)
+			}
+			event.ExDates = append(event.ExDates, t)
+		}
+	}
+	if reminders != "" {
+		for _, value := range strings.Split(reminders, ",") {
+			seconds, channel, _ := strings.Cut(value, ":")
+			before, err := strconv.ParseInt(seconds, 10, 64)
+			if err != nil {
+				return storage.Event{}, fmt.Errorf("malformed reminders of event %s: %w", event.ID, err)
+			}
+			event.Reminders = append(event.Reminders,
+				storage.Reminder{Before: time.Duration(before) * time.Second, Channel: channel})
+		}
+	}
+	return event, nil
+}
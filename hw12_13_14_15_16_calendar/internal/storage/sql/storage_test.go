@@ -0,0 +1,123 @@
+package sqlstorage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestStorage connects to a fresh SQLite database with all migrations applied.
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+	ctx := context.Background()
+
+	s := New(DriverSQLite, filepath.Join(t.TempDir(), "calendar.db"))
+	require.NoError(t, s.Connect(ctx))
+	t.Cleanup(func() { require.NoError(t, s.Close(ctx)) })
+	require.NoError(t, s.Migrate(ctx, "up", io.Discard))
+
+	return s
+}
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Storage {
+		t.Helper()
+		return newTestStorage(t)
+	})
+}
+
+// TestStoragePostgres runs the same checks against PostgreSQL when
+// CALENDAR_TEST_POSTGRES_DSN points to an empty database.
+func TestStoragePostgres(t *testing.T) {
+	dsn := os.Getenv("CALENDAR_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("CALENDAR_TEST_POSTGRES_DSN is not set")
+	}
+
+	storagetest.Run(t, func(t *testing.T) storagetest.Storage {
+		t.Helper()
+		ctx := context.Background()
+
+		s := New(DriverPostgres, dsn)
+		require.NoError(t, s.Connect(ctx))
+		require.NoError(t, s.Migrate(ctx, "up", io.Discard))
+		t.Cleanup(func() {
+			require.NoError(t, s.Migrate(ctx, "reset", io.Discard))
+			require.NoError(t, s.Close(ctx))
+		})
+
+		return s
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	// roll back to the very first version
+	for range 9 {
+		require.NoError(t, s.Migrate(ctx, "down", io.Discard))
+	}
+	_, err := s.ListDueReminders(ctx, storagetest.BaseTime)
+	require.Error(t, err)
+
+	// events created before notify_at appeared get it filled in, and then moved to the reminder schedule
+	_, err = s.db.ExecContext(ctx,
+		"INSERT INTO events (id, user_id, title, start_at, end_at, notify_before) VALUES ($1, $2, $3, $4, $5, $6)",
+		"1", "user", "event", storagetest.BaseTime, storagetest.BaseTime.Add(time.Hour), 3600)
+	require.NoError(t, err)
+	require.NoError(t, s.Migrate(ctx, "up", io.Discard))
+
+	reminders, err := s.ListDueReminders(ctx, storagetest.BaseTime.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, reminders, 1)
+	require.Equal(t, "1", reminders[0].Event.ID)
+
+	require.NoError(t, s.Migrate(ctx, "reset", io.Discard))
+	_, err = s.GetEvent(ctx, "1")
+	require.Error(t, err)
+
+	require.NoError(t, s.Migrate(ctx, "up", io.Discard))
+	require.ErrorIs(t, s.Migrate(ctx, "sideways", io.Discard), ErrUnknownMigrateCommand)
+}
+
+func TestMigrateSeriesReminders(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	// series created before their reminders were scheduled have no notify_at
+	series := storagetest.NewEvent("standup", "user", storagetest.BaseTime.AddDate(0, 0, -7), 15*time.Minute)
+	series.RRule = "FREQ=DAILY"
+	series.NotifyBefore = 10 * time.Minute
+	require.NoError(t, s.CreateEvent(ctx, series, storage.Audit{}))
+	require.NoError(t, s.Migrate(ctx, "down", io.Discard))
+	reminders, err := s.ListDueReminders(ctx, storagetest.BaseTime.Add(-5*time.Minute))
+	require.NoError(t, err)
+	require.Empty(t, reminders)
+
+	require.NoError(t, s.Migrate(ctx, "up", io.Discard))
+	reminders, err = s.ListDueReminders(ctx, storagetest.BaseTime.Add(-5*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, reminders, 1)
+	require.Equal(t, storagetest.BaseTime, reminders[0].Event.StartAt)
+
+	// once the occurrence has started, the reminder is scheduled for the next one
+	reminders, err = s.ListDueReminders(ctx, storagetest.BaseTime.Add(time.Minute))
+	require.NoError(t, err)
+	require.Empty(t, reminders)
+	var notifyAt time.Time
+	require.NoError(t, s.db.QueryRowContext(ctx,
+		"SELECT notify_at FROM reminder_schedule WHERE event_id = $1", "standup").Scan(&notifyAt))
+	require.Equal(t, storagetest.BaseTime.AddDate(0, 0, 1).Add(-10*time.Minute), notifyAt.UTC())
+}
+
+func TestConnect(t *testing.T) {
+	require.ErrorIs(t, New("oracle", "").Connect(context.Background()), ErrUnknownDriver)
+}
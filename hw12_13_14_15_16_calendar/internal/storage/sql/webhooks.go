@@ -0,0 +1,139 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	webhookColumns  = "id, user_id, url, secret, created_at"
+	deliveryColumns = "id, webhook_id, event_id, start_at, attempt, delivered_at, duration, status_code, error"
+)
+
+func (s *Storage) CreateWebhook(ctx context.Context, webhook storage.Webhook) error {
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO webhooks ("+webhookColumns+") VALUES ($1, $2, $3, $4, $5)",
+		webhook.ID, webhook.UserID, webhook.URL, webhook.Secret, webhook.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) GetWebhook(ctx context.Context, id string) (storage.Webhook, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", id)
+
+	webhook, err := scanWebhook(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Webhook{}, storage.ErrWebhookNotFound
+	}
+	if err != nil {
+		return storage.Webhook{}, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// ListWebhooks returns webhooks of the user in the order of creation.
+func (s *Storage) ListWebhooks(ctx context.Context, userID string) ([]storage.Webhook, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+webhookColumns+" FROM webhooks WHERE user_id = $1 ORDER BY created_at, id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var webhooks []storage.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list webhooks: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook deletes the webhook along with its deliveries.
+func (s *Storage) DeleteWebhook(ctx context.Context, id string) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM deliveries WHERE webhook_id = $1", id); err != nil {
+			return fmt.Errorf("failed to delete deliveries: %w", err)
+		}
+
+		result, err := tx.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1", id)
+		if err != nil {
+			return fmt.Errorf("failed to delete webhook: %w", err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		if affected == 0 {
+			return storage.ErrWebhookNotFound
+		}
+		return nil
+	})
+}
+
+func (s *Storage) AddDelivery(ctx context.Context, delivery storage.Delivery) error {
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO deliveries ("+deliveryColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		delivery.ID, delivery.WebhookID, delivery.EventID, delivery.StartAt.UTC(), delivery.Attempt,
+		delivery.DeliveredAt.UTC(), delivery.Duration.Milliseconds(), delivery.StatusCode, delivery.Error,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert delivery: %w", err)
+	}
+	return nil
+}
+
+// ListDeliveries returns at most limit latest deliveries to the webhook, the latest first.
+func (s *Storage) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]storage.Delivery, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+deliveryColumns+" FROM deliveries WHERE webhook_id = $1 "+
+			"ORDER BY delivered_at DESC, attempt DESC, id LIMIT $2",
+		webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []storage.Delivery
+	for rows.Next() {
+		var (
+			delivery storage.Delivery
+			duration int64
+		)
+		err := rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.EventID, &delivery.StartAt, &delivery.Attempt,
+			&delivery.DeliveredAt, &duration, &delivery.StatusCode, &delivery.Error)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list deliveries: %w", err)
+		}
+		delivery.StartAt = delivery.StartAt.UTC()
+		delivery.DeliveredAt = delivery.DeliveredAt.UTC()
+		delivery.Duration = time.Duration(duration) * time.Millisecond
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func scanWebhook(row scanner) (storage.Webhook, error) {
+	var webhook storage.Webhook
+	if err := row.Scan(&webhook.ID, &webhook.UserID, &webhook.URL, &webhook.Secret, &webhook.CreatedAt); err != nil {
+		return storage.Webhook{}, err
+	}
+	webhook.CreatedAt = webhook.CreatedAt.UTC()
+	return webhook, nil
+}
@@ -0,0 +1,919 @@
+// Package storagetest contains behaviour tests shared by all storage implementations.
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fixme_my_friend/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+type Storage interface {
+	Ping(ctx context.Context) error
+	CreateEvent(ctx context.Context, event storage.Event, audit storage.Audit) error
+	UpdateEvent(ctx context.Context, id string, event storage.Event, audit storage.Audit) error
+	DeleteEvent(ctx context.Context, id string, audit storage.Audit) error
+	GetEvent(ctx context.Context, id string) (storage.Event, error)
+	ListEventsForDay(ctx context.Context, userID string, date time.Time) ([]storage.Event, error)
+	ListEventsForWeek(ctx context.Context, userID string, date time.Time) ([]storage.Event, error)
+	ListEventsForMonth(ctx context.Context, userID string, date time.Time) ([]storage.Event, error)
+	ListUserEvents(ctx context.Context, userID string) ([]storage.Event, error)
+	ListDueReminders(ctx context.Context, now time.Time) ([]storage.DueReminder, error)
+	MarkReminderFired(ctx context.Context, due storage.DueReminder) error
+	DeleteEventsBefore(ctx context.Context, before time.Time, limit int) (int, error)
+	CountEventsBefore(ctx context.Context, before time.Time) (int, error)
+
+	CreateWebhook(ctx context.Context, webhook storage.Webhook) error
+	GetWebhook(ctx context.Context, id string) (storage.Webhook, error)
+	ListWebhooks(ctx context.Context, userID string) ([]storage.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	AddDelivery(ctx context.Context, delivery storage.Delivery) error
+	ListDeliveries(ctx context.Context, webhookID string, limit int) ([]storage.Delivery, error)
+
+	AddChange(ctx context.Context, change storage.Change) error
+	ListChanges(ctx context.Context, eventID string) ([]storage.Change, error)
+}
+
+var BaseTime = time.Date(2024, time.March, 11, 10, 0, 0, 0, time.UTC)
+
+// NewEvent returns the event as it's stored after creation, i.e. of the first version.
+func NewEvent(id, userID string, startAt time.Time, duration time.Duration) storage.Event {
+	return storage.Event{
+		ID:       id,
+		Title:    "event " + id,
+		StartAt:  startAt,
+		Duration: duration,
+		UserID:   userID,
+		Version:  1,
+	}
+}
+
+// Run checks that the storage returned by newStorage behaves as expected.
+// Every subtest gets a fresh empty storage.
+func Run(t *testing.T, newStorage func(t *testing.T) Storage) {
+	t.Helper()
+	ctx := context.Background()
+	audit := storage.Audit{ActorID: "user", ChangedAt: BaseTime}
+
+	t.Run("ping", func(t *testing.T) {
+		require.NoError(t, newStorage(t).Ping(ctx))
+	})
+
+	t.Run("create and get", func(t *testing.T) {
+		s := newStorage(t)
+		event := NewEvent("1", "user", BaseTime, time.Hour)
+		event.Description = "description"
+		event.NotifyBefore = 15 * time.Minute
+
+		require.NoError(t, s.CreateEvent(ctx, event, audit))
+
+		got, err := s.GetEvent(ctx, "1")
+		require.NoError(t, err)
+		require.Equal(t, event, got)
+
+		require.ErrorIs(t, s.CreateEvent(ctx, event, audit), storage.ErrEventAlreadyExists)
+	})
+
+	t.Run("offsets are kept to the second", func(t *testing.T) {
+		s := newStorage(t)
+		event := NewEvent("1", "user", BaseTime, time.Hour)
+		event.NotifyBefore = time.Hour + time.Second
+		withReminders := NewEvent("2", "user", BaseTime.Add(time.Hour), time.Hour)
+		withReminders.Reminders = []storage.Reminder{{Before: 90 * time.Second}}
+
+		for _, event := range []storage.Event{event, withReminders} {
+			require.NoError(t, s.CreateEvent(ctx, event, audit))
+			got, err := s.GetEvent(ctx, event.ID)
+			require.NoError(t, err)
+			require.Equal(t, event, got)
+		}
+	})
+
+	t.Run("times are returned in UTC", func(t *testing.T) {
+		s := newStorage(t)
+		event := NewEvent("1", "user", BaseTime.In(time.FixedZone("UTC+3", 3*60*60)), time.Hour)
+
+		require.NoError(t, s.CreateEvent(ctx, event, audit))
+
+		got, err := s.GetEvent(ctx, "1")
+		require.NoError(t, err)
+		require.Equal(t, BaseTime, got.StartAt)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		s := newStorage(t)
+
+		_, err := s.GetEvent(ctx, "1")
+		require.ErrorIs(t, err, storage.ErrEventNotFound)
+		require.ErrorIs(t, s.UpdateEvent(ctx, "1", NewEvent("1", "user", BaseTime, time.Hour), audit),
+			storage.ErrEventNotFound)
+		require.ErrorIs(t, s.DeleteEvent(ctx, "1", audit), storage.ErrEventNotFound)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		s := newStorage(t)
+		require.NoError(t, s.CreateEvent(ctx, NewEvent("1", "user", BaseTime, time.Hour), audit))
+
+		// moving an event within its own interval is not an overlap
+		updated := NewEvent("", "user", BaseTime.Add(30*time.Minute), time.Hour)
+		updated.Title = "updated"
+		require.NoError(t, s.UpdateEvent(ctx, "1", updated, audit))
+
+		got, err := s.GetEvent(ctx, "1")
+		require.NoError(t, err)
+		require.Equal(t, "1", got.ID)
+		require.Equal(t, "updated", got.Title)
+		require.Equal(t, BaseTime.Add(30*time.Minute), got.StartAt)
+		require.Equal(t, int64(2), got.Version)
+	})
+
+	t.Run("versions", func(t *testing.T) {
+		s := newStorage(t)
+		event := NewEvent("1", "user", BaseTime, time.Hour)
+		event.Version = 5 // the version of a new event is always the first one
+		require.NoError(t, s.CreateEvent(ctx, event, audit))
+
+		got, err := s.GetEvent(ctx, "1")
+		require.NoError(t, err)
+		require.Equal(t, int64(1), got.Version)
+
+		require.NoError(t, s.UpdateEvent(ctx, "1", got, audit))
+		// the update based on the first version is stale now
+		got.Title = "stale"
+		require.ErrorIs(t, s.UpdateEvent(ctx, "1", got, audit), storage.ErrVersionConflict)
+
+		got, err = s.GetEvent(ctx, "1")
+		require.NoError(t, err)
+		require.Equal(t, "event 1", got.Title)
+		require.Equal(t, int64(2), got.Version)
+
+		// zero version doesn't check the current one
+		got.Version = 0
+		require.NoError(t, s.UpdateEvent(ctx, "1", got, audit))
+		got, err = s.GetEvent(ctx, "1")
+		require.NoError(t, err)
+		require.Equal(t, int64(3), got.Version)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		s := newStorage(t)
+		require.NoError(t, s.CreateEvent(ctx, NewEvent("1", "user", BaseTime, time.Hour), audit))
+		require.NoError(t, s.DeleteEvent(ctx, "1", audit))
+
+		_, err := s.GetEvent(ctx, "1")
+		require.ErrorIs(t, err, storage.ErrEventNotFound)
+
+		// the released time is available again
+		require.NoError(t, s.CreateEvent(ctx, NewEvent("2", "user", BaseTime, time.Hour), audit))
+	})
+
+	t.Run("date busy", func(t *testing.T) {
+		s := newStorage(t)
+		require.NoError(t, s.CreateEvent(ctx, NewEvent("1", "user", BaseTime, time.Hour), audit))
+
+		tests := []struct {
+			name  string
+			event storage.Event
+			err   error
+		}{
+			{"same time", NewEvent("2", "user", BaseTime, time.Hour), storage.ErrDateBusy},
+			{"inside", NewEvent("2", "user", BaseTime.Add(10*time.Minute), 10*time.Minute), storage.ErrDateBusy},
+			{"covers", NewEvent("2", "user", BaseTime.Add(-time.Hour), 3*time.Hour), storage.ErrDateBusy},
+			{"tail", NewEvent("2", "user", BaseTime.Add(59*time.Minute), time.Hour), storage.ErrDateBusy},
+			{"right after", NewEvent("2", "user", BaseTime.Add(time.Hour), time.Hour), nil},
+			{"right before", NewEvent("3", "user", BaseTime.Add(-time.Hour), time.Hour), nil},
+			{"another user", NewEvent("4", "other", BaseTime, time.Hour), nil},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				err := s.CreateEvent(ctx, tc.event, audit)
+				if tc.err == nil {
+					require.NoError(t, err)
+				} else {
+					require.ErrorIs(t, err, tc.err)
+				}
+			})
+		}
+
+		// failed update must keep the original event intact
+		require.ErrorIs(t, s.UpdateEvent(ctx, "2", NewEvent("", "user", BaseTime, time.Hour), audit), storage.ErrDateBusy)
+		got, err := s.GetEvent(ctx, "2")
+		require.NoError(t, err)
+		require.Equal(t, BaseTime.Add(time.Hour), got.StartAt)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		s := newStorage(t)
+		monday := time.Date(2024, time.March, 11, 0, 0, 0, 0, time.UTC)
+		events := []storage.Event{
+			NewEvent("previous day", "user", monday.Add(-2*time.Hour), time.Hour),
+			NewEvent("crosses midnight", "user", monday.Add(-30*time.Minute), time.Hour),
+			NewEvent("morning", "user", monday.Add(9*time.Hour), time.Hour),
+			NewEvent("evening", "user", monday.Add(23*time.Hour), 2*time.Hour),
+			NewEvent("sunday", "user", monday.AddDate(0, 0, 6), time.Hour),
+			NewEvent("next monday", "user", monday.AddDate(0, 0, 7), time.Hour),
+			NewEvent("next month", "user", monday.AddDate(0, 1, 0), time.Hour),
+			NewEvent("another user", "other", monday.Add(9*time.Hour), time.Hour),
+		}
+		for _, event := range events {
+			require.NoError(t, s.CreateEvent(ctx, event, audit))
+		}
+
+		ids := func(events []storage.Event, err error) []string {
+			require.NoError(t, err)
+			result := make([]string, 0, len(events))
+			for _, event := range events {
+				result = append(result, event.ID)
+			}
+			return result
+		}
+
+		require.Equal(t,
+			[]string{"crosses midnight", "morning", "evening"},
+			ids(s.ListEventsForDay(ctx, "user", monday.Add(12*time.Hour))))
+		require.Equal(t,
+			[]string{"crosses midnight", "morning", "evening", "sunday"},
+			ids(s.ListEventsForWeek(ctx, "user", monday)))
+		require.Equal(t,
+			[]string{"crosses midnight", "morning", "evening", "sunday", "next monday"},
+			ids(s.ListEventsForMonth(ctx, "user", monday)))
+		require.Equal(t,
+			[]string{"another user"},
+			ids(s.ListEventsForDay(ctx, "other", monday)))
+		require.Empty(t, ids(s.ListEventsForDay(ctx, "nobody", monday)))
+
+		require.NoError(t, s.DeleteEvent(ctx, "morning", audit))
+		require.NoError(t, s.UpdateEvent(ctx, "sunday", NewEvent("", "user", monday.Add(12*time.Hour), time.Hour), audit))
+		require.Equal(t,
+			[]string{"crosses midnight", "sunday", "evening"},
+			ids(s.ListEventsForDay(ctx, "user", monday)))
+	})
+
+	t.Run("notifications", func(t *testing.T) {
+		s := newStorage(t)
+		withNotification := func(event storage.Event, before time.Duration) storage.Event {
+			event.NotifyBefore = before
+			return event
+		}
+		events := []storage.Event{
+			withNotification(NewEvent("due", "user", BaseTime.Add(time.Hour), time.Hour), 2*time.Hour),
+			withNotification(NewEvent("due exactly", "other", BaseTime.Add(time.Hour), time.Hour), time.Hour),
+			withNotification(NewEvent("not yet", "user", BaseTime.Add(3*time.Hour), time.Hour), time.Hour),
+			withNotification(NewEvent("started", "user", BaseTime, time.Hour), time.Hour),
+			NewEvent("without notification", "user", BaseTime.Add(5*time.Hour), time.Hour),
+		}
+		for _, event := range events {
+			require.NoError(t, s.CreateEvent(ctx, event, audit))
+		}
+
+		ids := func() []string {
+			t.Helper()
+			reminders, err := s.ListDueReminders(ctx, BaseTime)
+			require.NoError(t, err)
+			result := make([]string, 0, len(reminders))
+			for _, reminder := range reminders {
+				result = append(result, reminder.Event.ID)
+			}
+			return result
+		}
+
+		require.Equal(t, []string{"due", "due exactly"}, ids())
+
+		require.NoError(t, s.MarkReminderFired(ctx, storage.DueReminder{Event: events[0]}))
+		require.Equal(t, []string{"due exactly"}, ids())
+		unknown := withNotification(NewEvent("unknown", "user", BaseTime, time.Hour), time.Hour)
+		require.ErrorIs(t, s.MarkReminderFired(ctx, storage.DueReminder{Event: unknown}), storage.ErrEventNotFound)
+		require.ErrorIs(t, s.MarkReminderFired(ctx, storage.DueReminder{Event: events[0], Index: 1}),
+			storage.ErrReminderNotFound)
+
+		// changes not affecting the notification time keep the mark
+		updated := withNotification(NewEvent("", "user", BaseTime.Add(time.Hour), time.Hour), 2*time.Hour)
+		updated.Title = "renamed"
+		require.NoError(t, s.UpdateEvent(ctx, "due", updated, audit))
+		require.Equal(t, []string{"due exactly"}, ids())
+
+		// rescheduled event must be notified again
+		updated.StartAt = BaseTime.Add(90 * time.Minute)
+		updated.Version = 2
+		require.NoError(t, s.UpdateEvent(ctx, "due", updated, audit))
+		require.Equal(t, []string{"due exactly", "due"}, ids())
+
+		require.NoError(t, s.DeleteEvent(ctx, "due exactly", audit))
+		require.Equal(t, []string{"due"}, ids())
+	})
+
+	t.Run("delete old events", func(t *testing.T) {
+		s := newStorage(t)
+		events := []storage.Event{
+			NewEvent("oldest", "user", BaseTime.Add(-5*time.Hour), time.Hour),
+			NewEvent("old", "other", BaseTime.Add(-3*time.Hour), time.Hour),
+			NewEvent("older", "user", BaseTime.Add(-4*time.Hour), time.Hour),
+			NewEvent("ends right then", "user", BaseTime.Add(-time.Hour), time.Hour),
+			NewEvent("new", "user", BaseTime, time.Hour),
+		}
+		for _, event := range events {
+			require.NoError(t, s.CreateEvent(ctx, event, audit))
+		}
+
+		count, err := s.CountEventsBefore(ctx, BaseTime)
+		require.NoError(t, err)
+		require.Equal(t, 3, count)
+
+		deleted, err := s.DeleteEventsBefore(ctx, BaseTime, 2)
+		require.NoError(t, err)
+		require.Equal(t, 2, deleted)
+		_, err = s.GetEvent(ctx, "oldest")
+		require.ErrorIs(t, err, storage.ErrEventNotFound)
+		_, err = s.GetEvent(ctx, "older")
+		require.ErrorIs(t, err, storage.ErrEventNotFound)
+
+		deleted, err = s.DeleteEventsBefore(ctx, BaseTime, 2)
+		require.NoError(t, err)
+		require.Equal(t, 1, deleted)
+
+		deleted, err = s.DeleteEventsBefore(ctx, BaseTime, 2)
+		require.NoError(t, err)
+		require.Zero(t, deleted)
+
+		for _, id := range []string{"ends right then", "new"} {
+			_, err := s.GetEvent(ctx, id)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("series", func(t *testing.T) {
+		s := newStorage(t)
+		// BaseTime is Monday
+		series := NewEvent("standup", "user", BaseTime, 15*time.Minute)
+		series.RRule = "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR"
+		series.ExDates = []time.Time{BaseTime.AddDate(0, 0, 2)}
+		require.NoError(t, s.CreateEvent(ctx, series, audit))
+
+		got, err := s.GetEvent(ctx, "standup")
+		require.NoError(t, err)
+		require.Equal(t, series, got)
+
+		override := NewEvent("moved", "user", BaseTime.AddDate(0, 0, 3).Add(4*time.Hour), 15*time.Minute)
+		override.SeriesID = "standup"
+		override.RecurrenceID = BaseTime.AddDate(0, 0, 3)
+		require.NoError(t, s.CreateEvent(ctx, override, audit))
+
+		// occurrences don't make the time busy
+		require.NoError(t, s.CreateEvent(ctx, NewEvent("single", "user", BaseTime.AddDate(0, 0, 1), time.Hour), audit))
+
+		type occurrence struct {
+			ID           string
+			StartAt      time.Time
+			RecurrenceID time.Time
+		}
+		occurrences := func(events []storage.Event, err error) []occurrence {
+			t.Helper()
+			require.NoError(t, err)
+			result := make([]occurrence, 0, len(events))
+			for _, event := range events {
+				result = append(result, occurrence{event.ID, event.StartAt, event.RecurrenceID})
+			}
+			return result
+		}
+		day := func(days int) time.Time {
+			return BaseTime.AddDate(0, 0, days)
+		}
+
+		require.Equal(t, []occurrence{
+			{"standup", day(0), day(0)},
+			{"single", day(1), time.Time{}},
+			{"standup", day(1), day(1)},
+			{"moved", day(3).Add(4 * time.Hour), day(3)},
+			{"standup", day(4), day(4)},
+		}, occurrences(s.ListEventsForWeek(ctx, "user", BaseTime)))
+
+		require.Equal(t, []occurrence{
+			{"standup", day(7), day(7)},
+		}, occurrences(s.ListEventsForDay(ctx, "user", day(7))))
+		require.Empty(t, occurrences(s.ListEventsForDay(ctx, "user", day(-1))))
+
+		series.RRule = "FREQ=DAILY;COUNT=2"
+		series.ExDates = nil
+		require.NoError(t, s.UpdateEvent(ctx, "standup", series, audit))
+		series.Version = 2
+		// the override is kept even though the rule doesn't produce its occurrence anymore
+		require.Equal(t, []occurrence{
+			{"standup", day(0), day(0)},
+			{"single", day(1), time.Time{}},
+			{"standup", day(1), day(1)},
+			{"moved", day(3).Add(4 * time.Hour), day(3)},
+		}, occurrences(s.ListEventsForMonth(ctx, "user", BaseTime)))
+
+		events, err := s.ListUserEvents(ctx, "user")
+		require.NoError(t, err)
+		require.Equal(t, []storage.Event{series, events[1], override}, events)
+		require.Equal(t, "single", events[1].ID)
+		events, err = s.ListUserEvents(ctx, "other")
+		require.NoError(t, err)
+		require.Empty(t, events)
+
+		require.NoError(t, s.DeleteEvent(ctx, "standup", audit))
+		_, err = s.GetEvent(ctx, "moved")
+		require.ErrorIs(t, err, storage.ErrEventNotFound)
+	})
+
+	t.Run("series notifications", func(t *testing.T) {
+		s := newStorage(t)
+		series := NewEvent("daily", "user", BaseTime, time.Hour)
+		series.RRule = "FREQ=DAILY"
+		series.NotifyBefore = 30 * time.Minute
+		require.NoError(t, s.CreateEvent(ctx, series, audit))
+
+		override := NewEvent("moved", "user", BaseTime.AddDate(0, 0, 2).Add(-time.Hour), time.Hour)
+		override.SeriesID = "daily"
+		override.RecurrenceID = BaseTime.AddDate(0, 0, 2)
+		override.NotifyBefore = 30 * time.Minute
+		require.NoError(t, s.CreateEvent(ctx, override, audit))
+
+		due := func(now time.Time) []time.Time {
+			t.Helper()
+			reminders, err := s.ListDueReminders(ctx, now)
+			require.NoError(t, err)
+			result := make([]time.Time, 0, len(reminders))
+			for _, reminder := range reminders {
+				result = append(result, reminder.Event.StartAt)
+			}
+			return result
+		}
+
+		require.Empty(t, due(BaseTime.Add(-time.Hour)))
+		require.Equal(t, []time.Time{BaseTime}, due(BaseTime.Add(-10*time.Minute)))
+		require.NoError(t, s.MarkReminderFired(ctx, storage.DueReminder{Event: series}))
+		require.Empty(t, due(BaseTime.Add(-10*time.Minute)))
+
+		tomorrow := BaseTime.AddDate(0, 0, 1)
+		require.Equal(t, []time.Time{tomorrow}, due(tomorrow.Add(-10*time.Minute)))
+
+		// the overridden occurrence is notified as a single event
+		require.Equal(t, []time.Time{override.StartAt}, due(override.StartAt.Add(-10*time.Minute)))
+		require.Empty(t, due(override.RecurrenceID.Add(-10*time.Minute)))
+	})
+
+	t.Run("reminders of passed occurrences", func(t *testing.T) {
+		s := newStorage(t)
+		series := NewEvent("standup", "user", BaseTime.AddDate(0, 0, -7), 15*time.Minute)
+		series.RRule = "FREQ=DAILY"
+		series.NotifyBefore = 10 * time.Minute
+		require.NoError(t, s.CreateEvent(ctx, series, audit))
+		override := NewEvent("moved", "user", BaseTime.Add(2*time.Hour), 15*time.Minute)
+		override.SeriesID = "standup"
+		override.RecurrenceID = BaseTime
+		require.NoError(t, s.CreateEvent(ctx, override, audit))
+		meeting := NewEvent("meeting", "user", BaseTime, time.Hour)
+		meeting.NotifyBefore = time.Hour
+		require.NoError(t, s.CreateEvent(ctx, meeting, audit))
+
+		due := func(now time.Time) []string {
+			t.Helper()
+			reminders, err := s.ListDueReminders(ctx, now)
+			require.NoError(t, err)
+			result := make([]string, 0, len(reminders))
+			for _, reminder := range reminders {
+				result = append(result, reminder.Event.ID+" "+reminder.Event.StartAt.Format(time.RFC3339))
+			}
+			return result
+		}
+		at := func(id string, start time.Time) string {
+			return id + " " + start.Format(time.RFC3339)
+		}
+
+		// the occurrence comes back along with its reminder once the override is gone
+		require.Equal(t, []string{at("meeting", BaseTime)}, due(BaseTime.Add(-5*time.Minute)))
+		require.NoError(t, s.DeleteEvent(ctx, "moved", audit))
+		require.Equal(t, []string{at("meeting", BaseTime), at("standup", BaseTime)}, due(BaseTime.Add(-4*time.Minute)))
+
+		// reminders of started events aren't due anymore, the ones of the next occurrences are
+		require.Empty(t, due(BaseTime.Add(time.Minute)))
+		tomorrow := BaseTime.AddDate(0, 0, 1)
+		require.Equal(t, []string{at("standup", tomorrow)}, due(tomorrow.Add(-5*time.Minute)))
+
+		// a moved event is reminded about again
+		meeting.StartAt = tomorrow.Add(time.Hour)
+		require.NoError(t, s.UpdateEvent(ctx, "meeting", meeting, audit))
+		require.Equal(t, []string{at("meeting", meeting.StartAt)}, due(tomorrow.Add(time.Minute)))
+	})
+
+	t.Run("reminders", func(t *testing.T) {
+		s := newStorage(t)
+		meeting := NewEvent("meeting", "user", BaseTime.Add(2*time.Hour), time.Hour)
+		meeting.Reminders = []storage.Reminder{
+			{Before: 24 * time.Hour, Channel: "webhook"},
+			{Before: 10 * time.Minute, Channel: "log"},
+		}
+		require.NoError(t, s.CreateEvent(ctx, meeting, audit))
+
+		got, err := s.GetEvent(ctx, "meeting")
+		require.NoError(t, err)
+		require.Equal(t, meeting, got)
+
+		type due struct {
+			id      string
+			startAt time.Time
+			channel string
+		}
+		list := func(now time.Time) []due {
+			t.Helper()
+			reminders, err := s.ListDueReminders(ctx, now)
+			require.NoError(t, err)
+			result := make([]due, 0, len(reminders))
+			for _, r := range reminders {
+				result = append(result, due{r.Event.ID, r.Event.StartAt, r.Reminder().Channel})
+			}
+			return result
+		}
+
+		require.Equal(t, []due{{"meeting", meeting.StartAt, "webhook"}}, list(BaseTime))
+		require.NoError(t, s.MarkReminderFired(ctx, storage.DueReminder{Event: meeting}))
+		require.Empty(t, list(BaseTime))
+		require.Equal(t, []due{{"meeting", meeting.StartAt, "log"}}, list(meeting.StartAt.Add(-5*time.Minute)))
+
+		// a changed reminder is pending again, the unchanged ones keep having fired
+		previous := meeting
+		meeting.Reminders = []storage.Reminder{
+			{Before: 30 * time.Minute, Channel: "log"},
+			{Before: 24 * time.Hour, Channel: "webhook"},
+		}
+		require.NoError(t, s.UpdateEvent(ctx, "meeting", meeting, audit))
+		meeting.Version++
+		require.Empty(t, list(BaseTime))
+		require.Equal(t, []due{{"meeting", meeting.StartAt, "log"}}, list(meeting.StartAt.Add(-20*time.Minute)))
+		// a reminder listed before the change can't mark the one now at its index
+		require.ErrorIs(t, s.MarkReminderFired(ctx, storage.DueReminder{Event: previous, Index: 1}),
+			storage.ErrReminderNotFound)
+		require.Equal(t, []due{{"meeting", meeting.StartAt, "log"}}, list(meeting.StartAt.Add(-20*time.Minute)))
+
+		// moving the event reschedules its reminders
+		meeting.StartAt = BaseTime.Add(3 * time.Hour)
+		require.NoError(t, s.UpdateEvent(ctx, "meeting", meeting, audit))
+		require.Equal(t, []due{{"meeting", meeting.StartAt, "webhook"}}, list(BaseTime))
+		require.Equal(t, []due{{"meeting", meeting.StartAt, "webhook"}}, list(BaseTime.Add(2*time.Hour)))
+		require.Equal(t,
+			[]due{{"meeting", meeting.StartAt, "log"}, {"meeting", meeting.StartAt, "webhook"}},
+			list(BaseTime.Add(150*time.Minute)))
+
+		require.NoError(t, s.DeleteEvent(ctx, "meeting", audit))
+		require.ErrorIs(t, s.MarkReminderFired(ctx, storage.DueReminder{Event: meeting}), storage.ErrEventNotFound)
+		require.Empty(t, list(BaseTime))
+
+		series := NewEvent("daily", "user", BaseTime, time.Hour)
+		series.RRule = "FREQ=DAILY"
+		series.Reminders = []storage.Reminder{{Before: time.Hour, Channel: "webhook"}, {Before: 10 * time.Minute}}
+		require.NoError(t, s.CreateEvent(ctx, series, audit))
+
+		require.Equal(t, []due{{"daily", BaseTime, "webhook"}}, list(BaseTime.Add(-30*time.Minute)))
+		require.NoError(t, s.MarkReminderFired(ctx, storage.DueReminder{Event: series}))
+		require.Equal(t, []due{{"daily", BaseTime, ""}}, list(BaseTime.Add(-5*time.Minute)))
+		tomorrow := BaseTime.AddDate(0, 0, 1)
+		require.Equal(t, []due{{"daily", tomorrow, "webhook"}}, list(tomorrow.Add(-30*time.Minute)))
+
+		// a changed rule keeps the state of the occurrences which haven't moved
+		series.RRule = "FREQ=DAILY;COUNT=10"
+		require.NoError(t, s.UpdateEvent(ctx, "daily", series, audit))
+		series.Version++
+		require.Empty(t, list(BaseTime.Add(-30*time.Minute)))
+		require.Equal(t, []due{{"daily", BaseTime, ""}}, list(BaseTime.Add(-5*time.Minute)))
+
+		// moved occurrences are reminded about again
+		series.StartAt = BaseTime.Add(time.Hour)
+		require.NoError(t, s.UpdateEvent(ctx, "daily", series, audit))
+		require.Equal(t, []due{{"daily", series.StartAt, "webhook"}}, list(BaseTime.Add(30*time.Minute)))
+	})
+
+	t.Run("delete old series", func(t *testing.T) {
+		s := newStorage(t)
+		finite := NewEvent("finite", "user", BaseTime, time.Hour)
+		finite.RRule = "FREQ=WEEKLY;COUNT=3"
+		require.NoError(t, s.CreateEvent(ctx, finite, audit))
+		infinite := NewEvent("infinite", "user", BaseTime, time.Hour)
+		infinite.RRule = "FREQ=WEEKLY"
+		require.NoError(t, s.CreateEvent(ctx, infinite, audit))
+		override := NewEvent("override", "user", BaseTime.Add(30*time.Minute), time.Hour)
+		override.SeriesID = "finite"
+		override.RecurrenceID = BaseTime
+		require.NoError(t, s.CreateEvent(ctx, override, audit))
+
+		// the last occurrence of the finite series ends in two weeks and an hour
+		count, err := s.CountEventsBefore(ctx, BaseTime.AddDate(0, 0, 14))
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+
+		deleted, err := s.DeleteEventsBefore(ctx, BaseTime.AddDate(1, 0, 0), 10)
+		require.NoError(t, err)
+		require.Equal(t, 2, deleted)
+
+		for _, id := range []string{"finite", "override"} {
+			_, err := s.GetEvent(ctx, id)
+			require.ErrorIs(t, err, storage.ErrEventNotFound)
+		}
+		_, err = s.GetEvent(ctx, "infinite")
+		require.NoError(t, err)
+	})
+	t.Run("time zones", func(t *testing.T) {
+		s := newStorage(t)
+		newYork, err := storage.LoadLocation("America/New_York")
+		require.NoError(t, err)
+		moscow, err := storage.LoadLocation("Europe/Moscow")
+		require.NoError(t, err)
+
+		// 10:00 in New York on Mondays, DST starts there on Sunday, March 10
+		series := NewEvent("standup", "user", time.Date(2024, time.March, 4, 10, 0, 0, 0, newYork), time.Hour)
+		series.RRule = "FREQ=WEEKLY"
+		series.TimeZone = "America/New_York"
+		require.NoError(t, s.CreateEvent(ctx, series, audit))
+
+		got, err := s.GetEvent(ctx, "standup")
+		require.NoError(t, err)
+		require.Equal(t, "America/New_York", got.TimeZone)
+
+		events, err := s.ListEventsForWeek(ctx, "user", time.Date(2024, time.March, 4, 0, 0, 0, 0, newYork))
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, time.Date(2024, time.March, 4, 15, 0, 0, 0, time.UTC), events[0].StartAt)
+		events, err = s.ListEventsForWeek(ctx, "user", time.Date(2024, time.March, 11, 0, 0, 0, 0, newYork))
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, time.Date(2024, time.March, 11, 14, 0, 0, 0, time.UTC), events[0].StartAt)
+
+		// late evening in New York is the next day in Moscow
+		late := NewEvent("late", "user", time.Date(2024, time.March, 11, 23, 30, 0, 0, time.UTC), 15*time.Minute)
+		require.NoError(t, s.CreateEvent(ctx, late, audit))
+		events, err = s.ListEventsForDay(ctx, "user", time.Date(2024, time.March, 11, 0, 0, 0, 0, newYork))
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		events, err = s.ListEventsForDay(ctx, "user", time.Date(2024, time.March, 11, 0, 0, 0, 0, moscow))
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, "standup", events[0].ID)
+		events, err = s.ListEventsForDay(ctx, "user", time.Date(2024, time.March, 12, 0, 0, 0, 0, moscow))
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, "late", events[0].ID)
+	})
+
+	t.Run("attendees", func(t *testing.T) {
+		s := newStorage(t)
+
+		meeting := NewEvent("meeting", "user", BaseTime, time.Hour)
+		meeting.NotifyBefore = time.Hour
+		meeting.Attendees = []storage.Attendee{
+			{UserID: "bob", Status: storage.StatusNeedsAction},
+			{UserID: "alice", Status: storage.StatusAccepted},
+		}
+		require.NoError(t, s.CreateEvent(ctx, meeting, audit))
+		// events of the attendees don't make the time of the owner busy and vice versa
+		require.NoError(t, s.CreateEvent(ctx, NewEvent("alice's", "alice", BaseTime, time.Hour), audit))
+
+		series := NewEvent("standup", "user", BaseTime.Add(-time.Hour), 15*time.Minute)
+		series.RRule = "FREQ=DAILY"
+		series.Attendees = []storage.Attendee{{UserID: "alice", Status: storage.StatusTentative}}
+		require.NoError(t, s.CreateEvent(ctx, series, audit))
+		override := NewEvent("moved", "user", BaseTime.AddDate(0, 0, 1), 15*time.Minute)
+		override.SeriesID = "standup"
+		override.RecurrenceID = BaseTime.AddDate(0, 0, 1).Add(-time.Hour)
+		override.Attendees = series.Attendees
+		require.NoError(t, s.CreateEvent(ctx, override, audit))
+
+		got, err := s.GetEvent(ctx, "meeting")
+		require.NoError(t, err)
+		require.Equal(t, meeting, got)
+
+		ids := func(events []storage.Event, err error) []string {
+			t.Helper()
+			require.NoError(t, err)
+			result := make([]string, 0, len(events))
+			for _, event := range events {
+				result = append(result, event.ID)
+			}
+			return result
+		}
+		require.Equal(t, []string{"standup", "alice's", "meeting"}, ids(s.ListEventsForDay(ctx, "alice", BaseTime)))
+		require.Equal(t, []string{"moved"}, ids(s.ListEventsForDay(ctx, "alice", BaseTime.AddDate(0, 0, 1))))
+		require.Equal(t, []string{"meeting"}, ids(s.ListEventsForDay(ctx, "bob", BaseTime)))
+		require.Equal(t, []string{"standup", "meeting"}, ids(s.ListEventsForDay(ctx, "user", BaseTime)))
+
+		events, err := s.ListEventsForDay(ctx, "alice", BaseTime)
+		require.NoError(t, err)
+		require.Equal(t, series.Attendees, events[0].Attendees)
+
+		reminders, err := s.ListDueReminders(ctx, BaseTime.Add(-time.Minute))
+		require.NoError(t, err)
+		require.Len(t, reminders, 1)
+		require.Equal(t, meeting.Attendees, reminders[0].Event.Attendees)
+
+		meeting.Attendees = []storage.Attendee{{UserID: "alice", Status: storage.StatusDeclined}}
+		require.NoError(t, s.UpdateEvent(ctx, "meeting", meeting, audit))
+		meeting.Version = 2
+		got, err = s.GetEvent(ctx, "meeting")
+		require.NoError(t, err)
+		require.Equal(t, meeting, got)
+		require.Empty(t, ids(s.ListEventsForDay(ctx, "bob", BaseTime)))
+
+		require.NoError(t, s.DeleteEvent(ctx, "standup", audit))
+		require.Empty(t, ids(s.ListEventsForDay(ctx, "alice", BaseTime.AddDate(0, 0, 1))))
+		deleted, err := s.DeleteEventsBefore(ctx, BaseTime.AddDate(0, 0, 1), 10)
+		require.NoError(t, err)
+		require.Equal(t, 2, deleted)
+		require.Empty(t, ids(s.ListEventsForDay(ctx, "alice", BaseTime)))
+
+		// attendees of a deleted event don't come back with an event of the same ID
+		require.NoError(t, s.CreateEvent(ctx, NewEvent("meeting", "user", BaseTime, time.Hour), audit))
+		got, err = s.GetEvent(ctx, "meeting")
+		require.NoError(t, err)
+		require.Empty(t, got.Attendees)
+	})
+
+	t.Run("webhooks", func(t *testing.T) {
+		s := newStorage(t)
+
+		webhooks := []storage.Webhook{
+			{ID: "2", UserID: "user", URL: "https://example.com/b", Secret: "b", CreatedAt: BaseTime},
+			{ID: "1", UserID: "user", URL: "https://example.com/a", Secret: "a", CreatedAt: BaseTime.Add(time.Second)},
+			{ID: "3", UserID: "other", URL: "https://example.com/c", Secret: "c", CreatedAt: BaseTime},
+		}
+		for _, webhook := range webhooks {
+			require.NoError(t, s.CreateWebhook(ctx, webhook))
+		}
+
+		got, err := s.GetWebhook(ctx, "1")
+		require.NoError(t, err)
+		require.Equal(t, webhooks[1], got)
+		listed, err := s.ListWebhooks(ctx, "user")
+		require.NoError(t, err)
+		require.Equal(t, webhooks[:2], listed)
+
+		for attempt := 1; attempt <= 3; attempt++ {
+			delivery := storage.Delivery{
+				ID:          fmt.Sprint(attempt),
+				WebhookID:   "1",
+				EventID:     "event",
+				StartAt:     BaseTime.Add(time.Hour),
+				Attempt:     attempt,
+				DeliveredAt: BaseTime.Add(time.Duration(attempt) * time.Second),
+				Duration:    150 * time.Millisecond,
+				StatusCode:  500,
+				Error:       "internal server error",
+			}
+			require.NoError(t, s.AddDelivery(ctx, delivery))
+		}
+		deliveries, err := s.ListDeliveries(ctx, "1", 2)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 2)
+		require.Equal(t, 3, deliveries[0].Attempt)
+		require.Equal(t, storage.Delivery{
+			ID:          "2",
+			WebhookID:   "1",
+			EventID:     "event",
+			StartAt:     BaseTime.Add(time.Hour),
+			Attempt:     2,
+			DeliveredAt: BaseTime.Add(2 * time.Second),
+			Duration:    150 * time.Millisecond,
+			StatusCode:  500,
+			Error:       "internal server error",
+		}, deliveries[1])
+
+		require.NoError(t, s.DeleteWebhook(ctx, "1"))
+		_, err = s.GetWebhook(ctx, "1")
+		require.ErrorIs(t, err, storage.ErrWebhookNotFound)
+		require.ErrorIs(t, s.DeleteWebhook(ctx, "1"), storage.ErrWebhookNotFound)
+		deliveries, err = s.ListDeliveries(ctx, "1", 10)
+		require.NoError(t, err)
+		require.Empty(t, deliveries)
+	})
+
+	t.Run("audit log", func(t *testing.T) {
+		s := newStorage(t)
+
+		moscow, err := time.LoadLocation("Europe/Moscow")
+		require.NoError(t, err)
+		created := NewEvent("1", "user", BaseTime.In(moscow), time.Hour)
+		created.RRule = "FREQ=DAILY"
+		created.ExDates = []time.Time{BaseTime.AddDate(0, 0, 1).In(moscow)}
+		updated := created
+		updated.Title = "renamed"
+		updated.Version = 2
+
+		changes := []storage.Change{
+			{
+				EventID: "1", UserID: "user", ActorID: "user", Action: storage.ActionCreate,
+				ChangedAt: BaseTime.In(moscow), Fields: storage.Diff(storage.Event{}, created), Event: created,
+			},
+			{
+				EventID: "1", UserID: "user", ActorID: "other", Action: storage.ActionUpdate,
+				ChangedAt: BaseTime.Add(time.Second), Fields: storage.Diff(created, updated), Event: updated,
+			},
+			{
+				EventID: "1", UserID: "user", ActorID: "user", Action: storage.ActionDelete,
+				ChangedAt: BaseTime.Add(2 * time.Second), Fields: storage.Diff(updated, storage.Event{}), Event: updated,
+			},
+			{
+				EventID: "1", UserID: "user", ActorID: "user", Action: storage.ActionCreate,
+				ChangedAt: BaseTime.Add(3 * time.Second), Fields: storage.Diff(storage.Event{}, created), Event: created,
+				RestoredFrom: 1,
+			},
+			{
+				EventID: "2", UserID: "user", ActorID: "user", Action: storage.ActionCreate,
+				ChangedAt: BaseTime, Event: NewEvent("2", "user", BaseTime, time.Hour),
+			},
+		}
+		for _, change := range changes {
+			require.NoError(t, s.AddChange(ctx, change))
+		}
+
+		listed, err := s.ListChanges(ctx, "1")
+		require.NoError(t, err)
+		require.Len(t, listed, 4)
+		for i, change := range changes[:4] {
+			change.Revision = int64(i + 1)
+			change.ChangedAt = change.ChangedAt.UTC()
+			change.Event = change.Event.UTC()
+			require.Equal(t, change, listed[i])
+		}
+		require.Equal(t, []storage.FieldChange{{Field: "title", Before: "event 1", After: "renamed"}}, listed[1].Fields)
+
+		listed, err = s.ListChanges(ctx, "2")
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+		require.Equal(t, int64(1), listed[0].Revision)
+
+		listed, err = s.ListChanges(ctx, "unknown")
+		require.NoError(t, err)
+		require.Empty(t, listed)
+	})
+
+	t.Run("changes are recorded", func(t *testing.T) {
+		s := newStorage(t)
+		series := NewEvent("standup", "user", BaseTime, 15*time.Minute)
+		series.RRule = "FREQ=DAILY;COUNT=3"
+		require.NoError(t, s.CreateEvent(ctx, series, audit))
+		override := NewEvent("moved", "user", BaseTime.AddDate(0, 0, 1).Add(time.Hour), 15*time.Minute)
+		override.SeriesID = "standup"
+		override.RecurrenceID = BaseTime.AddDate(0, 0, 1)
+		require.NoError(t, s.CreateEvent(ctx, override, audit))
+
+		renamed := series
+		renamed.Title = "renamed"
+		restore := storage.Audit{ActorID: "other", ChangedAt: BaseTime.Add(time.Second), RestoredFrom: 1}
+		require.NoError(t, s.UpdateEvent(ctx, "standup", renamed, restore))
+		renamed.Version = 2
+		// failed changes aren't recorded
+		require.ErrorIs(t, s.UpdateEvent(ctx, "standup", series, audit), storage.ErrVersionConflict)
+		require.ErrorIs(t, s.DeleteEvent(ctx, "unknown", audit), storage.ErrEventNotFound)
+		require.NoError(t, s.DeleteEvent(ctx, "standup", audit))
+
+		changes, err := s.ListChanges(ctx, "standup")
+		require.NoError(t, err)
+		require.Equal(t, []storage.Change{
+			{
+				EventID: "standup", Revision: 1, UserID: "user", ActorID: "user", Action: storage.ActionCreate,
+				ChangedAt: BaseTime, Fields: storage.Diff(storage.Event{}, series), Event: series,
+			},
+			{
+				EventID: "standup", Revision: 2, UserID: "user", ActorID: "other", Action: storage.ActionUpdate,
+				ChangedAt: BaseTime.Add(time.Second), Fields: storage.Diff(series, renamed), Event: renamed,
+				RestoredFrom: 1,
+			},
+			{
+				EventID: "standup", Revision: 3, UserID: "user", ActorID: "user", Action: storage.ActionDelete,
+				ChangedAt: BaseTime, Fields: storage.Diff(renamed, storage.Event{}), Event: renamed,
+			},
+		}, changes)
+
+		// overrides deleted along with their series get the deletion as well
+		changes, err = s.ListChanges(ctx, "moved")
+		require.NoError(t, err)
+		require.Len(t, changes, 2)
+		require.Equal(t, storage.ActionDelete, changes[1].Action)
+		require.Equal(t, override, changes[1].Event)
+
+		// old events deleted by the retention take their logs along instead,
+		// and so do the events deleted before the moment
+		require.NoError(t, s.CreateEvent(ctx, NewEvent("old", "user", BaseTime, time.Hour), audit))
+		require.NoError(t, s.CreateEvent(ctx, NewEvent("new", "user", BaseTime.AddDate(0, 0, 2), time.Hour), audit))
+		deleted, err := s.DeleteEventsBefore(ctx, BaseTime, 10)
+		require.NoError(t, err)
+		require.Zero(t, deleted)
+		changes, err = s.ListChanges(ctx, "standup")
+		require.NoError(t, err)
+		require.Len(t, changes, 3)
+
+		deleted, err = s.DeleteEventsBefore(ctx, BaseTime.AddDate(0, 0, 1), 10)
+		require.NoError(t, err)
+		require.Equal(t, 1, deleted)
+		for _, id := range []string{"old", "standup", "moved"} {
+			changes, err = s.ListChanges(ctx, id)
+			require.NoError(t, err)
+			require.Empty(t, changes, id)
+		}
+		changes, err = s.ListChanges(ctx, "new")
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+	})
+}
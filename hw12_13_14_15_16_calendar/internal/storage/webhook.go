@@ -0,0 +1,31 @@
+package storage
+
+import "time"
+
+// Webhook is the URL notifications of the user are posted to.
+type Webhook struct {
+	ID     string
+	UserID string
+	URL    string
+	// Secret is the key the payloads are signed with.
+	Secret    string
+	CreatedAt time.Time
+}
+
+// Delivery is a single attempt to post the notification about the event to the webhook.
+type Delivery struct {
+	ID        string
+	WebhookID string
+	EventID   string
+	// StartAt is the start of the event or occurrence the notification is about.
+	StartAt time.Time
+	// Attempt counts attempts of the sender to deliver the notification, starting with 1.
+	// Redelivery of the notification from the queue starts the count anew.
+	Attempt     int
+	DeliveredAt time.Time
+	Duration    time.Duration
+	// StatusCode of the response, zero if there is no response at all.
+	StatusCode int
+	// Error is empty for a successful delivery.
+	Error string
+}
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE events (
+    id            TEXT PRIMARY KEY,
+    user_id       TEXT NOT NULL,
+    title         VARCHAR(255) NOT NULL,
+    description   TEXT NOT NULL DEFAULT '',
+    start_at      TIMESTAMP NOT NULL,
+    end_at        TIMESTAMP NOT NULL,
+    notify_before BIGINT NOT NULL DEFAULT 0 -- seconds
+);
+
+CREATE INDEX events_user_id_start_at_idx ON events (user_id, start_at);
+
+-- +goose Down
+DROP TABLE events;
@@ -0,0 +1,66 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// upNotifyAt adds the moment of notification, so the scheduler can find due events
+// with an index, and the moment the notification was sent. The former is filled in
+// for the existing events.
+func upNotifyAt(ctx context.Context, tx *sql.Tx) error {
+	for _, query := range []string{
+		"ALTER TABLE events ADD COLUMN notify_at TIMESTAMP",
+		"ALTER TABLE events ADD COLUMN notified_at TIMESTAMP",
+		"CREATE INDEX events_notify_at_idx ON events (notify_at)",
+	} {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, start_at, notify_before FROM events WHERE notify_before > 0")
+	if err != nil {
+		return err
+	}
+
+	notifyAt := make(map[string]time.Time)
+	for rows.Next() {
+		var (
+			id           string
+			startAt      time.Time
+			notifyBefore int64
+		)
+		if err := rows.Scan(&id, &startAt, &notifyBefore); err != nil {
+			rows.Close()
+			return err
+		}
+		notifyAt[id] = startAt.UTC().Add(-time.Duration(notifyBefore) * time.Second)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, at := range notifyAt {
+		if _, err := tx.ExecContext(ctx, "UPDATE events SET notify_at = $1 WHERE id = $2", at, id); err != nil {
+			return fmt.Errorf("failed to set notify_at of event %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func downNotifyAt(ctx context.Context, tx *sql.Tx) error {
+	for _, query := range []string{
+		"DROP INDEX events_notify_at_idx",
+		"ALTER TABLE events DROP COLUMN notified_at",
+		"ALTER TABLE events DROP COLUMN notify_at",
+	} {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+-- +goose Up
+ALTER TABLE events ADD COLUMN rrule TEXT NOT NULL DEFAULT '';
+ALTER TABLE events ADD COLUMN exdates TEXT NOT NULL DEFAULT ''; -- comma separated RFC 3339 times
+ALTER TABLE events ADD COLUMN series_id TEXT;
+ALTER TABLE events ADD COLUMN recurrence_id TIMESTAMP;
+-- end of the last occurrence, NULL for infinite series
+ALTER TABLE events ADD COLUMN last_end_at TIMESTAMP;
+-- start of the latest occurrence the owner has been notified about
+ALTER TABLE events ADD COLUMN notified_until TIMESTAMP;
+
+UPDATE events SET last_end_at = end_at;
+UPDATE events SET notified_until = start_at WHERE notified_at IS NOT NULL;
+ALTER TABLE events DROP COLUMN notified_at;
+
+CREATE INDEX events_series_id_idx ON events (series_id);
+CREATE INDEX events_last_end_at_idx ON events (last_end_at);
+
+-- +goose Down
+DROP INDEX events_last_end_at_idx;
+DROP INDEX events_series_id_idx;
+
+ALTER TABLE events ADD COLUMN notified_at TIMESTAMP;
+UPDATE events SET notified_at = notified_until WHERE notified_until IS NOT NULL;
+
+-- series and their overrides can't be represented without recurrence
+DELETE FROM events WHERE series_id IS NOT NULL OR rrule <> '';
+ALTER TABLE events DROP COLUMN notified_until;
+ALTER TABLE events DROP COLUMN last_end_at;
+ALTER TABLE events DROP COLUMN recurrence_id;
+ALTER TABLE events DROP COLUMN series_id;
+ALTER TABLE events DROP COLUMN exdates;
+ALTER TABLE events DROP COLUMN rrule;
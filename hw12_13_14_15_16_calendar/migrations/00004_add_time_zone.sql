@@ -0,0 +1,6 @@
+-- +goose Up
+-- IANA name of the time zone series recur in, empty for UTC
+ALTER TABLE events ADD COLUMN time_zone TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE events DROP COLUMN time_zone;
@@ -0,0 +1,28 @@
+-- +goose Up
+CREATE TABLE webhooks (
+    id         TEXT PRIMARY KEY,
+    user_id    TEXT NOT NULL,
+    url        TEXT NOT NULL,
+    secret     TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX webhooks_user_id_idx ON webhooks (user_id);
+
+CREATE TABLE deliveries (
+    id           TEXT PRIMARY KEY,
+    webhook_id   TEXT NOT NULL,
+    event_id     TEXT NOT NULL,
+    start_at     TIMESTAMP NOT NULL,
+    attempt      INTEGER NOT NULL,
+    delivered_at TIMESTAMP NOT NULL,
+    duration     BIGINT NOT NULL, -- milliseconds
+    status_code  INTEGER NOT NULL DEFAULT 0,
+    error        TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX deliveries_webhook_id_delivered_at_idx ON deliveries (webhook_id, delivered_at);
+
+-- +goose Down
+DROP TABLE deliveries;
+DROP TABLE webhooks;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- incremented by every update, so that concurrent updates don't overwrite each other
+ALTER TABLE events ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+
+-- +goose Down
+ALTER TABLE events DROP COLUMN version;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- the audit log of events, rows are only ever inserted
+CREATE TABLE event_changes (
+    event_id      TEXT NOT NULL,
+    revision      BIGINT NOT NULL,
+    user_id       TEXT NOT NULL,
+    actor_id      TEXT NOT NULL,
+    action        TEXT NOT NULL,
+    changed_at    TIMESTAMP NOT NULL,
+    fields        TEXT NOT NULL, -- JSON array of the changed fields
+    event         TEXT NOT NULL, -- JSON of the event after the change
+    restored_from BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (event_id, revision)
+);
+
+-- +goose Down
+DROP TABLE event_changes;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE attendees (
+    event_id TEXT NOT NULL,
+    user_id  TEXT NOT NULL,
+    status   TEXT NOT NULL,
+    position INTEGER NOT NULL, -- order of the attendees within the event
+    PRIMARY KEY (event_id, user_id)
+);
+
+CREATE INDEX attendees_user_id_idx ON attendees (user_id);
+
+-- +goose Down
+DROP TABLE attendees;
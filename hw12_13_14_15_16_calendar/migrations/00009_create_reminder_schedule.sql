@@ -0,0 +1,34 @@
+-- +goose Up
+ALTER TABLE events ADD COLUMN reminders TEXT NOT NULL DEFAULT ''; -- comma separated seconds:channel
+
+-- the schedule of the active reminders of the events, either the reminders or the one of notify_before
+CREATE TABLE reminder_schedule (
+    event_id    TEXT NOT NULL,
+    position    INTEGER NOT NULL, -- index of the reminder within the event
+    notify_at   TIMESTAMP,        -- moment the reminder is due, NULL for series
+    fired_until TIMESTAMP,        -- start of the latest occurrence the reminder has fired for
+    PRIMARY KEY (event_id, position)
+);
+
+CREATE INDEX reminder_schedule_notify_at_idx ON reminder_schedule (notify_at);
+
+INSERT INTO reminder_schedule (event_id, position, notify_at, fired_until)
+SELECT id, 0, notify_at, notified_until FROM events WHERE notify_before > 0;
+
+DROP INDEX events_notify_at_idx;
+ALTER TABLE events DROP COLUMN notified_until;
+ALTER TABLE events DROP COLUMN notify_at;
+
+-- +goose Down
+ALTER TABLE events ADD COLUMN notify_at TIMESTAMP;
+ALTER TABLE events ADD COLUMN notified_until TIMESTAMP;
+CREATE INDEX events_notify_at_idx ON events (notify_at);
+
+-- only the reminder of notify_before can be represented without the schedule
+UPDATE events SET
+    notify_at = (SELECT notify_at FROM reminder_schedule WHERE event_id = events.id AND position = 0),
+    notified_until = (SELECT fired_until FROM reminder_schedule WHERE event_id = events.id AND position = 0)
+WHERE notify_before > 0 AND reminders = '';
+
+DROP TABLE reminder_schedule;
+ALTER TABLE events DROP COLUMN reminders;
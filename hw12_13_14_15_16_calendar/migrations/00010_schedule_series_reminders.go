@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// upScheduleSeries makes notify_at of the reminder schedule the moment a reminder needs attention next
+// for series as well, so the scheduler reads only the due ones. The next occurrences aren't known here,
+// so the reminders of series are due at once and get rescheduled by the scheduler on its next tick.
+// The reminders of single events which have fired won't need attention anymore.
+func upScheduleSeries(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx,
+		"UPDATE reminder_schedule SET notify_at = $1 "+
+			"WHERE notify_at IS NULL AND event_id IN (SELECT id FROM events WHERE rrule <> '')",
+		time.Unix(0, 0).UTC())
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE reminder_schedule SET notify_at = NULL "+
+			"WHERE fired_until IS NOT NULL AND event_id IN (SELECT id FROM events WHERE rrule = '')")
+	return err
+}
+
+func downScheduleSeries(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx,
+		"UPDATE reminder_schedule SET notify_at = NULL WHERE event_id IN (SELECT id FROM events WHERE rrule <> '')")
+	return err
+}
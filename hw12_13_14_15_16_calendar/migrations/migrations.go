@@ -0,0 +1,20 @@
+// Package migrations contains versioned database schema migrations.
+// The same set of migrations is applied both to PostgreSQL and to SQLite,
+// so only the common subset of SQL is used. All timestamps are stored in UTC.
+package migrations
+
+import (
+	"embed"
+
+	"github.com/pressly/goose/v3"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+// Go contains migrations which need computations that can't be expressed
+// in the SQL common to both databases.
+var Go = []*goose.Migration{
+	goose.NewGoMigration(2, &goose.GoFunc{RunTx: upNotifyAt}, &goose.GoFunc{RunTx: downNotifyAt}),
+	goose.NewGoMigration(10, &goose.GoFunc{RunTx: upScheduleSeries}, &goose.GoFunc{RunTx: downScheduleSeries}),
+}
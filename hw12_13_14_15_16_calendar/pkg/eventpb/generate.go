@@ -0,0 +1,4 @@
+// Package eventpb contains code generated from api/EventService.proto.
+package eventpb
+
+//go:generate sh -c "cd ../.. && buf generate api"